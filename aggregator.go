@@ -0,0 +1,202 @@
+package console
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Upstream is a broker followed by an Aggregator or by AttachOptions.Upstreams.
+type Upstream struct {
+	// Name tags the upstream's lines: it becomes their Source, or is put
+	// before it as "name/source".
+	Name string
+	// Attach locates the broker. Socket, Address, Dial, SocketCandidates,
+	// SocketResolver, TLS, Token and the reconnect delays are used.
+	Attach AttachOptions
+}
+
+// Aggregator follows several brokers, such as one per dhcplane instance,
+// and appends their lines to one broker, tagged with their upstream's name,
+// so clients attached to it see the merged stream. Lines keep their
+// timestamps; set BrokerOptions.MergeWindow on the target broker to
+// interleave them in order. An upstream that goes away is redialled with
+// backoff and resumes after the newest line already taken.
+type Aggregator struct {
+	b         *Broker
+	upstreams []Upstream
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewAggregator returns an aggregator appending the lines of upstreams to b.
+func NewAggregator(b *Broker, upstreams ...Upstream) *Aggregator {
+	return &Aggregator{b: b, upstreams: append([]Upstream(nil), upstreams...)}
+}
+
+// Start follows the upstreams in the background until Stop.
+func (a *Aggregator) Start() error {
+	return a.StartContext(context.Background())
+}
+
+// StartContext is like Start but stops following when ctx is cancelled.
+func (a *Aggregator) StartContext(ctx context.Context) error {
+	if len(a.upstreams) == 0 {
+		return errors.New("console aggregator: no upstreams")
+	}
+	seen := map[string]bool{}
+	for _, up := range a.upstreams {
+		if up.Name == "" || seen[up.Name] {
+			return fmt.Errorf("console aggregator: upstream names must be set and unique: %q", up.Name)
+		}
+		seen[up.Name] = true
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.cancel != nil {
+		return errors.New("console aggregator: already started")
+	}
+	ctx, a.cancel = context.WithCancel(ctx)
+	for _, up := range a.upstreams {
+		a.wg.Go(func() { a.follow(ctx, up) })
+	}
+	return nil
+}
+
+// Stop disconnects from the upstreams and waits for their readers to end.
+func (a *Aggregator) Stop() {
+	a.mu.Lock()
+	cancel := a.cancel
+	a.cancel = nil
+	a.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		a.wg.Wait()
+	}
+}
+
+// follow reads one upstream, redialling until ctx is done.
+func (a *Aggregator) follow(ctx context.Context, up Upstream) {
+	minDelay := cmp.Or(up.Attach.ReconnectMinDelay, 250*time.Millisecond)
+	maxDelay := cmp.Or(up.Attach.ReconnectMaxDelay, 30*time.Second)
+	delay := minDelay
+	var newestUs int64
+	for {
+		connected := false
+		if path, err := up.Attach.resolvePath(); err == nil {
+			if conn, err := up.Attach.dial(ctx, path); err == nil {
+				connected = true
+				newestUs = a.read(ctx, up, conn, newestUs)
+				_ = conn.Close()
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			delay = minDelay
+			a.b.submit(a.notice(up, "[notice] disconnected from "+up.Name))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxDelay)
+	}
+}
+
+// read appends the lines of one connection until it ends, returning the
+// newest line timestamp taken.
+func (a *Aggregator) read(ctx context.Context, up Upstream, conn net.Conn, newestUs int64) int64 {
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+	if writeHello(conn, newestUs, up.Attach.token()) != nil {
+		return newestUs
+	}
+	fr := newConnFrameReader(conn)
+	for {
+		b, err := fr.next()
+		if err != nil {
+			return newestUs
+		}
+		typ, b := fr.peekFrameType(b)
+		var lines []Line
+		switch typ {
+		case "line":
+			var ev Line
+			if json.Unmarshal(b, &ev) == nil {
+				lines = []Line{ev}
+			}
+		case "lines":
+			var evs Lines
+			if json.Unmarshal(b, &evs) == nil {
+				lines = evs.Lines
+			}
+		case "notice":
+			var n Notice
+			if json.Unmarshal(b, &n) == nil {
+				a.b.submit(a.notice(up, n.Text))
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		for i := range lines {
+			ev := &lines[i]
+			newestUs = max(newestUs, ev.TsUs)
+			ev.Type, ev.Seq, ev.Spans = "line", 0, nil
+			ev.Source = upstreamSource(up.Name, ev.Source)
+		}
+		a.b.submit(lines...)
+	}
+}
+
+// notice returns a line carrying an upstream's notice.
+func (a *Aggregator) notice(up Upstream, text string) Line {
+	return Line{Type: "line", TsUs: time.Now().UnixMicro(), Text: text, Source: up.Name}
+}
+
+// upstreamSource tags a line's source with the upstream name.
+func upstreamSource(name, source string) string {
+	if source == "" {
+		return name
+	}
+	return name + "/" + source
+}
+
+// attachUpstreams runs attach, such as AttachContext, on the merged stream
+// of opts.Upstreams, through a private broker on a temporary socket.
+func attachUpstreams(ctx context.Context, opts AttachOptions, attach func(context.Context, AttachOptions) error) error {
+	dir, err := os.MkdirTemp("", "planeconsole-")
+	if err != nil {
+		return fmt.Errorf("console attach: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	b := NewBroker(BrokerOptions{
+		SocketCandidates: []string{filepath.Join(dir, "merged.sock")},
+		MergeWindow:      250 * time.Millisecond,
+	})
+	if err := b.StartContext(ctx); err != nil {
+		return err
+	}
+	defer b.Stop()
+	agg := NewAggregator(b, opts.Upstreams...)
+	if err := agg.StartContext(ctx); err != nil {
+		return err
+	}
+	defer agg.Stop()
+
+	opts.Upstreams, opts.Dial, opts.Address, opts.TLS, opts.Token = nil, nil, "", nil, ""
+	opts.Socket = b.SocketPath()
+	return attach(ctx, opts)
+}
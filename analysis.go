@@ -0,0 +1,148 @@
+package console
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// Variable parts of a line, masked in order to derive its template.
+var (
+	macPattern  = regexp.MustCompile(`\b[0-9a-fA-F]{2}(?:[:-][0-9a-fA-F]{2}){5}\b`)
+	ipv4Pattern = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}(?:/\d{1,2})?\b`)
+	hexPattern  = regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`)
+	numPattern  = regexp.MustCompile(`\d+(?:\.\d+)?`)
+)
+
+// lineTemplate masks MACs, IPv4 addresses, hex and decimal numbers so lines
+// that differ only in such values share a template.
+func lineTemplate(s string) string {
+	s = macPattern.ReplaceAllString(s, "<mac>")
+	s = ipv4Pattern.ReplaceAllString(s, "<ip>")
+	s = hexPattern.ReplaceAllString(s, "<hex>")
+	return numPattern.ReplaceAllString(s, "<n>")
+}
+
+// patternCount is a template with how many buffered lines match it.
+type patternCount struct {
+	template string
+	count    int
+}
+
+// topPatterns clusters lines by template and returns the n most frequent.
+func topPatterns(lines []string, n int) []patternCount {
+	counts := make(map[string]int)
+	for _, l := range lines {
+		counts[lineTemplate(l)]++
+	}
+	out := make([]patternCount, 0, len(counts))
+	for t, c := range counts {
+		out = append(out, patternCount{template: t, count: c})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].count != out[j].count {
+			return out[i].count > out[j].count
+		}
+		return out[i].template < out[j].template
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// patternTopN is how many templates the pattern view lists.
+const patternTopN = 25
+
+// showPatternsModal lists the most frequent line templates in the buffer.
+func (u *UI) showPatternsModal() {
+	u.mu.Lock()
+	lines := make([]string, 0, len(u.lines))
+	for i := range u.lines {
+		lines = append(lines, u.lines[i].text)
+		for _, c := range u.lines[i].cont {
+			lines = append(lines, c.text)
+		}
+	}
+	u.mu.Unlock()
+
+	top := topPatterns(lines, patternTopN)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d lines, top %d patterns (numbers, IPs and MACs masked)\n\n", len(lines), len(top))
+	for _, p := range top {
+		pct := 100 * float64(p.count) / float64(len(lines))
+		fmt.Fprintf(&b, "%7d  %5.1f%%  %s\n", p.count, pct, tview.Escape(p.template))
+	}
+	u.showTextModal(u.msg("modal.patterns"), b.String())
+}
+
+// xidPattern captures DHCP transaction IDs written as "xid=0x1234abcd",
+// "xid: 1234abcd" or similar.
+var xidPattern = regexp.MustCompile(`(?i)\bxid[=: ]+((?:0x)?[0-9a-f]+)\b`)
+
+// correlationToken extracts the key used to trace related lines: a MAC
+// address, else a transaction ID, else an IPv4 address.
+func correlationToken(s string) string {
+	if m := macPattern.FindString(s); m != "" {
+		return m
+	}
+	if m := xidPattern.FindStringSubmatch(s); m != nil {
+		return m[1]
+	}
+	return ipv4Pattern.FindString(s)
+}
+
+// showCorrelationModal opens a sub-view of all buffered lines sharing the
+// key token of the current line, ordered by timestamp.
+func (u *UI) showCorrelationModal() {
+	if u.rowsWrapped() {
+		u.setStatusMessage(u.msg("wrap.rows", "correlate"))
+		return
+	}
+	row, ok := u.currentRow()
+	if !ok {
+		u.setStatusMessage(u.msg("correlate.none"))
+		return
+	}
+	token := correlationToken(row.text)
+	if token == "" {
+		u.setStatusMessage(u.msg("correlate.no_token"))
+		return
+	}
+
+	type hit struct {
+		when time.Time
+		text string
+	}
+	want := strings.ToLower(token)
+	var hits []hit
+	u.mu.Lock()
+	for i := range u.lines {
+		l := &u.lines[i]
+		if strings.Contains(strings.ToLower(l.text), want) {
+			hits = append(hits, hit{l.when, l.text})
+		}
+		for _, c := range l.cont {
+			if strings.Contains(strings.ToLower(c.text), want) {
+				hits = append(hits, hit{l.when, c.text})
+			}
+		}
+	}
+	u.mu.Unlock()
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].when.Before(hits[j].when) })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d lines with %s\n\n", len(hits), tview.Escape(token))
+	for _, h := range hits {
+		line := tview.Escape(h.text)
+		if !u.noColour {
+			line = markVisible(line, token, false, "[::r]", "[::R]")
+		}
+		fmt.Fprintf(&b, "%s  %s\n", h.when.Format("15:04:05.000"), line)
+	}
+	u.showTextModal(u.msg("modal.trace", token), b.String())
+}
@@ -0,0 +1,141 @@
+package console
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// MarkupToANSI converts tview-tagged text to ANSI escape sequences, so the
+// colouring survives in terminals and `less -R`.
+func MarkupToANSI(s string) string {
+	var b strings.Builder
+	styled := false
+	walkMarkup(s, func(text string, st markupStyle) {
+		sgr := ansiSGR(st)
+		if sgr != "" {
+			b.WriteString("\x1b[0;" + sgr + "m")
+			styled = true
+		} else if styled {
+			b.WriteString("\x1b[0m")
+			styled = false
+		}
+		b.WriteString(text)
+	})
+	if styled {
+		b.WriteString("\x1b[0m")
+	}
+	return b.String()
+}
+
+// ansiSGR returns the SGR parameters for st, or "" for the default style.
+func ansiSGR(st markupStyle) string {
+	var p []string
+	for _, a := range st.attrs {
+		switch a {
+		case 'b':
+			p = append(p, "1")
+		case 'd':
+			p = append(p, "2")
+		case 'i':
+			p = append(p, "3")
+		case 'u':
+			p = append(p, "4")
+		case 'l':
+			p = append(p, "5")
+		case 'r':
+			p = append(p, "7")
+		case 's':
+			p = append(p, "9")
+		}
+	}
+	if c := ansiColour(st.fg, 30); c != "" {
+		p = append(p, c)
+	}
+	if c := ansiColour(st.bg, 40); c != "" {
+		p = append(p, c)
+	}
+	return strings.Join(p, ";")
+}
+
+// ansiColour returns the SGR parameter for a tview colour name; base is 30 for
+// foreground and 40 for background.
+func ansiColour(name string, base int) string {
+	if name == "" {
+		return ""
+	}
+	c := tcell.GetColor(name)
+	if !c.Valid() {
+		return ""
+	}
+	if c.IsRGB() {
+		r, g, bl := c.RGB()
+		return fmt.Sprintf("%d;2;%d;%d;%d", base+8, r, g, bl)
+	}
+	n := int(c - tcell.ColorValid)
+	switch {
+	case n < 8:
+		return strconv.Itoa(base + n)
+	case n < 16:
+		return strconv.Itoa(base + 60 + n - 8)
+	default:
+		return fmt.Sprintf("%d;5;%d", base+8, n)
+	}
+}
+
+// WriteANSI writes tview-tagged lines to w with ANSI colours.
+func WriteANSI(w io.Writer, lines []string) error {
+	for _, l := range lines {
+		if _, err := io.WriteString(w, MarkupToANSI(l)+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ANSIWriter is a headless output that applies a Config's highlight rules to
+// each line and writes it with ANSI colours. It is safe for concurrent use.
+type ANSIWriter struct {
+	mu       sync.Mutex
+	w        io.Writer
+	rules    []*highlightRule
+	noColour bool
+}
+
+// NewANSIWriter returns an ANSIWriter writing to w with the highlights in cfg.
+// With noColour set, lines are written as plain text.
+func NewANSIWriter(w io.Writer, cfg Config, noColour bool) *ANSIWriter {
+	return &ANSIWriter{w: w, rules: highlightRulesOf(cfg), noColour: noColour}
+}
+
+// SetConfig replaces the highlight rules.
+func (a *ANSIWriter) SetConfig(cfg Config) {
+	a.mu.Lock()
+	a.rules = highlightRulesOf(cfg)
+	a.mu.Unlock()
+}
+
+// WriteLine highlights and writes one line followed by a newline.
+func (a *ANSIWriter) WriteLine(line string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := applyHighlights(line, a.rules, a.noColour)
+	if a.noColour {
+		out = stripMarkup(out)
+	} else {
+		out = MarkupToANSI(out)
+	}
+	_, err := io.WriteString(a.w, out+"\n")
+	return err
+}
+
+// stripMarkup returns the visible text of tview-tagged s.
+func stripMarkup(s string) string {
+	var b strings.Builder
+	walkMarkup(s, func(text string, _ markupStyle) { b.WriteString(text) })
+	return b.String()
+}
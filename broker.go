@@ -2,12 +2,22 @@ package console
 
 import (
 	"bufio"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -15,138 +25,674 @@ type BrokerOptions struct {
 	Config           Config
 	SocketCandidates []string
 	ListenerFactory  func() (string, net.Listener, error)
+	// ListenAddr is a TCP address, such as ":7070", on which the broker also
+	// accepts clients. With no SocketCandidates or ListenerFactory the broker
+	// listens on TCP only. Connections are neither authenticated nor
+	// encrypted unless TLS is set.
+	ListenAddr string
+	// TLS, if set, secures the ListenAddr listener. UNIX sockets are
+	// unaffected.
+	TLS *TLSOptions
+	// HTTPAddr is a TCP address, such as ":8080", on which the broker also
+	// serves an HTTPBridge with the HTTPBridge options: the browser viewer,
+	// its WebSocket, the /stream Server-Sent Events endpoint and the /lines
+	// query. It is plain HTTP and asks Authenticate, if set, about every
+	// stream and query; see HTTPBridge.
+	HTTPAddr string
+	// HTTPBridge configures the HTTPAddr bridge, which is read-only and
+	// has no WebSocket by default; see HTTPBridgeOptions.Upgrade.
+	HTTPBridge *HTTPBridgeOptions
+	// AllowedUIDs and AllowedGIDs, when either is set, restrict which local
+	// users may attach through the UNIX socket: the peer's user ID must be
+	// in AllowedUIDs or one of its groups in AllowedGIDs (only the primary
+	// group on Linux). Others get a notice and are disconnected. The socket
+	// is then made accessible to all users, leaving the decision to this
+	// check rather than its mode. Supported on Linux, macOS and FreeBSD;
+	// elsewhere Start fails.
+	AllowedUIDs []int
+	AllowedGIDs []int
+	// Compress gzips the stream to TCP clients whose hello lists "gzip",
+	// for attachments over slow links.
+	Compress bool
+	// Authenticate, if set, is called with the Hello.Token of each TCP or
+	// gRPC client, from AttachOptions.Token. Clients it refuses, and those
+	// sending no hello within authTimeout, get an "auth failed" notice and
+	// are disconnected before seeing any lines. HTTPBridge streams and
+	// queries are asked as their request arrives, with ID 0, and refused
+	// with 401. Compare tokens with
+	// crypto/subtle.ConstantTimeCompare. UNIX socket clients are not
+	// asked; see AllowedUIDs.
+	Authenticate func(info ClientInfo, token string) bool
+
+	// OnClientConnect is called after a new client has received meta and the
+	// replayed ring. It runs on the client's goroutine, so it may call Append,
+	// or Disconnect to enforce a policy on the ClientInfo.
+	OnClientConnect func(ClientInfo)
+	// OnClientDisconnect is called once a client's connection has been
+	// closed, for the clients OnClientConnect was called for; those failing
+	// authentication or the replay are reported by neither.
+	OnClientDisconnect func(ClientInfo)
+
+	// ComputeSpans evaluates Config.Highlights on the broker and attaches
+	// the resulting style spans to every line, for clients that do not
+	// implement highlighting themselves.
+	ComputeSpans bool
+
+	// LevelFunc classifies appended lines for Line.Level, which clients
+	// colour and filter by. Lines it returns "" for, and all lines when it
+	// is nil, are classified by LevelOf. DetectLevel handles logrus, zap
+	// and syslog output.
+	LevelFunc LevelFunc
+
+	// CounterInterval is how often clients are sent the values of
+	// Config.Counters; default DefaultCounterInterval.
+	CounterInterval time.Duration
+
+	// MergeWindow holds appended lines for this long and releases them in
+	// timestamp order, so lines from several Sources interleave correctly.
+	// Zero delivers lines in arrival order. Stop releases the lines still
+	// held.
+	MergeWindow time.Duration
+
+	// PersistPath, if set, is a file the ring is saved to as lines are
+	// appended and restored from on startup, so clients attaching after a
+	// restart still get recent history. Line IDs continue from the saved
+	// ones.
+	PersistPath string
+
+	// FileSink, if set, also writes every appended line to rotated NDJSON
+	// files, a durable log kept whether or not a console is attached.
+	FileSink *FileSinkOptions
+	// Syslog, if set, forwards every appended line to a syslog daemon while
+	// the broker runs. Do not forward to a daemon whose messages come back
+	// through ListenSyslog.
+	Syslog *SyslogOptions
+	// OnLine, if set, is called with every line as it is added to the
+	// ring, in order, for exporters such as otelconsole's. It runs with the
+	// ring locked, so it must not block or call into the broker.
+	OnLine func(Line)
+
+	// OnCommand receives command frames typed into attached clients. It runs
+	// on the client's reader goroutine; use Notify to answer. When nil,
+	// commands are refused with a notice.
+	OnCommand func(clientID uint64, cmd string)
+
+	// DropPolicy decides what happens to lines for a client whose send
+	// queue is full; the default is DropOldest. BlockTimeout bounds the wait
+	// of BlockWithTimeout; default DefaultBlockTimeout.
+	DropPolicy   DropPolicy
+	BlockTimeout time.Duration
+	// PriorityLevels are the level classes ("error", "warn", "info",
+	// "debug") of lines that are never dropped for a slow client while its
+	// queue holds other lines that can be dropped instead; default "error".
+	PriorityLevels []string
+
+	// MaxClients caps the attached clients; further ones get a notice and
+	// are disconnected. Zero means unlimited.
+	MaxClients int
+
+	// AuthorizePublish decides whether a client may append lines with
+	// publish frames. When nil, publishing is refused.
+	AuthorizePublish func(ClientInfo) bool
+
+	// OnThreshold is called when one of Config.Counters goes over its
+	// CounterSpec.Threshold, with the count in its threshold window, for
+	// external alerting. It is called again only after the count has
+	// dropped back, and runs on the goroutine appending the line.
+	OnThreshold func(spec CounterSpec, count int)
+}
+
+// ClientInfo describes an attached viewer.
+type ClientInfo struct {
+	ID          uint64
+	RemoteAddr  string
+	ConnectedAt time.Time
+	// Transport is how the client connected: "unix", "tcp", "tls", "http"
+	// for the HTTPBridge, or the ConnOptions.Transport of a ServeConn
+	// client, such as "grpc" for grpcconsole.
+	Transport string
+	// UID and GID are the user and primary group of a client on a UNIX
+	// socket, where the platform reports them; -1 otherwise.
+	UID int
+	GID int
+	// Client and Version are from the client's hello; empty and 0 for
+	// clients that sent none, and in AuthorizePublish or Authenticate
+	// called before it was read.
+	Client  string
+	Version int
 }
 
 type Broker struct {
+	cfgMu    sync.Mutex // guards cfg, meta and spans, which UpdateConfig replaces
 	cfg      Config
-	metaBuf  []byte
+	meta     Meta
+	spans    []spanRule // cfg.Highlights compiled, with ComputeSpans
 	maxLines int
 
 	ringMu   sync.Mutex
 	clients  map[*client]struct{}
-	ring     [][]byte
+	ring     []ringEntry
+	seq      uint64 // last assigned Line.Seq
 	head     int
+	count    int // entries held, the oldest count before head
 	capacity int
+	bytes    int // sum of the entries' sizes
+	maxBytes int // Config.MaxBytes; 0 for no cap
 
 	stateMu          sync.Mutex
 	running          bool
 	listener         net.Listener
 	socketPath       string
+	stopCh           chan struct{}
 	listenerFactory  func() (string, net.Listener, error)
 	socketCandidates []string
+	listenAddr       string
+	tls              *TLSOptions
+	tcpListener      net.Listener
+	httpAddr         string
+	httpBridge       *HTTPBridgeOptions
+	httpServer       *http.Server
+	httpListener     net.Listener
+	compress         bool
+	allowedUIDs      []int
+	allowedGIDs      []int
+	authenticate     func(ClientInfo, string) bool
+
+	nextClientID       uint64
+	onClientConnect    func(ClientInfo)
+	onClientDisconnect func(ClientInfo)
+	computeSpans       bool
+	levelFunc          LevelFunc
+	maxClients         int
+	onCommand          func(clientID uint64, cmd string)
+
+	mergeWindow time.Duration
+	mergeMu     sync.Mutex
+	pending     mergeHeap
+	mergeOrder  uint64
+	flushing    bool
+	releaseMu   sync.Mutex // serializes taking lines off pending and committing them
+
+	authorizePublish func(ClientInfo) bool
+	onThreshold      func(CounterSpec, int)
+
+	sentBytes uint64 // line frame bytes queued to clients; guarded by ringMu
+
+	dropPolicy      DropPolicy
+	blockTimeout    time.Duration
+	priorityLevels  []string
+	slowDisconnects uint64 // clients dropped by DisconnectSlow; guarded by ringMu
+
+	// counters are evaluated over appended lines for metrics and clients
+	counterMu       sync.Mutex
+	counters        []*counterRule
+	counterInterval time.Duration
+
+	store       *ringStore // nil unless PersistPath is set; guarded by ringMu
+	persistErr  error      // opening the store failed; reported by Start
+	restoredSeq uint64     // last line ID restored from the store
+	sink        *fileSink  // nil unless FileSink is set; guarded by ringMu
+	sinkErr     error      // opening the sink failed; reported by Start
+	syslog      *syslogForwarder
+	onLine      func(Line)
 }
 
 type client struct {
+	info ClientInfo
 	conn net.Conn
 	bw   *bufio.Writer
-	ch   chan []byte
+	gz   *gzip.Writer // under bw once the replay switched to compression
+	ch   chan queued
+	done chan struct{}
+	// sendMu serializes the goroutines queueing on ch, so room made in
+	// the queue is not taken by another sender; only the writer takes
+	// from it otherwise
+	sendMu sync.Mutex
+
+	// delivery statistics, guarded by ringMu
+	dropped    uint64
+	highWater  int
+	unreported uint64 // dropped lines the client has not been told about
+	lagging    bool   // BlockWithTimeout timed out on it; guarded by sendMu
+
+	// greeted is closed once the client has sent its first frame, which
+	// for current clients is their hello
+	greeted   chan struct{}
+	greetOnce sync.Once
+	// left is closed when readClient returns
+	left chan struct{}
+	// single is set when the client's hello does not list "lines", so
+	// frames are not batched for it
+	single atomic.Bool
+	// sinceUs is the hello's SinceUs: older lines are left out of the replay
+	sinceUs atomic.Int64
+	// seesGaps is set for version 2 clients, which notice skipped line IDs
+	// themselves and need no notice about dropped lines
+	seesGaps atomic.Bool
+	// filter is the client's Filter, nil to send every line
+	filter atomic.Pointer[filterExpr]
+	// counters is set when the client's hello lists "counters"
+	counters atomic.Bool
+	// viewers is set when the client's hello lists "viewers"
+	viewers atomic.Bool
+	// wantsLength is set when the hello lists "length_prefix"; framed once
+	// the replay switched the connection to it
+	wantsLength atomic.Bool
+	framed      atomic.Bool
+	// wantsGzip is set when the hello lists "gzip"
+	wantsGzip atomic.Bool
+	// authed is set once the hello's token passed Authenticate
+	authed atomic.Bool
+	// hello is the client's first hello
+	hello atomic.Pointer[Hello]
 }
 
-func NewBroker(opts BrokerOptions) *Broker {
-	cfg := Config{
-		MaxLines:   opts.Config.MaxLines,
-		Counters:   append([]CounterSpec(nil), opts.Config.Counters...),
-		Highlights: make([]HighlightSpec, 0, len(opts.Config.Highlights)),
+// infoNow returns the client's info with what its hello said.
+func (cli *client) infoNow() ClientInfo {
+	info := cli.info
+	if h := cli.hello.Load(); h != nil {
+		info.Client, info.Version = h.Client, h.Version
+	}
+	return info
+}
+
+// flush writes out what the client's writers buffer.
+func (cli *client) flush() error {
+	if err := cli.bw.Flush(); err != nil {
+		return err
+	}
+	if cli.gz != nil {
+		return cli.gz.Flush()
+	}
+	return nil
+}
+
+// helloWait is how long the broker waits for a client's hello before
+// replaying the ring; clients that send none predate it.
+const helloWait = 100 * time.Millisecond
+
+// authTimeout is how long a client that must authenticate has to send its
+// hello.
+const authTimeout = 5 * time.Second
+
+// isTCP reports whether conn came from the TCP listener, with or without
+// TLS, rather than a UNIX socket or the HTTP bridge.
+func isTCP(conn net.Conn) bool {
+	_, ok := conn.LocalAddr().(*net.TCPAddr)
+	return ok
+}
+
+// mustAuthenticate reports whether cli has to pass Authenticate.
+func (b *Broker) mustAuthenticate(cli *client) bool {
+	if b.authenticate == nil {
+		return false
 	}
-	for _, h := range opts.Config.Highlights {
+	if sc, ok := cli.conn.(*servedConn); ok {
+		return sc.opts.Authenticate
+	}
+	return isTCP(cli.conn)
+}
+
+// brokerConfig copies cfg for a broker to own.
+func brokerConfig(cfg Config) Config {
+	out := Config{
+		MaxLines:   cfg.MaxLines,
+		MaxBytes:   cfg.MaxBytes,
+		Counters:   append([]CounterSpec(nil), cfg.Counters...),
+		Highlights: make([]HighlightSpec, 0, len(cfg.Highlights)),
+	}
+	for _, h := range cfg.Highlights {
 		cp := h
 		if h.Style != nil {
 			st := *h.Style
 			cp.Style = &st
 		}
-		cfg.Highlights = append(cfg.Highlights, cp)
+		out.Highlights = append(out.Highlights, cp)
 	}
-
-	if cfg.MaxLines <= 0 {
-		cfg.MaxLines = DefaultMaxLines
+	if out.MaxLines <= 0 {
+		out.MaxLines = DefaultMaxLines
 	}
+	return out
+}
 
-	meta := MakeMeta(cfg)
-	metaBytes, _ := json.Marshal(meta)
-	metaBytes = append(metaBytes, '\n')
+func NewBroker(opts BrokerOptions) *Broker {
+	cfg := brokerConfig(opts.Config)
 
 	size := cfg.EffectiveMaxLines()
 	candidates := append([]string(nil), opts.SocketCandidates...)
+	meta := MakeMeta(cfg)
+	meta.StartedUs = time.Now().UnixMicro()
+	meta.Version = ProtocolVersion
+	meta.Capabilities = []string{"lines", "history", "stats", "channels", "fields", "filter", "viewers", framingLength}
+	if opts.ComputeSpans {
+		meta.Capabilities = append(meta.Capabilities, "spans")
+	}
+	if opts.AuthorizePublish != nil {
+		meta.Capabilities = append(meta.Capabilities, "publish")
+	}
+	if opts.OnCommand != nil {
+		meta.Capabilities = append(meta.Capabilities, "command")
+	}
+	if opts.Compress {
+		meta.Capabilities = append(meta.Capabilities, compressionGzip)
+	}
+	if len(cfg.Counters) > 0 {
+		meta.Capabilities = append(meta.Capabilities, "counters")
+	}
 
-	return &Broker{
+	b := &Broker{
 		cfg:              cfg,
-		metaBuf:          metaBytes,
+		meta:             meta,
 		maxLines:         size,
 		clients:          make(map[*client]struct{}),
-		ring:             make([][]byte, size),
+		ring:             make([]ringEntry, size),
 		capacity:         size,
+		maxBytes:         max(cfg.MaxBytes, 0),
 		listenerFactory:  opts.ListenerFactory,
 		socketCandidates: candidates,
+		listenAddr:       strings.TrimSpace(opts.ListenAddr),
+		httpAddr:         strings.TrimSpace(opts.HTTPAddr),
+		httpBridge:       opts.HTTPBridge,
+		tls:              opts.TLS,
+		compress:         opts.Compress,
+		allowedUIDs:      slices.Clone(opts.AllowedUIDs),
+		allowedGIDs:      slices.Clone(opts.AllowedGIDs),
+		authenticate:     opts.Authenticate,
+
+		onClientConnect:    opts.OnClientConnect,
+		onClientDisconnect: opts.OnClientDisconnect,
+		computeSpans:       opts.ComputeSpans,
+		levelFunc:          opts.LevelFunc,
+		maxClients:         opts.MaxClients,
+		onCommand:          opts.OnCommand,
+		mergeWindow:        opts.MergeWindow,
+		authorizePublish:   opts.AuthorizePublish,
+		onThreshold:        opts.OnThreshold,
+		onLine:             opts.OnLine,
+		counterInterval:    cmp.Or(opts.CounterInterval, DefaultCounterInterval),
+		dropPolicy:         opts.DropPolicy,
+		blockTimeout:       cmp.Or(opts.BlockTimeout, DefaultBlockTimeout),
+		priorityLevels:     opts.PriorityLevels,
+	}
+	if b.priorityLevels == nil {
+		b.priorityLevels = []string{"error"}
 	}
+	if b.computeSpans {
+		b.spans = compileSpanRules(cfg.Highlights)
+	}
+	for _, spec := range cfg.Counters {
+		b.counters = append(b.counters, newCounterRule(spec))
+	}
+	if opts.PersistPath != "" {
+		store, entries, lastSeq, err := openRingStore(opts.PersistPath, size)
+		if err != nil {
+			b.persistErr = fmt.Errorf("console broker: persist: %w", err)
+		} else {
+			b.store = store
+			for _, e := range entries {
+				b.enqueueLocked(e)
+			}
+			b.seq, b.restoredSeq = lastSeq, lastSeq
+		}
+	}
+	if opts.FileSink != nil {
+		b.sink, b.sinkErr = openFileSink(*opts.FileSink)
+	}
+	if opts.Syslog != nil {
+		b.syslog = newSyslogForwarder(*opts.Syslog)
+	}
+	return b
 }
 
 func (b *Broker) Start() error {
+	return b.StartContext(context.Background())
+}
+
+// StartContext is like Start but stops the broker when ctx is cancelled.
+func (b *Broker) StartContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b.persistErr != nil {
+		return b.persistErr
+	}
+	if b.sinkErr != nil {
+		return b.sinkErr
+	}
+	if err := b.checkPeerSupport(); err != nil {
+		return err
+	}
+
 	var (
-		path string
-		ln   net.Listener
-		err  error
+		path   string
+		ln     net.Listener
+		tcpLn  net.Listener
+		tlsCfg *tls.Config
+		err    error
 	)
 
-	if b.listenerFactory != nil {
+	// check the TLS files before taking any socket
+	if b.listenAddr != "" && b.tls != nil {
+		if tlsCfg, err = b.tls.serverConfig(); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case b.listenerFactory != nil:
 		path, ln, err = b.listenerFactory()
-	} else {
+	case len(b.socketCandidates) > 0 || b.listenAddr == "":
 		path, ln, err = listenFirstAvailable(b.socketCandidates)
 	}
 	if err != nil {
 		return err
 	}
-	_ = os.Chmod(path, 0o600)
+	if path != "" {
+		mode := os.FileMode(0o600)
+		if b.restrictsPeers() {
+			mode = 0o666
+		}
+		_ = os.Chmod(path, mode)
+	}
+	abort := func(err error) error {
+		for _, l := range []net.Listener{ln, tcpLn} {
+			if l != nil {
+				_ = l.Close()
+			}
+		}
+		if path != "" {
+			_ = os.Remove(path)
+		}
+		return fmt.Errorf("console broker: %w", err)
+	}
+	if b.listenAddr != "" {
+		tcpLn, err = net.Listen("tcp", b.listenAddr)
+		if err != nil {
+			return abort(err)
+		}
+		if tlsCfg != nil {
+			tcpLn = tls.NewListener(tcpLn, tlsCfg)
+		}
+	}
+	var (
+		httpLn  net.Listener
+		httpSrv *http.Server
+	)
+	if b.httpAddr != "" {
+		httpLn, err = net.Listen("tcp", b.httpAddr)
+		if err != nil {
+			return abort(err)
+		}
+		httpSrv = &http.Server{Handler: NewHTTPBridge(b, b.httpBridge), ReadHeaderTimeout: 10 * time.Second}
+	}
 
+	stopCh := make(chan struct{})
 	b.stateMu.Lock()
 	b.running = true
 	b.listener = ln
 	b.socketPath = path
+	b.tcpListener = tcpLn
+	b.httpListener = httpLn
+	b.httpServer = httpSrv
+	b.stopCh = stopCh
 	b.stateMu.Unlock()
 
-	go func() {
-		for {
-			c, err := ln.Accept()
-			if err != nil {
-				b.stateMu.Lock()
-				running := b.running
-				b.stateMu.Unlock()
-				if !running {
-					return
-				}
-				continue
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				b.Stop()
+			case <-stopCh:
 			}
-			b.handleNewClient(c)
+		}()
+	}
+
+	for _, l := range []net.Listener{ln, tcpLn} {
+		if l != nil {
+			go b.acceptLoop(l)
 		}
-	}()
+	}
+	if httpSrv != nil {
+		go func() { _ = httpSrv.Serve(httpLn) }()
+	}
+	if b.store != nil || b.sink != nil {
+		go b.flushStore(stopCh)
+	}
+	if b.syslog != nil {
+		go b.syslog.run(stopCh)
+	}
+	go b.sendCounters(stopCh)
 
 	return nil
 }
 
+func (b *Broker) acceptLoop(ln net.Listener) {
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			b.stateMu.Lock()
+			running := b.running
+			b.stateMu.Unlock()
+			if !running {
+				return
+			}
+			continue
+		}
+		if tc, ok := c.(*tls.Conn); ok {
+			// handshake off the accept loop so a slow peer cannot stall it
+			go b.handshakeClient(tc)
+			continue
+		}
+		b.handleNewClient(c)
+	}
+}
+
+// handshakeClient completes the TLS handshake before registering the client,
+// so clients failing verification never see any lines.
+func (b *Broker) handshakeClient(c *tls.Conn) {
+	_ = c.SetDeadline(time.Now().Add(tlsHandshakeTimeout))
+	if err := c.Handshake(); err != nil {
+		_ = c.Close()
+		return
+	}
+	_ = c.SetDeadline(time.Time{})
+	b.handleNewClient(c)
+}
+
 func (b *Broker) Stop() {
 	b.stateMu.Lock()
 	ln := b.listener
+	tcpLn := b.tcpListener
+	httpSrv := b.httpServer
 	path := b.socketPath
+	stopCh := b.stopCh
 	b.running = false
 	b.listener = nil
+	b.tcpListener = nil
+	b.httpListener = nil
+	b.httpServer = nil
 	b.socketPath = ""
+	b.stopCh = nil
 	b.stateMu.Unlock()
 
+	if stopCh != nil {
+		close(stopCh)
+	}
+
 	if ln != nil {
 		_ = ln.Close()
 	}
+	if tcpLn != nil {
+		_ = tcpLn.Close()
+	}
+	if httpSrv != nil {
+		_ = httpSrv.Close()
+	}
 	if path != "" {
 		_ = os.Remove(path)
 	}
 
+	b.drainPending()
 	b.ringMu.Lock()
+	if b.store != nil {
+		b.store.flush()
+	}
+	if b.sink != nil {
+		b.sink.flush()
+	}
 	for cli := range b.clients {
-		_ = cli.bw.Flush()
+		_ = cli.flush()
 		_ = cli.conn.Close()
+		close(cli.done)
 		delete(b.clients, cli)
 	}
 	b.ringMu.Unlock()
 }
 
+// SocketPath returns the path the broker is listening on, or "" when stopped.
+func (b *Broker) SocketPath() string {
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+	return b.socketPath
+}
+
+// TCPAddr returns the TCP address the broker is listening on, or "" when it
+// is stopped or ListenAddr is unset. With a ":0" ListenAddr it reports the
+// port actually chosen.
+func (b *Broker) TCPAddr() string {
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+	if b.tcpListener == nil {
+		return ""
+	}
+	return b.tcpListener.Addr().String()
+}
+
+// HTTPAddr returns the address of the HTTPAddr listener, or "" when the
+// broker is stopped or HTTPAddr is unset.
+func (b *Broker) HTTPAddr() string {
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+	if b.httpListener == nil {
+		return ""
+	}
+	return b.httpListener.Addr().String()
+}
+
+// Running reports whether the broker is currently accepting clients.
+func (b *Broker) Running() bool {
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+	return b.running
+}
+
+// ClientCount returns the number of currently attached clients.
+func (b *Broker) ClientCount() int {
+	b.ringMu.Lock()
+	defer b.ringMu.Unlock()
+	return len(b.clients)
+}
+
 func (b *Broker) Append(line string) {
 	b.appendWithWhen(time.Now(), line)
 }
@@ -155,112 +701,891 @@ func (b *Broker) Appendf(format string, args ...any) {
 	b.Append(fmt.Sprintf(format, args...))
 }
 
+// AppendTo appends a line to the named channel.
+func (b *Broker) AppendTo(channel, line string) {
+	b.submit(Line{Type: "line", TsUs: time.Now().UnixMicro(), Text: line, Channel: channel})
+}
+
+// AppendfTo appends a formatted line to the named channel.
+func (b *Broker) AppendfTo(channel, format string, args ...any) {
+	b.AppendTo(channel, fmt.Sprintf(format, args...))
+}
+
+// AppendFields appends msg with structured fields, e.g.
+// AppendFields("lease granted", map[string]string{"mac": mac, "ip": ip}).
+func (b *Broker) AppendFields(msg string, fields map[string]string) {
+	b.submit(Line{Type: "line", TsUs: time.Now().UnixMicro(), Text: msg, Fields: maps.Clone(fields)})
+}
+
+// AppendLevel appends a line with an explicit level, such as "warn" or
+// "debug", instead of one detected from its text.
+func (b *Broker) AppendLevel(level, line string) {
+	b.submit(Line{Type: "line", TsUs: time.Now().UnixMicro(), Text: line, Level: level})
+}
+
+// AppendLines appends several lines at once, stamped with the same time.
+// They are stored and sent to clients under a single lock acquisition,
+// which is cheaper than calling Append for each when producing in bulk.
+func (b *Broker) AppendLines(lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	ts := time.Now().UnixMicro()
+	evs := make([]Line, len(lines))
+	for i, line := range lines {
+		evs[i] = Line{Type: "line", TsUs: ts, Text: line}
+	}
+	b.submit(evs...)
+}
+
 func (b *Broker) appendWithWhen(when time.Time, line string) {
-	ev := Line{Type: "line", TsUs: when.UnixMicro(), Text: line, Level: LevelOf(line)}
+	b.submit(Line{Type: "line", TsUs: when.UnixMicro(), Text: line})
+}
+
+// commit assigns each line its sequence number, stores it in the ring and
+// sends it to every client. Lines without a level get one from their text.
+func (b *Broker) commit(evs ...Line) {
+	var rules []spanRule
+	if b.computeSpans {
+		b.cfgMu.Lock()
+		rules = b.spans
+		b.cfgMu.Unlock()
+	}
+	for i := range evs {
+		ev := &evs[i]
+		ev.Text = truncateLineText(ev.Text)
+		if ev.Level == "" {
+			ev.Level = b.levelOf(ev.Text)
+		}
+		if b.computeSpans {
+			ev.Spans = spansOf(ev.Text, rules)
+		}
+		fitLineFrame(ev)
+		b.observeCounters(ev.Text)
+	}
+
+	// assign the sequence number under the ring lock so seq order, ring
+	// order and delivery order agree
+	b.ringMu.Lock()
+	defer b.ringMu.Unlock()
+	for _, ev := range evs {
+		b.seq++
+		ev.Seq = b.seq
+		e := newRingEntry(ev, slices.Contains(b.priorityLevels, LevelClass(ev.Level)))
+		b.enqueueLocked(e)
+		// marshal once for the files and clients, and only if one takes it
+		var buf []byte
+		if b.store != nil || b.sink != nil || len(b.clients) > 0 {
+			buf = lineFrame(ev)
+		}
+		if b.store != nil {
+			b.store.append(buf, b.entriesLocked)
+		}
+		if b.sink != nil {
+			b.sink.write(buf)
+		}
+		if b.syslog != nil {
+			b.syslog.send(ev)
+		}
+		if b.onLine != nil {
+			b.onLine(ev)
+		}
+		b.broadcastLocked(e, buf)
+	}
+}
+
+// ringEntry is a line as held in the ring, with its text for client
+// filters. Line frames are marshalled from it when they are sent.
+type ringEntry struct {
+	ev       Line
+	text     string
+	size     int  // estimated bytes held, counted against Config.MaxBytes
+	priority bool // see BrokerOptions.PriorityLevels
+}
+
+func newRingEntry(ev Line, priority bool) ringEntry {
+	e := ringEntry{ev: ev, text: filterText(ev), priority: priority}
+	// struct and map overheads are rough; the strings dominate
+	e.size = 128 + len(ev.Text) + len(ev.Level) + len(ev.Source) + len(ev.Channel) + 16*len(ev.Spans)
+	for k, v := range ev.Fields {
+		e.size += 32 + len(k) + len(v)
+	}
+	if ev.Source != "" {
+		e.size += len(e.text) // the prefixed copy
+	}
+	return e
+}
+
+// lineFrame marshals ev as a line frame.
+func lineFrame(ev Line) []byte {
 	buf, _ := json.Marshal(ev)
-	buf = append(buf, '\n')
+	return append(buf, '\n')
+}
 
-	b.enqueue(buf)
-	b.broadcast(buf)
+// ConnOptions describe a connection handed to ServeConn.
+type ConnOptions struct {
+	// Transport names how the client connected, for ClientInfo.Transport,
+	// such as "grpc".
+	Transport string
+	// RemoteAddr is the peer's address, for ClientInfo.RemoteAddr.
+	RemoteAddr string
+	// Authenticate makes the client pass Authenticate with the token of its
+	// hello, as TCP clients do. Leave it unset for connections the
+	// transport has authenticated already.
+	Authenticate bool
 }
 
+// ServeConn makes conn, one end of a stream from a transport the broker
+// does not listen on itself, such as a gRPC call or a WebSocket, an
+// ordinary client speaking the NDJSON protocol, so MaxClients, the connect
+// callbacks and Stats apply. It returns at once; the broker closes conn
+// when the client goes. The grpcconsole package serves gRPC clients with
+// it.
+func (b *Broker) ServeConn(conn net.Conn, opts ConnOptions) {
+	b.handleNewClient(&servedConn{Conn: conn, opts: opts})
+}
+
+// servedConn is a connection from ServeConn.
+type servedConn struct {
+	net.Conn
+	opts ConnOptions
+}
+
+func (c *servedConn) RemoteAddr() net.Addr {
+	return servedAddr{network: c.opts.Transport, addr: c.opts.RemoteAddr}
+}
+
+type servedAddr struct{ network, addr string }
+
+func (a servedAddr) Network() string { return a.network }
+func (a servedAddr) String() string  { return a.addr }
+
 func (b *Broker) handleNewClient(conn net.Conn) {
+	cred, notice, ok := b.authorizePeer(conn)
+	if !ok {
+		go rejectClient(conn, notice)
+		return
+	}
 	b.ringMu.Lock()
-	if len(b.clients) >= 5 {
+	if b.maxClients > 0 && len(b.clients) >= b.maxClients {
 		b.ringMu.Unlock()
-		_ = conn.Close()
+		go rejectClient(conn, serverFullNotice(b.maxClients))
 		return
 	}
+	b.nextClientID++
+	id := b.nextClientID
+	b.ringMu.Unlock()
 	cli := &client{
-		conn: conn,
-		bw:   bufio.NewWriterSize(conn, 64<<10),
-		ch:   make(chan []byte, 512),
+		info: ClientInfo{
+			ID:          id,
+			RemoteAddr:  remoteAddrString(conn),
+			ConnectedAt: time.Now(),
+			Transport:   transportOf(conn),
+			UID:         cred.uid,
+			GID:         cred.gid(),
+		},
+		conn:    conn,
+		bw:      bufio.NewWriterSize(conn, 64<<10),
+		ch:      make(chan queued, 512),
+		done:    make(chan struct{}),
+		greeted: make(chan struct{}),
+		left:    make(chan struct{}),
 	}
-	b.clients[cli] = struct{}{}
-	b.ringMu.Unlock()
+	go b.readClient(cli)
 
 	go func() {
+		connected := false // OnClientDisconnect only follows OnClientConnect
 		defer func() {
-			b.ringMu.Lock()
-			delete(b.clients, cli)
-			b.ringMu.Unlock()
+			b.dropClient(cli)
 			_ = conn.Close()
+			if connected && b.onClientDisconnect != nil {
+				b.onClientDisconnect(cli.infoNow())
+			}
 		}()
 
-		if err := b.safeSend(cli, b.metaBuf); err != nil {
+		wait := helloWait
+		if b.mustAuthenticate(cli) {
+			wait = authTimeout
+		}
+		select {
+		case <-cli.greeted:
+		case <-time.After(wait):
+		case <-cli.left:
+			return
+		}
+		if b.mustAuthenticate(cli) && !cli.authed.Load() {
+			_ = conn.SetWriteDeadline(time.Now().Add(time.Second))
+			_, _ = conn.Write(noticeFrame("[notice] auth failed"))
 			return
 		}
+		snapshot, ok := b.registerClient(cli)
+		if !ok {
+			return
+		}
+		if err := b.replay(cli, snapshot); err != nil {
+			return
+		}
+		if buf := b.countersFrame(); buf != nil && cli.counters.Load() {
+			b.trySend(cli, buf)
+		}
+		if cli.viewers.Load() {
+			b.ringMu.Lock()
+			_ = b.safeSend(cli, viewersFrame(len(b.clients)))
+			b.ringMu.Unlock()
+		}
 
-		b.replay(cli)
+		connected = true
+		if b.onClientConnect != nil {
+			b.onClientConnect(cli.infoNow())
+		}
 
-		for msg := range cli.ch {
-			if _, err := cli.bw.Write(msg); err != nil {
-				return
-			}
-			if err := cli.bw.Flush(); err != nil {
+		batch := make([][]byte, 0, maxBatchFrames)
+		for {
+			select {
+			case msg := <-cli.ch:
+				batch = append(batch[:0], msg.buf)
+				last := msg.last
+			drain:
+				for !last && len(batch) < maxBatchFrames {
+					select {
+					case more := <-cli.ch:
+						batch = append(batch, more.buf)
+						last = more.last
+					default:
+						break drain
+					}
+				}
+				if err := writeBatch(cli.bw, batch, !cli.single.Load(), cli.framed.Load()); err != nil {
+					return
+				}
+				if err := cli.flush(); err != nil || last {
+					return
+				}
+			case <-cli.done:
 				return
 			}
 		}
 	}()
 }
 
-func (b *Broker) replay(cli *client) {
+// registerClient attaches cli once its hello has passed, so peers that
+// never authenticate take no MaxClients slot and see no lines, and returns
+// the ring to replay. A full broker gets cli a notice. It reports false if
+// cli is not to be served.
+func (b *Broker) registerClient(cli *client) ([]ringEntry, bool) {
+	if !b.Running() {
+		return nil, false
+	}
+	b.ringMu.Lock()
+	select {
+	case <-cli.left:
+		b.ringMu.Unlock()
+		return nil, false // readClient has gone and will not drop it
+	default:
+	}
+	if b.maxClients > 0 && len(b.clients) >= b.maxClients {
+		b.ringMu.Unlock()
+		rejectClient(cli.conn, serverFullNotice(b.maxClients))
+		return nil, false
+	}
+	// register and snapshot under one lock so every line is delivered
+	// exactly once: either in the replay or through the queue
+	b.clients[cli] = struct{}{}
+	snapshot := b.entriesLocked()
+	b.broadcastViewersLocked()
+	b.ringMu.Unlock()
+	return snapshot, true
+}
+
+// dropClient unregisters cli and stops its writer. It is safe to call more
+// than once.
+func (b *Broker) dropClient(cli *client) {
 	b.ringMu.Lock()
 	defer b.ringMu.Unlock()
-	for i := 0; i < b.capacity; i++ {
-		idx := (b.head + i) % b.capacity
-		if b.ring[idx] != nil {
-			_ = b.safeSend(cli, b.ring[idx])
+	b.removeClientLocked(cli)
+}
+
+// removeClientLocked unregisters cli, stops its writer, closes its
+// connection, which the writer may be stuck writing to, and sends the new
+// viewer count to the others. It reports false if cli was not attached.
+// Callers hold b.ringMu.
+func (b *Broker) removeClientLocked(cli *client) bool {
+	if _, ok := b.clients[cli]; !ok {
+		return false
+	}
+	delete(b.clients, cli)
+	close(cli.done)
+	_ = cli.conn.Close()
+	b.broadcastViewersLocked()
+	return true
+}
+
+// broadcastViewersLocked sends the number of attached clients to those
+// that show it. Callers hold b.ringMu.
+func (b *Broker) broadcastViewersLocked() {
+	buf := viewersFrame(len(b.clients))
+	for cli := range b.clients {
+		if cli.viewers.Load() {
+			_ = b.safeSend(cli, buf)
 		}
 	}
 }
 
-func (b *Broker) enqueue(buf []byte) {
+// viewersFrame marshals a viewers frame.
+func viewersFrame(n int) []byte {
+	buf, _ := json.Marshal(Viewers{Type: "viewers", Count: n})
+	return append(buf, '\n')
+}
+
+// readClient handles frames sent by a client until it disconnects.
+func (b *Broker) readClient(cli *client) {
+	defer func() {
+		close(cli.left)
+		b.dropClient(cli)
+	}()
+	fr := newFrameReader(cli.conn)
+	var publishAllowed, publishChecked bool
+	for {
+		buf, err := fr.next()
+		if err != nil {
+			return
+		}
+		typ, buf := fr.peekFrameType(buf)
+		if typ != "hello" {
+			cli.greetOnce.Do(func() { close(cli.greeted) })
+		}
+		if typ == "hello" {
+			var h Hello
+			if json.Unmarshal(buf, &h) == nil {
+				cli.single.Store(!slices.Contains(h.Capabilities, "lines"))
+				cli.sinceUs.Store(h.SinceUs)
+				cli.seesGaps.Store(h.Version >= 2)
+				cli.counters.Store(slices.Contains(h.Capabilities, "counters"))
+				cli.viewers.Store(slices.Contains(h.Capabilities, "viewers"))
+				cli.wantsLength.Store(slices.Contains(h.Capabilities, framingLength))
+				cli.wantsGzip.Store(slices.Contains(h.Capabilities, compressionGzip))
+				cli.hello.CompareAndSwap(nil, &h)
+				if b.mustAuthenticate(cli) && !cli.authed.Load() {
+					cli.authed.Store(b.authenticate(cli.infoNow(), h.Token))
+				}
+			}
+			cli.greetOnce.Do(func() { close(cli.greeted) })
+			continue
+		}
+		if b.mustAuthenticate(cli) && !cli.authed.Load() {
+			continue // the writer disconnects it
+		}
+		switch typ {
+		case "history_request":
+			var req HistoryRequest
+			if json.Unmarshal(buf, &req) == nil {
+				_ = b.safeSend(cli, b.historyFrame(req, cli.filter.Load()))
+			}
+		case "filter":
+			var f Filter
+			if json.Unmarshal(buf, &f) != nil {
+				continue
+			}
+			if e, ok := parseFilter(f.Text, f.CaseSensitive, f.Regex); ok {
+				cli.filter.Store(&e)
+			} else {
+				cli.filter.Store(nil)
+			}
+		case "stats_request":
+			buf, _ := json.Marshal(b.Stats())
+			_ = b.safeSend(cli, append(buf, '\n'))
+		case "publish":
+			if !publishChecked {
+				publishChecked = true
+				publishAllowed = b.authorizePublish != nil && b.authorizePublish(cli.info)
+				if !publishAllowed {
+					_ = b.safeSend(cli, noticeFrame("[notice] publishing is not allowed for this client"))
+				}
+			}
+			var p Publish
+			if !publishAllowed || json.Unmarshal(buf, &p) != nil {
+				continue
+			}
+			b.publish(cli, p)
+		case "command":
+			var c Command
+			if json.Unmarshal(buf, &c) != nil || c.Text == "" {
+				continue
+			}
+			if b.onCommand == nil {
+				_ = b.safeSend(cli, noticeFrame("[notice] commands are not accepted by this server"))
+				continue
+			}
+			b.onCommand(cli.info.ID, c.Text)
+		}
+	}
+}
+
+// Notify sends a notice to one client, typically the answer to a command.
+// It reports false if the client is no longer attached.
+func (b *Broker) Notify(clientID uint64, text string) bool {
 	b.ringMu.Lock()
-	b.ring[b.head] = buf
-	b.head = (b.head + 1) % b.capacity
+	defer b.ringMu.Unlock()
+	for cli := range b.clients {
+		if cli.info.ID == clientID {
+			_ = b.safeSend(cli, noticeFrame(text))
+			return true
+		}
+	}
+	return false
+}
+
+// notifyAll sends a notice to every attached client.
+func (b *Broker) notifyAll(text string) {
+	buf := noticeFrame(text)
+	b.ringMu.Lock()
+	defer b.ringMu.Unlock()
+	for cli := range b.clients {
+		_ = b.safeSend(cli, buf)
+	}
+}
+
+// Disconnect closes the connection of the client with ID id, after the
+// frames queued for it and a notice with reason unless that is empty. It
+// reports false if no such client is attached.
+func (b *Broker) Disconnect(id uint64, reason string) bool {
+	b.ringMu.Lock()
+	defer b.ringMu.Unlock()
+	for cli := range b.clients {
+		if cli.info.ID != id {
+			continue
+		}
+		if reason == "" {
+			_ = cli.conn.Close()
+		} else {
+			b.sendKept(cli, queued{buf: noticeFrame(reason), keep: true, last: true})
+		}
+		return true
+	}
+	return false
+}
+
+// publish appends a line received from a client.
+func (b *Broker) publish(cli *client, p Publish) {
+	ts := p.TsUs
+	if ts == 0 {
+		ts = time.Now().UnixMicro()
+	}
+	src := p.Source
+	if src == "" {
+		src = fmt.Sprintf("client-%d", cli.info.ID)
+	}
+	b.submit(Line{Type: "line", TsUs: ts, Text: p.Text, Source: src, Channel: p.Channel})
+}
+
+// maxHistoryLines caps the lines returned for a single history request.
+const maxHistoryLines = 2000
+
+// historyFrame encodes up to req.Limit ring lines before req.BeforeSeq, or
+// older than req.BeforeUs without it, that pass filter, which may be nil,
+// oldest first.
+func (b *Broker) historyFrame(req HistoryRequest, filter *filterExpr) []byte {
+	limit := req.Limit
+	if limit <= 0 || limit > maxHistoryLines {
+		limit = maxHistoryLines
+	}
+
+	b.ringMu.Lock()
+	var picked [][]byte
+	more := false
+	size := 64
+	// walk newest to oldest
+	for i := 1; i <= b.count; i++ {
+		e := b.ring[(b.head-i+b.capacity)%b.capacity]
+		if req.BeforeSeq > 0 && e.ev.Seq >= req.BeforeSeq || req.BeforeSeq == 0 && e.ev.TsUs >= req.BeforeUs || !filter.pass(e.text, time.UnixMicro(e.ev.TsUs)) {
+			continue
+		}
+		buf := lineFrame(e.ev)
+		if len(picked) == limit || size+len(buf) > MaxFrameBytes {
+			more = true
+			break
+		}
+		picked = append(picked, buf)
+		size += len(buf)
+	}
 	b.ringMu.Unlock()
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, `{"type":"history","more":%t,"lines":[`, more)
+	for i := len(picked) - 1; i >= 0; i-- {
+		out.Write(bytes.TrimSuffix(picked[i], []byte{'\n'}))
+		if i > 0 {
+			out.WriteByte(',')
+		}
+	}
+	out.WriteString("]}\n")
+	return out.Bytes()
 }
 
-func (b *Broker) broadcast(buf []byte) {
+// maxBatchFrames bounds how many queued frames are coalesced per flush.
+const maxBatchFrames = 256
+
+// lineFramePrefix is how every marshalled Line frame starts.
+var lineFramePrefix = []byte(`{"type":"line",`)
+
+// writeBatch writes queued frames, merging runs of consecutive line frames
+// into a single "lines" frame no larger than MaxFrameBytes. Other frames are
+// written unchanged in order, as are all frames unless combine is set.
+// Frames are only ever written whole: on a write error the caller closes the
+// connection, so a peer never sees a partial frame followed by another frame.
+func writeBatch(w *bufio.Writer, frames [][]byte, combine, lengthPrefixed bool) error {
+	for i := 0; i < len(frames); {
+		j := i
+		size := 64
+		for combine && j < len(frames) && bytes.HasPrefix(frames[j], lineFramePrefix) && size+len(frames[j]) <= MaxFrameBytes {
+			size += len(frames[j])
+			j++
+		}
+		if j-i < 2 {
+			if err := writeFrame(w, frames[i], lengthPrefixed); err != nil {
+				return err
+			}
+			i++
+			continue
+		}
+		const open = `{"type":"lines","lines":[`
+		end := "]}\n"
+		if lengthPrefixed {
+			end = "]}"
+			n := len(open) + len(end) + j - i - 1
+			for k := i; k < j; k++ {
+				n += len(bytes.TrimSuffix(frames[k], []byte{'\n'}))
+			}
+			if err := writeLength(w, n); err != nil {
+				return err
+			}
+		}
+		if _, err := w.WriteString(open); err != nil {
+			return err
+		}
+		for k := i; k < j; k++ {
+			if k > i {
+				if err := w.WriteByte(','); err != nil {
+					return err
+				}
+			}
+			if _, err := w.Write(bytes.TrimSuffix(frames[k], []byte{'\n'})); err != nil {
+				return err
+			}
+		}
+		if _, err := w.WriteString(end); err != nil {
+			return err
+		}
+		i = j
+	}
+	return nil
+}
+
+// metaFrame encodes the meta message stamped with the current server time.
+func (b *Broker) metaFrame(framing, compression string) []byte {
+	b.cfgMu.Lock()
+	meta := b.meta
+	b.cfgMu.Unlock()
+	meta.ServerTimeUs = time.Now().UnixMicro()
+	meta.Framing, meta.Compression = framing, compression
+	buf, _ := json.Marshal(meta)
+	return append(buf, '\n')
+}
+
+// UpdateConfig replaces the broker's rules and sends the new meta to every
+// attached client, which applies it as it does on connecting, so highlight
+// and counter changes take effect without reconnecting. Counters that keep
+// their label and match keep their history. A different MaxLines resizes
+// the ring, keeping the newest lines.
+func (b *Broker) UpdateConfig(cfg Config) {
+	cfg = brokerConfig(cfg)
+	meta := MakeMeta(cfg)
+	var rules []spanRule
+	if b.computeSpans {
+		rules = compileSpanRules(cfg.Highlights)
+	}
+	b.cfgMu.Lock()
+	old := b.meta
+	meta.StartedUs, meta.Version = old.StartedUs, old.Version
+	meta.Capabilities = slices.DeleteFunc(slices.Clone(old.Capabilities), func(c string) bool { return c == "counters" })
+	if len(cfg.Counters) > 0 {
+		meta.Capabilities = append(meta.Capabilities, "counters")
+	}
+	b.cfg, b.meta, b.spans = cfg, meta, rules
+	b.cfgMu.Unlock()
+
+	counters := make([]*counterRule, 0, len(cfg.Counters))
+	b.counterMu.Lock()
+	for _, spec := range cfg.Counters {
+		cr := newCounterRule(spec)
+		for _, old := range b.counters {
+			if cr.sameRule(old) {
+				cr.inherit(old)
+				break
+			}
+		}
+		counters = append(counters, cr)
+	}
+	b.counters = counters
+	b.counterMu.Unlock()
+
 	b.ringMu.Lock()
 	defer b.ringMu.Unlock()
+	if size, maxBytes := cfg.EffectiveMaxLines(), max(cfg.MaxBytes, 0); size != b.capacity || maxBytes != b.maxBytes {
+		entries := b.entriesLocked()
+		entries = entries[max(len(entries)-size, 0):]
+		b.ring, b.head, b.count, b.capacity, b.maxLines = make([]ringEntry, size), 0, 0, size, size
+		b.bytes, b.maxBytes = 0, maxBytes
+		for _, e := range entries {
+			b.enqueueLocked(e)
+		}
+		if b.store != nil {
+			b.store.capacity = size
+		}
+	}
+	buf := b.metaFrame("", "")
 	for cli := range b.clients {
-		if !b.trySend(cli, buf) {
-			dropped := 0
-			for len(cli.ch) == cap(cli.ch) {
-				<-cli.ch
-				dropped++
+		_ = b.safeSend(cli, buf)
+	}
+}
+
+// entriesLocked returns the ring entries, oldest first. Callers hold
+// b.ringMu.
+func (b *Broker) entriesLocked() []ringEntry {
+	out := make([]ringEntry, 0, b.count)
+	for i := b.count; i > 0; i-- {
+		out = append(out, b.ring[(b.head-i+b.capacity)%b.capacity])
+	}
+	return out
+}
+
+// flushStore writes persisted and sunk lines to disk periodically until
+// stopCh closes.
+func (b *Broker) flushStore(stopCh chan struct{}) {
+	t := time.NewTicker(persistFlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			b.ringMu.Lock()
+			if b.store != nil {
+				b.store.flush()
 			}
-			_ = b.trySend(cli, buf)
-			if dropped > 0 {
-				notice := Notice{Type: "notice", Text: fmt.Sprintf("[viewer lagged; dropped %d lines]", dropped)}
-				nb, _ := json.Marshal(notice)
-				nb = append(nb, '\n')
-				_ = b.trySend(cli, nb)
+			if b.sink != nil {
+				b.sink.flush()
+			}
+			b.ringMu.Unlock()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// PersistError returns the error that stopped saving the ring to
+// PersistPath, or nil.
+func (b *Broker) PersistError() error {
+	b.ringMu.Lock()
+	defer b.ringMu.Unlock()
+	if b.persistErr != nil {
+		return b.persistErr
+	}
+	if b.store != nil {
+		return b.store.err
+	}
+	return nil
+}
+
+// SinkError returns the error that stopped writing lines to FileSink, or
+// nil.
+func (b *Broker) SinkError() error {
+	b.ringMu.Lock()
+	defer b.ringMu.Unlock()
+	if b.sinkErr != nil {
+		return b.sinkErr
+	}
+	if b.sink != nil {
+		return b.sink.err
+	}
+	return nil
+}
+
+// replay writes meta and the ring snapshot straight to the client, bypassing
+// its queue so a large ring cannot push meta out of the bounded channel.
+// Lines at or before the client's SinceUs and lines its filter rejects are
+// left out.
+func (b *Broker) replay(cli *client, snapshot []ringEntry) error {
+	framing := ""
+	if cli.wantsLength.Load() {
+		// the meta is the last NDJSON frame on this connection
+		framing = framingLength
+		cli.framed.Store(true)
+	}
+	compression := ""
+	if b.compress && cli.wantsGzip.Load() && isTCP(cli.conn) {
+		compression = compressionGzip
+	}
+	if _, err := cli.bw.Write(b.metaFrame(framing, compression)); err != nil {
+		return err
+	}
+	if compression != "" {
+		if err := cli.bw.Flush(); err != nil {
+			return err
+		}
+		// log lines repeat a lot; the fastest level gets most of the gain
+		cli.gz, _ = gzip.NewWriterLevel(cli.conn, gzip.BestSpeed)
+		cli.bw.Reset(cli.gz)
+	}
+	since, filter := cli.sinceUs.Load(), cli.filter.Load()
+	frames := make([][]byte, 0, maxBatchFrames)
+	for len(snapshot) > 0 {
+		n := min(len(snapshot), maxBatchFrames)
+		frames = frames[:0]
+		for _, e := range snapshot[:n] {
+			if e.ev.TsUs > since && filter.pass(e.text, time.UnixMicro(e.ev.TsUs)) {
+				frames = append(frames, lineFrame(e.ev))
 			}
 		}
+		if err := writeBatch(cli.bw, frames, !cli.single.Load(), cli.framed.Load()); err != nil {
+			return err
+		}
+		snapshot = snapshot[n:]
 	}
+	return cli.flush()
+}
+
+// enqueueLocked appends e to the ring, dropping the oldest entries past
+// the capacity or MaxBytes. The newest entry is kept whatever its size.
+func (b *Broker) enqueueLocked(e ringEntry) {
+	if b.count == b.capacity {
+		b.bytes -= b.ring[b.head].size
+	} else {
+		b.count++
+	}
+	b.ring[b.head] = e
+	b.bytes += e.size
+	b.head = (b.head + 1) % b.capacity
+	for b.maxBytes > 0 && b.bytes > b.maxBytes && b.count > 1 {
+		oldest := (b.head - b.count + b.capacity) % b.capacity
+		b.bytes -= b.ring[oldest].size
+		b.ring[oldest] = ringEntry{}
+		b.count--
+	}
+}
+
+// broadcastLocked queues buf, the frame of e, to the clients whose filter
+// it passes.
+func (b *Broker) broadcastLocked(e ringEntry, buf []byte) {
+	for cli := range b.clients {
+		filter := cli.filter.Load()
+		if !filter.pass(e.text, time.UnixMicro(e.ev.TsUs)) {
+			continue
+		}
+		b.sentBytes += uint64(len(buf))
+		b.deliverLocked(cli, queued{buf: buf, keep: e.priority}, filter != nil)
+		if n := len(cli.ch); n > cli.highWater {
+			cli.highWater = n
+		}
+	}
+}
+
+// Stats returns delivery statistics for every attached client, ordered by
+// client ID.
+func (b *Broker) Stats() Stats {
+	b.ringMu.Lock()
+	defer b.ringMu.Unlock()
+	st := Stats{Type: "stats", Lines: b.seq - b.restoredSeq, SlowDisconnects: b.slowDisconnects, SentBytes: b.sentBytes, Clients: make([]ClientStats, 0, len(b.clients))}
+	for cli := range b.clients {
+		st.Clients = append(st.Clients, ClientStats{
+			ID:             cli.info.ID,
+			RemoteAddr:     cli.info.RemoteAddr,
+			ConnectedUs:    cli.info.ConnectedAt.UnixMicro(),
+			Dropped:        cli.dropped,
+			QueueLen:       len(cli.ch),
+			QueueHighWater: cli.highWater,
+			QueueCap:       cap(cli.ch),
+		})
+	}
+	slices.SortFunc(st.Clients, func(a, c ClientStats) int { return cmp.Compare(a.ID, c.ID) })
+	return st
+}
+
+// rejectClient sends a notice to a connection that will not be served and
+// closes it.
+func rejectClient(conn net.Conn, text string) {
+	_ = conn.SetWriteDeadline(time.Now().Add(time.Second))
+	_, _ = conn.Write(noticeFrame(text))
+	_ = conn.Close()
+}
+
+// serverFullNotice is the notice refusing a client over MaxClients.
+func serverFullNotice(maxClients int) string {
+	return fmt.Sprintf("[notice] server full (%d clients); try again later", maxClients)
+}
+
+// noticeFrame marshals a notice frame carrying text.
+func noticeFrame(text string) []byte {
+	nb, _ := json.Marshal(Notice{Type: "notice", Text: text})
+	return append(nb, '\n')
 }
 
 func (b *Broker) trySend(cli *client, buf []byte) bool {
+	cli.sendMu.Lock()
+	defer cli.sendMu.Unlock()
+	return offer(cli, queued{buf: buf})
+}
+
+// offer queues q if there is room. Callers hold cli.sendMu.
+func offer(cli *client, q queued) bool {
 	select {
-	case cli.ch <- buf:
+	case cli.ch <- q:
 		return true
 	default:
 		return false
 	}
 }
 
+// safeSend queues buf, making room if needed. It is used for replies and
+// meta, which are kept when room is made for a priority line.
 func (b *Broker) safeSend(cli *client, buf []byte) error {
+	b.sendKept(cli, queued{buf: buf, keep: true})
+	return nil
+}
+
+// sendKept queues q, dropping the oldest queued frame if the queue is full.
+func (b *Broker) sendKept(cli *client, q queued) {
+	cli.sendMu.Lock()
+	defer cli.sendMu.Unlock()
+	if offer(cli, q) {
+		return
+	}
 	select {
-	case cli.ch <- buf:
-		return nil
-	default:
-		<-cli.ch
-		cli.ch <- buf
-		return nil
+	case <-cli.ch:
+	default: // the writer made room meanwhile
+	}
+	cli.ch <- q
+}
+
+// transportOf names how conn reached the broker, for ClientInfo.
+func transportOf(conn net.Conn) string {
+	switch conn := conn.(type) {
+	case *net.UnixConn:
+		return "unix"
+	case *tls.Conn:
+		return "tls"
+	case *servedConn:
+		return conn.opts.Transport
+	}
+	if isTCP(conn) {
+		return "tcp"
+	}
+	return conn.LocalAddr().Network()
+}
+
+func remoteAddrString(conn net.Conn) string {
+	if addr := conn.RemoteAddr(); addr != nil && addr.String() != "" {
+		return addr.String()
 	}
+	return "local"
 }
 
 func listenFirstAvailable(candidates []string) (string, net.Listener, error) {
@@ -0,0 +1,72 @@
+package console
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// An extraction counter's samples survive UpdateConfig with their values,
+// so the next append can prune them.
+func TestUpdateConfigKeepsExtractValues(t *testing.T) {
+	cfg := Config{Counters: []CounterSpec{{Match: "took", Label: "LAT", Extract: `took (\d+)ms`, Unit: "ms"}}}
+	b := NewBroker(BrokerOptions{Config: cfg})
+	b.Append("took 5ms")
+	b.UpdateConfig(cfg)
+	b.Append("took 7ms")
+	b.Append("unrelated")
+
+	b.counterMu.Lock()
+	defer b.counterMu.Unlock()
+	c := b.counters[0]
+	if len(c.times) != 2 || len(c.values) != 2 {
+		t.Fatalf("got %d times and %d values, want 2 of each", len(c.times), len(c.values))
+	}
+}
+
+// A client disconnected for being slow has its connection closed, so its
+// writer returns and OnClientDisconnect fires while the peer still is not
+// reading.
+func TestDisconnectSlowClosesConnection(t *testing.T) {
+	gone := make(chan struct{})
+	b := NewBroker(BrokerOptions{
+		ListenAddr:         "127.0.0.1:0",
+		DropPolicy:         DisconnectSlow,
+		OnClientDisconnect: func(ClientInfo) { close(gone) },
+	})
+	if err := b.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Stop()
+	conn, err := net.Dial("tcp", b.TCPAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := writeHello(conn, 0, ""); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * helloWait)
+
+	line := strings.Repeat("x", 32<<10)
+fill:
+	for range 2000 {
+		b.Append(line)
+		select {
+		case <-gone:
+			break fill
+		default:
+		}
+	}
+	select {
+	case <-gone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnClientDisconnect was not called")
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.Copy(io.Discard, conn); err != nil {
+		t.Fatalf("reading the dropped connection: %v, want EOF", err)
+	}
+}
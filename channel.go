@@ -0,0 +1,93 @@
+package console
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// addChannelLocked records a named channel the first time a line arrives on
+// it. Callers hold u.mu.
+func (u *UI) addChannelLocked(channel string) {
+	if channel != "" && !slices.Contains(u.channels, channel) {
+		u.channels = append(u.channels, channel)
+	}
+}
+
+// channelShownLocked reports whether lines on channel pass the channel
+// selection. Callers hold u.mu.
+func (u *UI) channelShownLocked(channel string) bool {
+	return u.channel == "" || u.channel == channel
+}
+
+// channelNames returns the selectable channels, "all" first.
+func (u *UI) channelNames() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]string{u.msg("channel.all")}, u.channels...)
+}
+
+// setChannelDirect shows only channel, or every line for "" or "all". It
+// reports false for a channel that has not been seen.
+func (u *UI) setChannelDirect(channel string) bool {
+	if channel == u.msg("channel.all") {
+		channel = ""
+	}
+	u.mu.Lock()
+	if channel != "" && !slices.Contains(u.channels, channel) {
+		u.mu.Unlock()
+		return false
+	}
+	u.channel = channel
+	u.mu.Unlock()
+	u.refreshDirect()
+	return true
+}
+
+// cycleChannelDirect moves the selection step channels along, wrapping
+// through "all".
+func (u *UI) cycleChannelDirect(step int) {
+	u.mu.Lock()
+	if len(u.channels) == 0 {
+		u.mu.Unlock()
+		return
+	}
+	// index 0 is "all"
+	i := slices.Index(u.channels, u.channel) + 1
+	n := len(u.channels) + 1
+	i = ((i+step)%n + n) % n
+	channel := ""
+	if i > 0 {
+		channel = u.channels[i-1]
+	}
+	u.mu.Unlock()
+	u.setChannelDirect(channel)
+}
+
+// channelTabs renders the channel selector for the top bar, or "" while no
+// named channel has been seen.
+func (u *UI) channelTabs() string {
+	u.mu.Lock()
+	channels := append([]string(nil), u.channels...)
+	current := u.channel
+	u.mu.Unlock()
+	if len(channels) == 0 {
+		return ""
+	}
+	pal := u.currentPalette()
+	tab := func(label string, active bool) string {
+		switch {
+		case active && u.noColour:
+			return tview.Escape("[" + label + "]")
+		case active:
+			return tagStyle(tview.Escape(label), pal.Active, false)
+		}
+		return tagStyle(tview.Escape(label), pal.Inactive, u.noColour)
+	}
+	tabs := []string{tab(u.msg("channel.all"), current == "")}
+	for _, c := range channels {
+		tabs = append(tabs, tab(c, c == current))
+	}
+	return strings.Join(tabs, " ")
+}
@@ -0,0 +1,109 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultCopyMaxBytes caps how much :copy puts on the clipboard.
+const DefaultCopyMaxBytes = 1 << 20
+
+// clipboardCommands are tried in order to reach the system clipboard.
+var clipboardCommands = [][]string{
+	{"pbcopy"},
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+	{"clip.exe"},
+}
+
+var errNoClipboard = errors.New("no clipboard tool found")
+
+// clipboardTimeout bounds a clipboard tool, which can hang, such as xclip
+// waiting for an X server that does not answer.
+const clipboardTimeout = 5 * time.Second
+
+// writeClipboard copies text with the first available clipboard tool.
+func writeClipboard(text string) error {
+	for _, args := range clipboardCommands {
+		path, err := exec.LookPath(args[0])
+		if err != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), clipboardTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, path, args[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("%s: no answer after %v", args[0], clipboardTimeout)
+			}
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+		return nil
+	}
+	return errNoClipboard
+}
+
+// plainRows returns the visible text of the filtered lines, one per line.
+func (u *UI) plainRows() []byte {
+	var b bytes.Buffer
+	for _, r := range u.displayRows() {
+		b.WriteString(stripMarkup(r.text))
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// copyFiltered copies every filtered line, not just the viewport. Text
+// larger than the copy cap, or with no clipboard available, goes to a temp
+// file instead; toFile forces that. The clipboard tool and the file are
+// written from another goroutine, which reports in the status bar.
+func (u *UI) copyFiltered(toFile bool) {
+	text := u.plainRows()
+	n := strings.Count(string(text), "\n")
+	if n == 0 {
+		u.setStatusMessage(u.msg("copy.empty"))
+		return
+	}
+	u.mu.Lock()
+	limit := u.copyMaxBytes
+	u.mu.Unlock()
+	go func() {
+		msg := u.copyOut(text, n, limit, toFile)
+		u.Do(func() { u.setStatusMessage(msg) })
+	}()
+}
+
+// copyOut puts text, n lines, on the clipboard or in a temp file and
+// returns the status message saying where.
+func (u *UI) copyOut(text []byte, n, limit int, toFile bool) string {
+	reason := ""
+	if !toFile {
+		if len(text) > limit {
+			reason = u.msg("copy.over_limit", limit)
+		} else if err := writeClipboard(string(text)); err == nil {
+			return u.msg("copy.clipboard", n)
+		} else {
+			reason = " (" + err.Error() + ")"
+		}
+	}
+	f, err := os.CreateTemp("", "console-copy-*.txt")
+	if err != nil {
+		return u.msg("copy.error", err)
+	}
+	_, err = f.Write(text)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return u.msg("copy.error", err)
+	}
+	return u.msg("copy.file", n, f.Name(), reason)
+}
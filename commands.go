@@ -0,0 +1,224 @@
+package console
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// commandNames are the commands typed after ':', offered by Tab.
+var commandNames = []string{"channel", "clear", "columns", "copy", "export", "filter", "goto", "jump", "maxlines", "palette", "save", "speed", "stats", "theme", "where"}
+
+// commandArgs returns the completions for the next argument of the command
+// in fields, the command and the arguments typed so far.
+func (u *UI) commandArgs(fields []string) []string {
+	switch fields[0] {
+	case "palette", "theme":
+		if len(fields) == 1 {
+			names := make([]string, len(Palettes))
+			for i, p := range Palettes {
+				names[i] = p.Name
+			}
+			return names
+		}
+	case "channel":
+		if len(fields) == 1 {
+			return u.channelNames()
+		}
+	case "export":
+		if len(fields) == 1 {
+			return append([]string{"all"}, exportFormats...)
+		}
+		if len(fields) == 2 && fields[1] == "all" {
+			return exportFormats
+		}
+	case "copy":
+		if len(fields) == 1 {
+			return []string{"file"}
+		}
+	case "filter":
+		out := make([]string, len(levels))
+		for i, l := range levels {
+			out[i] = "level=" + l
+		}
+		return out
+	}
+	return nil
+}
+
+// completeInputDirect completes the last word in the input field: command
+// names and their arguments after ':', otherwise words offered by
+// UIOptions.Completer. A unique match is filled in, several are completed to
+// their common prefix and listed in the status bar. It reports whether the
+// Tab key was used up.
+func (u *UI) completeInputDirect() bool {
+	text := u.inputField.GetText()
+	line, command := strings.CutPrefix(text, ":")
+	if !command && (u.completer == nil || strings.HasPrefix(text, "/") || strings.HasPrefix(text, "!")) {
+		return false
+	}
+	fields := strings.Fields(line)
+	word := ""
+	if len(fields) > 0 && !strings.HasSuffix(line, " ") {
+		word, fields = fields[len(fields)-1], fields[:len(fields)-1]
+	}
+	var candidates []string
+	switch {
+	case command && len(fields) == 0:
+		candidates = commandNames
+	case command:
+		candidates = u.commandArgs(fields)
+		if fields[0] == "filter" || fields[0] == "where" || candidates == nil {
+			candidates = append(candidates, u.complete(word)...)
+		}
+	default:
+		if word == "" {
+			return false
+		}
+		candidates = u.complete(strings.TrimPrefix(word, "-"))
+		if strings.HasPrefix(word, "-") {
+			for i, c := range candidates {
+				candidates[i] = "-" + c
+			}
+		}
+	}
+	var matches []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, word) && !slices.Contains(matches, c) {
+			matches = append(matches, c)
+		}
+	}
+	var completed string
+	switch len(matches) {
+	case 0:
+		return command
+	case 1:
+		completed = matches[0]
+		if !strings.HasSuffix(completed, "=") {
+			completed += " "
+		}
+	default:
+		completed = commonPrefix(matches)
+		u.setStatusMessage(strings.Join(matches, " "))
+	}
+	u.inputField.SetText(text[:len(text)-len(word)] + completed)
+	return true
+}
+
+// complete returns UIOptions.Completer's words for prefix, if it is set.
+func (u *UI) complete(prefix string) []string {
+	if u.completer == nil {
+		return nil
+	}
+	return u.completer(prefix)
+}
+
+// commonPrefix returns the longest prefix shared by every string in ss.
+func commonPrefix(ss []string) string {
+	prefix := ss[0]
+	for _, s := range ss[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// clearDirect empties the buffer. Scrolling back afterwards does not fetch
+// the cleared lines again.
+func (u *UI) clearDirect() {
+	u.mu.Lock()
+	n := len(u.lines)
+	for _, l := range u.lines {
+		u.historyFloor = max(u.historyFloor, l.seq)
+		for _, c := range l.cont {
+			u.historyFloor = max(u.historyFloor, c.seq)
+		}
+	}
+	u.historyExhausted = true
+	u.lines = nil
+	u.extraHistory = 0
+	u.pendingCount = 0
+	u.needFull = true
+	u.tsBase = time.Time{}
+	u.mu.Unlock()
+	u.refreshDirect()
+	u.setStatusMessage(u.msg("clear.done", n))
+}
+
+// setMaxLinesLocked changes how many lines the buffer keeps, dropping the
+// oldest ones past it. Callers hold u.mu.
+func (u *UI) setMaxLinesLocked(n int) {
+	u.maxLines = n
+	u.extraHistory = 0
+	if len(u.lines) > u.maxLines {
+		u.lines = append([]logLine(nil), u.lines[len(u.lines)-u.maxLines:]...)
+	}
+	u.needFull = true
+}
+
+// maxLinesCommand handles :maxlines <n>.
+func (u *UI) maxLinesCommand(args []string) {
+	n := 0
+	if len(args) == 1 {
+		n, _ = strconv.Atoi(args[0])
+	}
+	if n <= 0 {
+		u.setStatusMessage(u.msg("maxlines.usage"))
+		return
+	}
+	u.mu.Lock()
+	u.setMaxLinesLocked(n)
+	u.mu.Unlock()
+	u.refreshDirect()
+	u.setStatusMessage(u.msg("maxlines.set", n))
+}
+
+// filterCommand handles :filter: level=a,b arguments show only those
+// levels, "level=" all of them, and the rest is set as the filter. With no
+// arguments the filter is cleared and every level shown.
+func (u *UI) filterCommand(args []string) {
+	var text []string
+	var shown []string
+	setLevels := len(args) == 0
+	for _, a := range args {
+		v, ok := strings.CutPrefix(a, "level=")
+		if !ok {
+			text = append(text, a)
+			continue
+		}
+		setLevels = true
+		for l := range strings.SplitSeq(v, ",") {
+			if l == "" {
+				continue
+			}
+			if !isLevelName(l) {
+				u.setStatusMessage(u.msg("filter.level_unknown", l, strings.Join(levels, ", ")))
+				return
+			}
+			shown = append(shown, LevelClass(l))
+		}
+	}
+	u.mu.Lock()
+	u.preset = ""
+	if setLevels {
+		clear(u.hiddenLevels)
+		for _, l := range levels {
+			if len(shown) > 0 && !slices.Contains(shown, l) {
+				u.hiddenLevels[l] = true
+			}
+		}
+	}
+	if len(text) > 0 || len(args) == 0 {
+		u.filter = strings.Join(text, " ")
+		u.filterActive = u.filter != ""
+	}
+	filter := u.filter
+	u.mu.Unlock()
+	if len(text) > 0 || len(args) == 0 {
+		u.inputField.SetText(filter)
+		u.notifyFilterChange()
+	}
+	u.refreshDirect()
+}
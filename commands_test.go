@@ -0,0 +1,20 @@
+package console
+
+import "testing"
+
+// After :clear, reaching the top of the buffer does not ask the broker for
+// the cleared lines again.
+func TestClearStopsHistoryBackfill(t *testing.T) {
+	u := NewUI(UIOptions{})
+	asked := 0
+	u.onNeedHistory = func(int64, uint64) { asked++ }
+	u.server = Meta{Version: ProtocolVersion, Capabilities: []string{"history"}}
+	u.lines = []logLine{{text: "first", tsUs: 1, seq: 1}, {text: "second", tsUs: 2, seq: 2}}
+
+	u.clearDirect()
+	u.lines = append(u.lines, logLine{text: "new", tsUs: 3, seq: 3})
+	u.maybeRequestHistory()
+	if asked != 0 {
+		t.Fatalf("history requested %d times after clear, want none", asked)
+	}
+}
@@ -0,0 +1,556 @@
+package console
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// FileConfig is a console configuration file as read by LoadConfig. Keys use
+// the JSON names of the types they fill, in every format:
+//
+//	max_lines: 20000
+//	palette: deuteranopia
+//	counters:
+//	  - {match: "ERROR", label: "ERR", window_s: 60, threshold: 20}
+//	highlights:
+//	  - {match: "DHCPACK", style: {fg: green, attrs: b}}
+//	theme:
+//	  error: {fg: "#ff5f5f", attrs: b}
+//	keys:
+//	  pause: p
+//	  quit: ""
+//	presets:
+//	  - {name: errors, levels: [error, warn]}
+//	  - {name: leases, filter: "DHCPACK || DHCPNAK"}
+type FileConfig struct {
+	MaxLines   int             `json:"max_lines"`
+	MaxBytes   int             `json:"max_bytes"`
+	Counters   []CounterSpec   `json:"counters"`
+	Highlights []HighlightSpec `json:"highlights"`
+	// Palette names the built-in palette the theme starts from.
+	Palette string `json:"palette"`
+	// Theme overrides palette styles by slot: key, active, inactive,
+	// message, error, warn, info, debug, text, bar, title, counter,
+	// separator, focus, input and selection.
+	Theme map[string]Style `json:"theme"`
+	// Keys rebinds log view actions, named as in KeyActions, to a single
+	// character or "space". An empty key disables the action. A key serves
+	// one action, so binding one to another action's default key means
+	// rebinding or disabling that action too.
+	Keys map[string]string `json:"keys"`
+	// Presets are named filters selected with Alt+1–9; see
+	// UIOptions.Presets.
+	Presets []FilterPreset `json:"presets"`
+}
+
+// KeyActions maps each rebindable log view action to its default key.
+var KeyActions = map[string]rune{
+	"quit":         'q',
+	"mouse":        'm',
+	"help":         '?',
+	"pause":        ' ',
+	"case":         'c',
+	"smart_case":   'C',
+	"regex":        'e',
+	"send_filter":  'F',
+	"newest_first": 'r',
+	"fold":         'z',
+	"trace":        'x',
+	"patterns":     'p',
+	"stats":        's',
+	"export":       'w',
+	"copy":         'y',
+	"diff":         'd',
+	"ids":          '#',
+	"timestamps":   't',
+	"wrap":         'W',
+	"inspect":      'i',
+	"search":       '/',
+	"search_next":  'n',
+	"search_prev":  'N',
+	"channel_prev": '[',
+	"channel_next": ']',
+	"level_error":  '1',
+	"level_warn":   '2',
+	"level_info":   '3',
+	"level_debug":  '4',
+	"command":      '!',
+	"slower":       '<',
+	"faster":       '>',
+}
+
+// LoadConfig reads a YAML (.yaml, .yml), TOML (.toml) or JSON file. Unknown
+// keys and invalid rules are reported together in the returned error.
+func LoadConfig(path string) (*FileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("console config: %w", err)
+	}
+	fc, err := ParseConfig(data, strings.TrimPrefix(filepath.Ext(path), "."))
+	if err != nil {
+		return nil, fmt.Errorf("console config %s: %w", path, err)
+	}
+	return fc, nil
+}
+
+// configWatchInterval is how often WatchConfig checks the file.
+const configWatchInterval = time.Second
+
+// WatchConfig loads the config file at path, applies its rules to b with
+// UpdateConfig, and keeps doing so whenever the file changes until stop is
+// called, so highlights and counters can be tweaked live by editing it.
+// Attached viewers get a notice of each reload, which is not added to the
+// ring; a file that fails to load is reported the same way and the rules
+// in effect are kept.
+func WatchConfig(path string, b *Broker) (stop func(), err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("console config: %w", err)
+	}
+	fc, err := ParseConfig(data, strings.TrimPrefix(filepath.Ext(path), "."))
+	if err != nil {
+		return nil, fmt.Errorf("console config %s: %w", path, err)
+	}
+	b.UpdateConfig(fc.Rules())
+
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(configWatchInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+			case <-done:
+				return
+			}
+			cur, err := os.ReadFile(path)
+			if err != nil || bytes.Equal(cur, data) {
+				continue // a missing file is usually an editor replacing it
+			}
+			data = cur
+			fc, err := ParseConfig(cur, strings.TrimPrefix(filepath.Ext(path), "."))
+			if err != nil {
+				b.notifyAll(fmt.Sprintf("[notice] config %s not reloaded: %v", path, err))
+				continue
+			}
+			b.UpdateConfig(fc.Rules())
+			b.notifyAll(fmt.Sprintf("[notice] config %s reloaded", path))
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }, nil
+}
+
+// ParseConfig parses data in format "yaml", "yml", "toml" or "json" and
+// validates it.
+func ParseConfig(data []byte, format string) (*FileConfig, error) {
+	// YAML and TOML are converted to JSON so the json tags apply throughout
+	switch strings.ToLower(format) {
+	case "json":
+	case "yaml", "yml":
+		var v any
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		if v == nil {
+			v = map[string]any{}
+		}
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	case "toml":
+		var v map[string]any
+		if err := toml.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format %q", format)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var fc FileConfig
+	if err := dec.Decode(&fc); err != nil {
+		return nil, err
+	}
+	if err := fc.Validate(); err != nil {
+		return nil, err
+	}
+	return &fc, nil
+}
+
+// Validate checks patterns, styles, palette and key names.
+func (fc *FileConfig) Validate() error {
+	var errs []error
+	bad := func(format string, args ...any) { errs = append(errs, fmt.Errorf(format, args...)) }
+	if fc.MaxLines < 0 {
+		bad("max_lines: must not be negative")
+	}
+	if fc.MaxBytes < 0 {
+		bad("max_bytes: must not be negative")
+	}
+	for i, c := range fc.Counters {
+		if c.Match == "" {
+			bad("counters[%d]: match is empty", i)
+		} else if c.Regex {
+			if _, err := regexp.Compile(c.Match); err != nil {
+				bad("counters[%d]: %w", i, err)
+			}
+		}
+		if c.Extract != "" {
+			if re, err := regexp.Compile(c.Extract); err != nil {
+				bad("counters[%d]: extract: %w", i, err)
+			} else if re.NumSubexp() < 1 {
+				bad("counters[%d]: extract needs a capture group", i)
+			}
+		}
+		if c.WindowSeconds < 0 {
+			bad("counters[%d]: window_s must not be negative", i)
+		}
+		if c.TopN < 0 {
+			bad("counters[%d]: top_n must not be negative", i)
+		}
+		if c.Sparkline < 0 {
+			bad("counters[%d]: sparkline must not be negative", i)
+		}
+		if c.Threshold < 0 || c.ThresholdWindow < 0 {
+			bad("counters[%d]: threshold and threshold_window_s must not be negative", i)
+		}
+	}
+	for i, h := range fc.Highlights {
+		if h.Match == "" {
+			bad("highlights[%d]: match is empty", i)
+		} else if h.Regex {
+			if _, err := regexp.Compile(h.Match); err != nil {
+				bad("highlights[%d]: %w", i, err)
+			}
+		}
+		if h.Style != nil {
+			if err := validateStyle(*h.Style); err != nil {
+				bad("highlights[%d]: %w", i, err)
+			}
+		}
+	}
+	if _, ok := PaletteByName(fc.Palette); !ok {
+		bad("palette: unknown %q; choose %s", fc.Palette, paletteNames())
+	}
+	for slot, st := range fc.Theme {
+		if _, ok := paletteSlot(&Palette{}, slot); !ok {
+			bad("theme: unknown slot %q", slot)
+		} else if err := validateStyle(st); err != nil {
+			bad("theme.%s: %w", slot, err)
+		}
+	}
+	bound := make(map[rune]string) // key to the action Keys binds to it
+	for _, action := range slices.Sorted(maps.Keys(fc.Keys)) {
+		if _, ok := KeyActions[action]; !ok {
+			bad("keys: unknown action %q", action)
+		} else if r, err := parseKey(fc.Keys[action]); err != nil {
+			bad("keys.%s: %w", action, err)
+		} else if other, dup := bound[r]; dup && r != 0 {
+			bad("keys: %s and %s are both bound to %q", other, action, keyName(r))
+		} else {
+			bound[r] = action
+		}
+	}
+	for _, action := range slices.Sorted(maps.Keys(KeyActions)) {
+		if _, rebound := fc.Keys[action]; rebound {
+			continue
+		}
+		if other, ok := bound[KeyActions[action]]; ok {
+			bad("keys.%s: %q is the key of %s; rebind or disable %s too", other, keyName(KeyActions[action]), action, action)
+		}
+	}
+	if len(fc.Presets) > maxPresets {
+		bad("presets: at most %d, one per Alt+number key", maxPresets)
+	}
+	for i, p := range fc.Presets {
+		if p.Name == "" {
+			bad("presets[%d]: name is empty", i)
+		} else if slices.ContainsFunc(fc.Presets[:i], func(q FilterPreset) bool { return q.Name == p.Name }) {
+			bad("presets[%d]: duplicate name %q", i, p.Name)
+		}
+		if p.Regex {
+			if _, err := regexp.Compile(p.Filter); err != nil {
+				bad("presets[%d]: %w", i, err)
+			}
+		}
+		for _, l := range p.Levels {
+			if !isLevelName(l) {
+				bad("presets[%d]: unknown level %q", i, l)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Rules returns the counters, highlights, limits and palette as a Config.
+func (fc *FileConfig) Rules() Config {
+	return Config{
+		MaxLines:   fc.MaxLines,
+		MaxBytes:   fc.MaxBytes,
+		Counters:   fc.Counters,
+		Highlights: fc.Highlights,
+		Palette:    fc.Palette,
+	}
+}
+
+// ThemePalette returns the named palette with the theme's overrides, or nil
+// if the file sets no theme.
+func (fc *FileConfig) ThemePalette() *Palette {
+	if len(fc.Theme) == 0 {
+		return nil
+	}
+	p, _ := PaletteByName(fc.Palette)
+	p.Name = "custom"
+	for slot, st := range fc.Theme {
+		if dst, ok := paletteSlot(&p, slot); ok {
+			*dst = st
+		}
+	}
+	return &p
+}
+
+// KeyRunes returns the key bindings as used by UIOptions.Keys.
+func (fc *FileConfig) KeyRunes() map[string]rune {
+	if len(fc.Keys) == 0 {
+		return nil
+	}
+	keys := make(map[string]rune, len(fc.Keys))
+	for action, key := range fc.Keys {
+		keys[action], _ = parseKey(key)
+	}
+	return keys
+}
+
+// UIOptions returns an option applying the whole file to a UI.
+func (fc *FileConfig) UIOptions() UIOption {
+	return func(o *UIOptions) {
+		o.Rules = fc.Rules()
+		o.MaxLines = fc.MaxLines
+		if fc.Palette != "" {
+			o.Palette = fc.Palette
+		}
+		if p := fc.ThemePalette(); p != nil {
+			o.CustomPalette = p
+		}
+		if keys := fc.KeyRunes(); keys != nil {
+			o.Keys = keys
+		}
+		if len(fc.Presets) > 0 {
+			o.Presets = fc.Presets
+		}
+	}
+}
+
+// paletteSlot returns the style in p named by slot.
+func paletteSlot(p *Palette, slot string) (*Style, bool) {
+	switch strings.ToLower(slot) {
+	case "key":
+		return &p.Key, true
+	case "active":
+		return &p.Active, true
+	case "inactive":
+		return &p.Inactive, true
+	case "message":
+		return &p.Message, true
+	case "error":
+		return &p.Error, true
+	case "warn":
+		return &p.Warn, true
+	case "info":
+		return &p.Info, true
+	case "debug":
+		return &p.Debug, true
+	case "text":
+		return &p.Text, true
+	case "bar":
+		return &p.Bar, true
+	case "title":
+		return &p.Title, true
+	case "counter":
+		return &p.Counter, true
+	case "separator":
+		return &p.Separator, true
+	case "focus":
+		return &p.Focus, true
+	case "input":
+		return &p.Input, true
+	case "selection":
+		return &p.Selection, true
+	}
+	return nil, false
+}
+
+// validateStyle checks that colours are tview names or hex values and that
+// attributes are known flags.
+func validateStyle(st Style) error {
+	for _, c := range []string{st.FG, st.BG} {
+		if c == "" || c == "-" {
+			continue
+		}
+		if tcell.GetColor(c) == tcell.ColorDefault && !strings.EqualFold(c, "default") {
+			return fmt.Errorf("unknown colour %q", c)
+		}
+	}
+	if i := strings.IndexFunc(st.Attrs, func(r rune) bool { return !strings.ContainsRune("bdilrsuBDILRSU-", r) }); i >= 0 {
+		return fmt.Errorf("unknown attribute %q", st.Attrs[i:i+1])
+	}
+	return nil
+}
+
+// parseKey reads a key binding: one character, "space", or "" for none.
+func parseKey(key string) (rune, error) {
+	if strings.EqualFold(key, "space") {
+		return ' ', nil
+	}
+	r := []rune(key)
+	switch len(r) {
+	case 0:
+		return 0, nil
+	case 1:
+		return r[0], nil
+	}
+	return 0, fmt.Errorf("key %q is not a single character", key)
+}
+
+// keyRemap translates pressed keys to the default key of their action, so
+// bindKeys can keep matching defaults. A zero target disables the key.
+func keyRemap(keys map[string]rune) map[rune]rune {
+	if len(keys) == 0 {
+		return nil
+	}
+	remap := make(map[rune]rune)
+	// free the default keys of rebound actions first
+	actions := make([]string, 0, len(keys))
+	for action := range keys {
+		actions = append(actions, action)
+	}
+	slices.Sort(actions)
+	for _, action := range actions {
+		if def, ok := KeyActions[action]; ok && keys[action] != def {
+			remap[def] = 0
+		}
+	}
+	for _, action := range actions {
+		if def, ok := KeyActions[action]; ok && keys[action] != 0 {
+			remap[keys[action]] = def
+		}
+	}
+	return remap
+}
+
+// boundKey returns the key now bound to the action whose default key is
+// def, reporting false if Keys disabled it.
+func (u *UI) boundKey(def rune) (rune, bool) {
+	if u.keyRemap[def] == def {
+		return def, true
+	}
+	for pressed, d := range u.keyRemap {
+		if d == def {
+			return pressed, true
+		}
+	}
+	if _, taken := u.keyRemap[def]; taken {
+		return 0, false
+	}
+	return def, true
+}
+
+// keyName is how help and hints show a key.
+func keyName(r rune) string {
+	if r == ' ' {
+		return "Space"
+	}
+	return string(r)
+}
+
+// helpKeyWidth is the width of the key column in help text.
+const helpKeyWidth = 20
+
+// rebindHelp rewrites the key column of help text, the keys starting each
+// indented line up to a double space, to the keys bound by UIOptions.Keys.
+// Lines whose keys are all disabled are left out.
+func (u *UI) rebindHelp(text string) string {
+	if u.keyRemap == nil {
+		return text
+	}
+	defaults := make(map[rune]bool, len(KeyActions))
+	for _, r := range KeyActions {
+		defaults[r] = true
+	}
+	lines := strings.Split(text, "\n")
+	out := lines[:0]
+	for _, line := range lines {
+		keys, desc, ok := strings.Cut(strings.TrimPrefix(line, "  "), "  ")
+		if !ok || !strings.HasPrefix(line, "  ") {
+			out = append(out, line)
+			continue
+		}
+		toks := strings.Fields(keys)
+		var kept []string
+		changed, nkeys := false, 0
+		for i, tok := range toks {
+			def, isKey := []rune(tok)[0], utf8.RuneCountInString(tok) == 1
+			if tok == "Space" {
+				def, isKey = ' ', true
+			}
+			switch {
+			case tok == "/" && i > 0 && i < len(toks)-1:
+				kept = append(kept, tok) // "n / N"
+				continue
+			case !isKey || !defaults[def]:
+				kept = append(kept, tok)
+				continue
+			}
+			r, bound := u.boundKey(def)
+			if !bound {
+				changed = true
+				continue
+			}
+			changed = changed || r != def
+			kept = append(kept, keyName(r))
+			nkeys++
+		}
+		if !changed {
+			out = append(out, line)
+			continue
+		}
+		if nkeys == 0 {
+			continue
+		}
+		// drop separators left next to a disabled key
+		var b []string
+		for _, tok := range kept {
+			if tok == "/" && (len(b) == 0 || b[len(b)-1] == "/") {
+				continue
+			}
+			b = append(b, tok)
+		}
+		if len(b) > 0 && b[len(b)-1] == "/" {
+			b = b[:len(b)-1]
+		}
+		col := strings.Join(b, " ")
+		out = append(out, "  "+col+strings.Repeat(" ", max(2, helpKeyWidth-utf8.RuneCountInString(col)))+strings.TrimLeft(desc, " "))
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,147 @@
+package console
+
+import (
+	"fmt"
+	"time"
+)
+
+// DropPolicy decides what the broker does when a client's send queue is full
+// because the viewer reads slower than lines are appended.
+type DropPolicy int
+
+const (
+	// DropOldest discards the queued lines to make room for the new one.
+	DropOldest DropPolicy = iota
+	// DropNewest keeps the queue and discards the new line.
+	DropNewest
+	// DisconnectSlow disconnects the client; it can reattach and replay
+	// the ring.
+	DisconnectSlow
+	// BlockWithTimeout waits up to BrokerOptions.BlockTimeout for room,
+	// holding up the appender, then discards the new line. A client that
+	// made it wait the whole timeout has its lines discarded without
+	// waiting until its queue is half empty again, so one stalled viewer
+	// does not hold up every line.
+	BlockWithTimeout
+)
+
+// DefaultBlockTimeout is how long BlockWithTimeout waits by default.
+const DefaultBlockTimeout = 100 * time.Millisecond
+
+// queued is a frame waiting in a client's send queue. keep marks priority
+// lines and replies, which are not dropped to make room for a priority line.
+// last closes the connection once buf is written.
+type queued struct {
+	buf  []byte
+	keep bool
+	last bool
+}
+
+// deliverLocked queues a line frame for cli, applying the drop policy if its
+// queue is full. A priority line first takes the place of the oldest queued
+// frame that is not kept. filtered tells whether the client filters lines,
+// so it cannot tell drops from filtered ones by their IDs. Callers hold
+// b.ringMu.
+func (b *Broker) deliverLocked(cli *client, q queued, filtered bool) {
+	cli.sendMu.Lock()
+	defer cli.sendMu.Unlock()
+	report := !cli.seesGaps.Load() || filtered
+	if cli.unreported > 0 && report && len(cli.ch) < cap(cli.ch)-1 {
+		_ = offer(cli, queued{buf: dropNotice(cli.unreported)})
+		cli.unreported = 0
+	}
+	if cli.lagging && len(cli.ch) <= cap(cli.ch)/2 {
+		cli.lagging = false
+	}
+	if offer(cli, q) {
+		return
+	}
+	if q.keep && b.evictLocked(cli) {
+		cli.dropped++
+		cli.unreported++
+		cli.ch <- q
+		return
+	}
+	switch b.dropPolicy {
+	case DropNewest:
+		cli.dropped++
+		cli.unreported++
+	case DisconnectSlow:
+		if b.removeClientLocked(cli) {
+			cli.dropped++
+			b.slowDisconnects++
+		}
+	case BlockWithTimeout:
+		if cli.lagging {
+			cli.dropped++
+			cli.unreported++
+			return
+		}
+		t := time.NewTimer(b.blockTimeout)
+		defer t.Stop()
+		select {
+		case cli.ch <- q:
+		case <-t.C:
+			cli.lagging = true
+			cli.dropped++
+			cli.unreported++
+		}
+	default:
+		dropped := 0
+		for !offer(cli, q) {
+			select {
+			case <-cli.ch:
+				dropped++
+			default: // the writer made room meanwhile
+			}
+		}
+		if dropped > 0 {
+			cli.dropped += uint64(dropped)
+			if report {
+				_ = offer(cli, queued{buf: dropNotice(uint64(dropped))})
+			}
+		}
+	}
+}
+
+// evictLocked discards the oldest frame in cli's queue that is not kept,
+// reporting false if there is none. Callers hold b.ringMu and cli.sendMu.
+func (b *Broker) evictLocked(cli *client) bool {
+	held := make([]queued, 0, len(cli.ch))
+	evicted := false
+drain:
+	for range cap(cli.ch) {
+		select {
+		case f := <-cli.ch:
+			if !evicted && !f.keep {
+				evicted = true
+				continue
+			}
+			held = append(held, f)
+		default:
+			break drain
+		}
+	}
+	// no other sender can queue meanwhile and the writer only takes from
+	// the queue, so everything fits back
+	for _, f := range held {
+		cli.ch <- f
+	}
+	return evicted
+}
+
+// dropNotice encodes the notice telling a viewer it lost n lines.
+func dropNotice(n uint64) []byte {
+	return noticeFrame(fmt.Sprintf("[viewer lagged; dropped %d lines]", n))
+}
+
+// ClientStats returns the delivery statistics of the client with id, or
+// false if it is not attached.
+func (b *Broker) ClientStats(id uint64) (ClientStats, bool) {
+	for _, cs := range b.Stats().Clients {
+		if cs.ID == id {
+			return cs, true
+		}
+	}
+	return ClientStats{}, false
+}
@@ -0,0 +1,62 @@
+package console
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// diffOpen and diffClose mark fields that changed since the previous similar
+// line. Attribute toggles leave colours from highlight rules intact.
+const (
+	diffOpen  = "[::bu]"
+	diffClose = "[::BU]"
+)
+
+// diffChanges returns, for each row, the byte ranges of its text holding
+// fields that differ from the previous similar row, for markVisibleRanges
+// to mark once the row is styled. Rows are similar when they have the same
+// number of fields and the same key (see diffKey); rows must be in
+// chronological order.
+func diffChanges(rows []displayRow) [][][]int {
+	out := make([][][]int, len(rows))
+	prev := make(map[string][]string)
+	for i, r := range rows {
+		fields := strings.Fields(r.text)
+		key := diffKey(fields)
+		last, seen := prev[key]
+		prev[key] = fields
+		if seen {
+			out[i] = changedFields(r.text, fields, last)
+		}
+	}
+	return out
+}
+
+// diffKey identifies "the same kind of line": the first purely alphabetic
+// field (skipping timestamps and counters) plus the field count.
+func diffKey(fields []string) string {
+	word := ""
+	for _, f := range fields {
+		if strings.IndexFunc(f, unicode.IsDigit) < 0 && strings.IndexFunc(f, unicode.IsLetter) >= 0 {
+			word = f
+			break
+		}
+	}
+	return word + "\x00" + strconv.Itoa(len(fields))
+}
+
+// changedFields returns the ranges in text of each of its fields that
+// differs from the field at the same position in prev.
+func changedFields(text string, fields, prev []string) [][]int {
+	var out [][]int
+	pos := 0
+	for idx, f := range fields {
+		start := pos + strings.Index(text[pos:], f)
+		pos = start + len(f)
+		if idx < len(prev) && prev[idx] != f {
+			out = append(out, []int{start, pos})
+		}
+	}
+	return out
+}
@@ -0,0 +1,283 @@
+package console
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// exportHTMLHead opens a self-contained HTML document; %s is the title and
+// literal percent signs are doubled.
+const exportHTMLHead = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { background: #101010; color: #d0d0d0; margin: 0; }
+pre { font-family: ui-monospace, Menlo, Consolas, monospace; font-size: 13px; padding: 1em; margin: 0; }
+@keyframes blink { 50%% { opacity: 0; } }
+</style>
+</head>
+<body>
+<pre>
+`
+
+const exportHTMLTail = `</pre>
+</body>
+</html>
+`
+
+// WriteHTML writes tview-tagged lines as a self-contained HTML document that
+// preserves their colours and attributes.
+func WriteHTML(w io.Writer, title string, lines []string) error {
+	if _, err := fmt.Fprintf(w, exportHTMLHead, html.EscapeString(title)); err != nil {
+		return err
+	}
+	for _, l := range lines {
+		if _, err := io.WriteString(w, markupToHTML(l)+"\n"); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, exportHTMLTail)
+	return err
+}
+
+// markupToHTML converts one tview-tagged line to HTML spans.
+func markupToHTML(s string) string {
+	var b strings.Builder
+	walkMarkup(s, func(text string, st markupStyle) {
+		css := htmlStyle(st)
+		if css == "" {
+			b.WriteString(html.EscapeString(text))
+			return
+		}
+		fmt.Fprintf(&b, `<span style="%s">%s</span>`, css, html.EscapeString(text))
+	})
+	return b.String()
+}
+
+func htmlStyle(st markupStyle) string {
+	fg, bg := st.fg, st.bg
+	if strings.Contains(st.attrs, "r") {
+		fg, bg = bg, fg
+		if fg == "" {
+			fg = "#101010"
+		}
+		if bg == "" {
+			bg = "#d0d0d0"
+		}
+	}
+	var css []string
+	if fg != "" {
+		css = append(css, "color:"+fg)
+	}
+	if bg != "" {
+		css = append(css, "background:"+bg)
+	}
+	for _, a := range st.attrs {
+		switch a {
+		case 'b':
+			css = append(css, "font-weight:bold")
+		case 'i':
+			css = append(css, "font-style:italic")
+		case 'u':
+			css = append(css, "text-decoration:underline")
+		case 's':
+			css = append(css, "text-decoration:line-through")
+		case 'd':
+			css = append(css, "opacity:0.6")
+		case 'l':
+			css = append(css, "animation:blink 1s step-start infinite")
+		}
+	}
+	return html.EscapeString(strings.Join(css, ";"))
+}
+
+// exportFormats are the formats :export and the export modal write.
+var exportFormats = []string{"text", "ndjson", "html", "ansi"}
+
+// exportRows returns the filtered rows in display order, or with all set
+// every buffered line, unfolded and in buffer order.
+func (u *UI) exportRows(all bool) []displayRow {
+	if !all {
+		return u.displayRows()
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	out := make([]displayRow, 0, len(u.lines))
+	for i := range u.lines {
+		l := &u.lines[i]
+		out = append(out, displayRow{text: u.rowTextLocked(l), seq: l.seq, key: rowKey{l.ord, -1}, level: l.level, source: l.source})
+		for j, c := range l.cont {
+			out = append(out, displayRow{text: u.contTextLocked(c.text), seq: c.seq, key: rowKey{l.ord, j}, level: l.level})
+		}
+	}
+	return out
+}
+
+// exportLines returns rows with highlight rules applied.
+func (u *UI) exportLines(rows []displayRow) []string {
+	out := make([]string, len(rows))
+	for i, r := range rows {
+		out[i] = u.styleRow(r.level, r.source, r.text)
+	}
+	return out
+}
+
+// exportRecords returns the lines behind rows as line frames. A folded row
+// stands for its whole group.
+func (u *UI) exportRecords(rows []displayRow, folded bool) []Line {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	byOrd := make(map[uint64]*logLine, len(u.lines))
+	for i := range u.lines {
+		byOrd[u.lines[i].ord] = &u.lines[i]
+	}
+	out := make([]Line, 0, len(rows))
+	for _, r := range rows {
+		l := byOrd[r.key.ord]
+		if l == nil {
+			continue // trimmed since
+		}
+		ts := l.tsUs
+		if ts == 0 {
+			ts = l.when.UnixMicro()
+		}
+		ev := Line{Type: "line", TsUs: ts, Level: l.level, Channel: l.channel, Fields: l.fields}
+		if r.key.sub < 0 {
+			ev.Text, ev.Seq = stripMarkup(l.text), l.seq
+			out = append(out, ev)
+			if !folded {
+				continue
+			}
+		}
+		for j, c := range l.cont {
+			if folded || j == r.key.sub {
+				ev.Text, ev.Seq, ev.Fields = stripMarkup(c.text), c.seq, nil
+				out = append(out, ev)
+			}
+		}
+	}
+	return out
+}
+
+// exportPath returns path, or a timestamped file name with ext when empty.
+func exportPath(path, ext string) string {
+	if path != "" {
+		return path
+	}
+	return "console-" + time.Now().Format("20060102-150405") + "." + ext
+}
+
+// exportFile writes the filtered buffer, or with all set the whole buffer,
+// in format to path or a timestamped file. It returns the path written and
+// the number of lines.
+func (u *UI) exportFile(format, path string, all bool) (string, int, error) {
+	ext := map[string]string{"text": "txt", "ndjson": "ndjson", "html": "html", "ansi": "ansi"}[format]
+	if ext == "" {
+		return "", 0, fmt.Errorf("unknown format %s", format)
+	}
+	path = exportPath(path, ext)
+	rows := u.exportRows(all)
+	u.mu.Lock()
+	title, folded := u.title, u.folded && !all
+	u.mu.Unlock()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", 0, err
+	}
+	n := len(rows)
+	switch format {
+	case "text":
+		w := bufio.NewWriter(f)
+		for _, r := range rows {
+			_, _ = w.WriteString(stripMarkup(r.text) + "\n")
+		}
+		err = w.Flush()
+	case "ndjson":
+		records := u.exportRecords(rows, folded)
+		n = len(records)
+		enc := json.NewEncoder(f)
+		for i := 0; i < len(records) && err == nil; i++ {
+			err = enc.Encode(records[i])
+		}
+	case "html":
+		if title == "" {
+			title = u.msg("export.title")
+		}
+		err = WriteHTML(f, title, u.exportLines(rows))
+	case "ansi":
+		err = WriteANSI(f, u.exportLines(rows))
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if abs, aerr := filepath.Abs(path); aerr == nil {
+		path = abs
+	}
+	return path, n, err
+}
+
+// exportCommand runs :export [all] <format> [path].
+func (u *UI) exportCommand(args []string) {
+	all := len(args) > 0 && args[0] == "all"
+	if all {
+		args = args[1:]
+	}
+	if len(args) < 1 || len(args) > 2 {
+		u.setStatusMessage(u.msg("export.usage"))
+		return
+	}
+	if !slices.Contains(exportFormats, args[0]) {
+		u.setStatusMessage(u.msg("export.format", args[0]))
+		return
+	}
+	path := ""
+	if len(args) == 2 {
+		path = args[1]
+	}
+	path, n, err := u.exportFile(args[0], path, all)
+	if err != nil {
+		u.setStatusMessage(u.msg("export.error", err))
+		return
+	}
+	u.setStatusMessage(u.msg("export.done", n, path))
+}
+
+// showExportModal asks what to export, writes it to a timestamped file in
+// the working directory and shows where it went.
+func (u *UI) showExportModal() {
+	type choice struct {
+		format string
+		all    bool
+	}
+	choices := []choice{{"text", false}, {"ndjson", false}, {"text", true}, {"ndjson", true}}
+	labels := []string{u.msg("export.text"), u.msg("export.ndjson"), u.msg("export.all_text"), u.msg("export.all_ndjson"), u.msg("export.cancel")}
+	m := tview.NewModal().SetText(u.msg("export.prompt")).AddButtons(labels)
+	m.SetDoneFunc(func(i int, _ string) {
+		if i < 0 || i >= len(choices) {
+			u.closeModal()
+			return
+		}
+		path, n, err := u.exportFile(choices[i].format, "", choices[i].all)
+		text := u.msg("export.done", n, path)
+		if err != nil {
+			text = u.msg("export.error", err)
+		}
+		done := tview.NewModal().SetText(text).AddButtons([]string{u.msg("help.close")})
+		done.SetDoneFunc(func(int, string) { u.closeModal() })
+		u.showModal(done)
+	})
+	u.showModal(m)
+}
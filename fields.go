@@ -0,0 +1,127 @@
+package console
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rivo/tview"
+)
+
+// maxColumnWidth caps a field column; longer values are cut with an ellipsis.
+const maxColumnWidth = 24
+
+// formatFields renders fields as sorted " key=value" pairs, leaving out the
+// keys in skip. The result is escaped for tview.
+func formatFields(fields map[string]string, skip []string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		if !slices.Contains(skip, k) {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(" " + tview.Escape(k+"="+fields[k]))
+	}
+	return b.String()
+}
+
+// groupShownLocked reports whether the group passes the channel and field
+// selections. Callers hold u.mu.
+func (u *UI) groupShownLocked(l *logLine) bool {
+	if !u.channelShownLocked(l.channel) || u.hiddenLevels[l.level] {
+		return false
+	}
+	if u.whereKey == "" {
+		return true
+	}
+	v, ok := l.fields[u.whereKey]
+	return ok && v == u.whereValue
+}
+
+// rowTextLocked returns the text of a group's parent row: the selected
+// fields as aligned columns before the message, or otherwise every field as
+// key=value pairs after it. Callers hold u.mu.
+func (u *UI) rowTextLocked(l *logLine) string {
+	if len(u.columns) == 0 {
+		return l.text + formatFields(l.fields, nil)
+	}
+	var b strings.Builder
+	for i, c := range u.columns {
+		v := l.fields[c]
+		if utf8.RuneCountInString(v) > maxColumnWidth {
+			v = string([]rune(v)[:maxColumnWidth-1]) + "…"
+		}
+		b.WriteString(tview.Escape(v))
+		b.WriteString(strings.Repeat(" ", u.colWidths[i]-utf8.RuneCountInString(v)+1))
+	}
+	b.WriteString(l.text)
+	return b.String()
+}
+
+// contTextLocked returns a continuation row, indented past any columns.
+// Callers hold u.mu.
+func (u *UI) contTextLocked(text string) string {
+	if len(u.columns) == 0 {
+		return text
+	}
+	n := 0
+	for _, w := range u.colWidths {
+		n += w + 1
+	}
+	return strings.Repeat(" ", n) + text
+}
+
+// widenColumnsLocked grows the column widths to fit fields and reports
+// whether any changed. Callers hold u.mu.
+func (u *UI) widenColumnsLocked(fields map[string]string) bool {
+	grew := false
+	for i, c := range u.columns {
+		w := min(utf8.RuneCountInString(fields[c]), maxColumnWidth)
+		if w > u.colWidths[i] {
+			u.colWidths[i] = w
+			grew = true
+		}
+	}
+	return grew
+}
+
+// setColumnsLocked selects the fields shown as columns, sized to the
+// buffered lines. Callers hold u.mu.
+func (u *UI) setColumnsLocked(columns []string) {
+	u.columns = columns
+	u.colWidths = make([]int, len(columns))
+	for i := range u.lines {
+		u.widenColumnsLocked(u.lines[i].fields)
+	}
+	u.needFull = true
+}
+
+// setColumnsDirect selects the fields shown as columns; none restores the
+// key=value rendering.
+func (u *UI) setColumnsDirect(columns []string) {
+	u.mu.Lock()
+	u.setColumnsLocked(columns)
+	u.mu.Unlock()
+	u.refreshDirect()
+}
+
+// setWhereDirect shows only lines whose field key equals value, from an
+// expression "key=value"; an empty expression clears it. It reports false
+// for a malformed expression.
+func (u *UI) setWhereDirect(expr string) bool {
+	key, value, ok := strings.Cut(expr, "=")
+	if expr != "" && (!ok || key == "") {
+		return false
+	}
+	u.mu.Lock()
+	u.whereKey, u.whereValue = key, value
+	u.mu.Unlock()
+	u.refreshDirect()
+	return true
+}
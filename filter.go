@@ -0,0 +1,263 @@
+package console
+
+import (
+	"strings"
+	"time"
+)
+
+// maxFilterBadgeWidth caps the filter shown in the status bar.
+const maxFilterBadgeWidth = 32
+
+// filterTerm is one pattern of a filter. A term starting with "-" hides the
+// lines it matches instead of keeping them; "\-" filters for a literal
+// leading dash. A time term, "since:<when>" or "until:<when>", keeps the
+// lines stamped at or after, or before, a time instead of matching text.
+type filterTerm struct {
+	text    string       // the pattern, without the "-"
+	m       *textMatcher // nil for a time term
+	exclude bool
+	since   time.Time
+	until   time.Time
+}
+
+// parseFilterTerm parses one term. An invalid regular expression, typically
+// one still being typed, is matched literally.
+func parseFilterTerm(text string, caseSensitive, regex bool) filterTerm {
+	var t filterTerm
+	switch {
+	case strings.HasPrefix(text, `\-`):
+		text = text[1:]
+	case strings.HasPrefix(text, "-"):
+		text, t.exclude = text[1:], true
+	}
+	if since, until, ok := parseTimeTerm(text, time.Now()); ok {
+		t.text, t.since, t.until = text, since, until
+		return t
+	}
+	m, err := newTextMatcher(text, caseSensitive, regex)
+	if err != nil {
+		m, _ = newTextMatcher(text, caseSensitive, false)
+	}
+	t.text, t.m = text, m
+	return t
+}
+
+// parseTimeTerm parses a time term. The time is a duration ago, such as
+// 10m, a time of day today, such as 14:30 or 14:30:05, or a timestamp in one
+// of DefaultTimestampLayouts. Durations count back from now, when the filter
+// is applied.
+func parseTimeTerm(text string, now time.Time) (since, until time.Time, ok bool) {
+	name, when, found := strings.Cut(text, ":")
+	if !found || name != "since" && name != "until" {
+		return time.Time{}, time.Time{}, false
+	}
+	t, ok := parseFilterTime(strings.TrimSpace(when), now)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	if name == "since" {
+		return t, time.Time{}, true
+	}
+	return time.Time{}, t, true
+}
+
+// filterTimeOfDay are the layouts of a time of day in a time term.
+var filterTimeOfDay = []string{"15:04:05", "15:04"}
+
+// parseFilterTime parses the time of a time term.
+func parseFilterTime(s string, now time.Time) (time.Time, bool) {
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), true
+	}
+	for _, layout := range filterTimeOfDay {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			y, m, d := now.Date()
+			return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), 0, time.Local), true
+		}
+	}
+	for _, layout := range DefaultTimestampLayouts {
+		if t, ok := parseTimestamp(layout, s, now); ok {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// match reports whether a line stamped when passes the term. A time term
+// never keeps a line without a time.
+func (t filterTerm) match(s string, when time.Time) bool {
+	if t.m == nil {
+		in := !when.IsZero() && (t.since.IsZero() || !when.Before(t.since)) && (t.until.IsZero() || when.Before(t.until))
+		return in != t.exclude
+	}
+	return t.m.match(s) != t.exclude
+}
+
+// filterExpr is a parsed filter: alternatives separated by "||", each a set
+// of terms separated by "&&" that must all pass. "OFFER && 192.168.10"
+// keeps offers for that subnet, "NAK || DECLINE" either kind of line, and
+// "since:10m && -keepalive" the last ten minutes without keepalives.
+type filterExpr [][]filterTerm
+
+// parseFilter parses filter text, reporting false if it has no pattern.
+// Text without operators is a single term, spaces included.
+func parseFilter(text string, caseSensitive, regex bool) (filterExpr, bool) {
+	if !strings.Contains(text, "&&") && !strings.Contains(text, "||") {
+		if strings.TrimSpace(strings.TrimPrefix(text, "-")) == "" {
+			return nil, false
+		}
+		return filterExpr{{parseFilterTerm(text, caseSensitive, regex)}}, true
+	}
+	var e filterExpr
+	for _, alt := range strings.Split(text, "||") {
+		var all []filterTerm
+		for _, s := range strings.Split(alt, "&&") {
+			s = strings.TrimSpace(s)
+			if strings.TrimSpace(strings.TrimPrefix(s, "-")) == "" {
+				continue // an operand still being typed
+			}
+			all = append(all, parseFilterTerm(s, caseSensitive, regex))
+		}
+		if len(all) > 0 {
+			e = append(e, all)
+		}
+	}
+	return e, len(e) > 0
+}
+
+// match reports whether a line stamped when passes the filter.
+func (e filterExpr) match(s string, when time.Time) bool {
+	for _, all := range e {
+		ok := true
+		for _, t := range all {
+			if !t.match(s, when) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// pass reports whether a line stamped when passes the filter; a nil filter
+// passes everything.
+func (e *filterExpr) pass(s string, when time.Time) bool {
+	return e == nil || e.match(s, when)
+}
+
+// filterText is the text of ev that a broker-side Filter is matched against.
+func filterText(ev Line) string {
+	if ev.Source == "" {
+		return ev.Text
+	}
+	return "[" + ev.Source + "] " + ev.Text
+}
+
+// markers returns the matchers of the text terms that keep lines, whose
+// matches are marked in the view.
+func (e filterExpr) markers() []*textMatcher {
+	var out []*textMatcher
+	for _, all := range e {
+		for _, t := range all {
+			if !t.exclude && t.m != nil {
+				out = append(out, t.m)
+			}
+		}
+	}
+	return out
+}
+
+// excludeOnly reports whether the filter is a single term hiding lines.
+func (e filterExpr) excludeOnly() bool {
+	return len(e) == 1 && len(e[0]) == 1 && e[0][0].exclude
+}
+
+// String formats the filter for the status bar.
+func (e filterExpr) String() string {
+	if e.excludeOnly() {
+		return e[0][0].text
+	}
+	alts := make([]string, len(e))
+	for i, all := range e {
+		terms := make([]string, len(all))
+		for j, t := range all {
+			switch {
+			case t.exclude:
+				terms[j] = "-" + t.text
+			case strings.HasPrefix(t.text, "-"):
+				terms[j] = `\` + t.text
+			default:
+				terms[j] = t.text
+			}
+		}
+		alts[i] = strings.Join(terms, " && ")
+	}
+	return strings.Join(alts, " || ")
+}
+
+// toggleServerFilterDirect switches between having the broker apply the
+// filter, so only passing lines are sent, and receiving every line. Lines
+// the broker left out are not fetched when the filter widens.
+func (u *UI) toggleServerFilterDirect() {
+	u.mu.Lock()
+	send, supported := u.onServerFilter, u.server.Supports("filter")
+	if send != nil && supported {
+		u.serverFilter = !u.serverFilter
+	}
+	on := u.serverFilter
+	u.mu.Unlock()
+	switch {
+	case send == nil:
+		u.setStatusMessage(u.msg("filter.unavailable"))
+		return
+	case !supported:
+		u.setStatusMessage(u.msg("server.unsupported", "filtering"))
+		return
+	case on:
+		u.setStatusMessage(u.msg("filter.server_on"))
+	default:
+		u.setStatusMessage(u.msg("filter.server_off"))
+	}
+	u.syncServerFilter()
+	u.updateBottomBarDirect()
+}
+
+// syncServerFilter sends the broker the filter it should apply, if that
+// changed since it was last sent.
+func (u *UI) syncServerFilter() {
+	u.mu.Lock()
+	send := u.onServerFilter
+	var f Filter
+	if u.serverFilter && u.filterActive {
+		caseOn := u.caseSensitiveLocked()
+		if _, ok := parseFilter(u.filter, caseOn, u.filterRegex); ok {
+			f = Filter{Text: u.filter, CaseSensitive: caseOn, Regex: u.filterRegex}
+		}
+	}
+	changed := f != u.sentFilter
+	u.sentFilter = f
+	u.mu.Unlock()
+	if send != nil && changed {
+		f.Type = "filter"
+		send(f)
+	}
+}
+
+// resendServerFilter sends the filter again on a new connection.
+func (u *UI) resendServerFilter() {
+	u.mu.Lock()
+	u.sentFilter = Filter{} // what a new connection starts with
+	u.mu.Unlock()
+	u.syncServerFilter()
+}
+
+// serverFiltering reports whether the broker currently filters the lines it
+// sends, so gaps in line IDs are expected.
+func (u *UI) serverFiltering() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.sentFilter.Text != ""
+}
@@ -0,0 +1,112 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// logLine is one buffered entry: a parent line plus any continuation lines
+// (indented lines, Go panic traces) grouped under it. A group counts as one
+// line against MaxLines.
+type logLine struct {
+	text string
+	when time.Time
+	tsUs int64  // server timestamp, 0 for local lines
+	seq  uint64 // broker line ID, 0 for local lines
+	ord  uint64 // UI-local identity, unique among buffered groups
+	// fields are the line's structured fields, shown as columns or pairs
+	fields map[string]string
+	// channel is the broker channel of the group, "" for the default one
+	channel string
+	level   string // level class, one of levels
+	source  string // Line.Source of the parent line
+	cont    []subLine
+}
+
+// subLine is a continuation line within a group.
+type subLine struct {
+	text string
+	seq  uint64
+}
+
+// hasSeq reports whether the parent or a continuation line carries id.
+func (l *logLine) hasSeq(id uint64) bool {
+	if l.seq == id {
+		return true
+	}
+	for _, c := range l.cont {
+		if c.seq == id {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAny reports whether the parent or any continuation line matches;
+// they all carry the group's time.
+func (l *logLine) matchesAny(match func(text string, when time.Time) bool) bool {
+	if match(l.text, l.when) {
+		return true
+	}
+	for _, c := range l.cont {
+		if match(c.text, l.when) {
+			return true
+		}
+	}
+	return false
+}
+
+// foldedText renders the group, whose parent row reads text, as a single row
+// with a hidden-line marker.
+func (l *logLine) foldedText(text string) string {
+	if len(l.cont) == 0 {
+		return text
+	}
+	return fmt.Sprintf("%s ▸ +%d lines", text, len(l.cont))
+}
+
+// maxGroupLines caps the continuation lines in a group. MaxLines counts
+// groups, so without it a producer indenting all its output would build one
+// group that is never trimmed; past the cap a new group starts.
+const maxGroupLines = 256
+
+// isContinuation reports whether text, on channel, belongs to the group
+// started by last.
+func isContinuation(last *logLine, channel, text string) bool {
+	if last == nil || last.channel != channel || len(last.cont) >= maxGroupLines {
+		return false
+	}
+	if strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t") {
+		return true
+	}
+	if !isPanicHeader(last.text) {
+		return false
+	}
+	switch {
+	case text == "":
+		return true
+	case strings.HasPrefix(text, "goroutine ") && strings.HasSuffix(text, "]:"):
+		return true
+	case strings.HasPrefix(text, "created by "):
+		return true
+	case strings.HasPrefix(text, "exit status "):
+		return true
+	}
+	return looksLikeGoFrame(text)
+}
+
+func isPanicHeader(s string) bool {
+	return strings.HasPrefix(s, "panic: ") || strings.HasPrefix(s, "fatal error: ")
+}
+
+// looksLikeGoFrame matches stack frame lines such as "main.main()" or
+// "example.com/pkg.(*T).Method(0xc000010000, ...)".
+func looksLikeGoFrame(s string) bool {
+	open := strings.IndexByte(s, '(')
+	if open <= 0 || !strings.HasSuffix(s, ")") {
+		return false
+	}
+	fn := s[:open]
+	return strings.Contains(fn, ".") && !strings.ContainsAny(fn, " \t")
+}
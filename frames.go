@@ -0,0 +1,306 @@
+package console
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+// MaxFrameBytes is the largest NDJSON frame exchanged between broker and
+// clients. The broker truncates line text so that no frame exceeds it, and
+// clients discard anything longer as malformed.
+const MaxFrameBytes = 1 << 20
+
+// maxLineTextBytes leaves room for the JSON envelope around a line's text.
+const maxLineTextBytes = MaxFrameBytes - 4096
+
+const truncatedSuffix = "…[truncated]"
+
+// truncateLineText shortens s so that its Line frame stays under MaxFrameBytes.
+func truncateLineText(s string) string {
+	return truncateEncoded(s, maxLineTextBytes)
+}
+
+// truncateEncoded shortens s, marking the cut with truncatedSuffix, so that
+// it JSON-encodes to at most budget bytes. Escaping grows control
+// characters, quotes and HTML characters up to six times, so the cut is
+// measured on the encoded form rather than on s.
+func truncateEncoded(s string, budget int) string {
+	if len(s) <= budget/6 || encodedLen(s) <= budget {
+		return s
+	}
+	budget -= encodedLen(truncatedSuffix)
+	n, cut := 0, 0
+	for cut < len(s) {
+		r, size := utf8.DecodeRuneInString(s[cut:])
+		n += encodedRuneLen(r, size)
+		if n > budget {
+			break
+		}
+		cut += size
+	}
+	return s[:cut] + truncatedSuffix
+}
+
+// encodedLen returns the length of s as encoded by json.Marshal, without
+// its quotes.
+func encodedLen(s string) int {
+	n := 0
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		n += encodedRuneLen(r, size)
+		i += size
+	}
+	return n
+}
+
+// encodedRuneLen returns the encoded length of r, which took size bytes of
+// the string it came from.
+func encodedRuneLen(r rune, size int) int {
+	switch {
+	case r == '"' || r == '\\' || r == '\n' || r == '\r' || r == '\t':
+		return 2
+	case r < 0x20 || r == '<' || r == '>' || r == '&' || r == '\u2028' || r == '\u2029':
+		return 6
+	case r == utf8.RuneError && size == 1:
+		return 6 // invalid bytes become \ufffd
+	}
+	return size
+}
+
+// fitLineFrame bounds ev's whole line frame under MaxFrameBytes. The text is
+// cut first; when Source, Channel, Fields and Spans still push the frame
+// over, the text gives up the difference, and if that is not enough the
+// fields and spans are dropped and the source and channel cut as well.
+func fitLineFrame(ev *Line) {
+	ev.Text = truncateLineText(ev.Text)
+	over := len(lineFrame(*ev)) - MaxFrameBytes
+	if over <= 0 {
+		return
+	}
+	if text := encodedLen(ev.Text); over < text {
+		ev.Text = truncateEncoded(ev.Text, text-over)
+		ev.Spans = clipSpans(ev.Spans, len(ev.Text)-len(truncatedSuffix))
+		return
+	}
+	ev.Fields, ev.Spans = nil, nil
+	ev.Source = truncateEncoded(ev.Source, 1024)
+	ev.Channel = truncateEncoded(ev.Channel, 1024)
+	if over = len(lineFrame(*ev)) - MaxFrameBytes; over > 0 {
+		ev.Text = truncateEncoded(ev.Text, encodedLen(ev.Text)-over)
+	}
+}
+
+// clipSpans drops the spans past n bytes of text and shortens the one
+// crossing it.
+func clipSpans(spans []Span, n int) []Span {
+	out := spans[:0]
+	for _, sp := range spans {
+		if sp.Start >= n {
+			continue
+		}
+		sp.End = min(sp.End, n)
+		out = append(out, sp)
+	}
+	return out
+}
+
+// frameReader reads newline-delimited frames. Frames longer than the reader's
+// buffer are skipped up to the next newline and counted as malformed, so one
+// oversized frame cannot desynchronize the stream.
+//
+// A reader of a broker connection switches to length-prefixed frames and
+// gzip decompression if the first meta frame says the broker does; see
+// Meta.Framing and Meta.Compression.
+type frameReader struct {
+	r         *bufio.Reader
+	malformed int
+	// negotiate is set for broker connections, whose hello asked for
+	// length-prefixed frames and compression
+	negotiate      bool
+	sawMeta        bool
+	lengthPrefixed bool
+	gunzip         bool // the rest of the stream is gzipped
+}
+
+func newFrameReader(r io.Reader) *frameReader {
+	return &frameReader{r: bufio.NewReaderSize(r, MaxFrameBytes)}
+}
+
+// newConnFrameReader reads frames from a broker connection after writeHello.
+func newConnFrameReader(r io.Reader) *frameReader {
+	fr := newFrameReader(r)
+	fr.negotiate = true
+	return fr
+}
+
+// errFrameTooLarge reports a length prefix over MaxFrameBytes, after which
+// the stream cannot be resynchronized.
+var errFrameTooLarge = errors.New("frame exceeds MaxFrameBytes")
+
+// metaPrefix starts a meta frame as the broker encodes it.
+var metaPrefix = []byte(`{"type":"meta"`)
+
+// next returns the next frame including its trailing newline. The returned
+// slice is owned by the caller.
+func (fr *frameReader) next() ([]byte, error) {
+	if fr.gunzip {
+		fr.gunzip = false
+		zr, err := gzip.NewReader(fr.r)
+		if err != nil {
+			return nil, err
+		}
+		fr.r = bufio.NewReaderSize(gzipEOF{zr}, MaxFrameBytes)
+	}
+	if fr.lengthPrefixed {
+		var hdr [4]byte
+		if _, err := io.ReadFull(fr.r, hdr[:]); err != nil {
+			return nil, err
+		}
+		n := binary.BigEndian.Uint32(hdr[:])
+		if n > MaxFrameBytes {
+			return nil, errFrameTooLarge
+		}
+		b := make([]byte, n, n+1)
+		if _, err := io.ReadFull(fr.r, b); err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	}
+	b, err := fr.nextLine()
+	if err == nil && fr.negotiate && !fr.sawMeta && bytes.HasPrefix(b, metaPrefix) {
+		// only the meta opening a connection switches framing
+		fr.sawMeta = true
+		var m struct {
+			Framing     string `json:"framing"`
+			Compression string `json:"compression"`
+		}
+		if json.Unmarshal(b, &m) == nil {
+			fr.lengthPrefixed = m.Framing == framingLength
+			fr.gunzip = m.Compression == compressionGzip
+		}
+	}
+	return b, err
+}
+
+func (fr *frameReader) nextLine() ([]byte, error) {
+	for {
+		line, err := fr.r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			fr.malformed++
+			if err := fr.skipLine(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err == io.EOF && len(line) > 0 {
+			// a final frame without its newline is still a frame
+			return append([]byte(nil), line...), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), line...), nil
+	}
+}
+
+func (fr *frameReader) skipLine() error {
+	for {
+		_, err := fr.r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		return err
+	}
+}
+
+var frameStart = []byte(`{"type":`)
+
+// framingLength is the Meta.Framing value, and the hello capability asking
+// for it, of frames sent as a 4-byte big-endian length and the JSON
+// document, without a newline.
+const framingLength = "length_prefix"
+
+// compressionGzip is the Meta.Compression value, and the hello capability
+// asking for it, of a stream gzipped after the meta.
+const compressionGzip = "gzip"
+
+// gzipEOF reads a compressed stream, which the broker never ends: a closed
+// connection is the end of the stream, as it is uncompressed.
+type gzipEOF struct{ r io.Reader }
+
+func (g gzipEOF) Read(p []byte) (int, error) {
+	n, err := g.r.Read(p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// writeFrame writes f, which ends with a newline, as is or length-prefixed.
+func writeFrame(w *bufio.Writer, f []byte, lengthPrefixed bool) error {
+	if lengthPrefixed {
+		f = bytes.TrimSuffix(f, []byte{'\n'})
+		if err := writeLength(w, len(f)); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(f)
+	return err
+}
+
+// writeLength writes the length prefix of a frame of n bytes.
+func writeLength(w *bufio.Writer, n int) error {
+	var hdr [4]byte
+	binary.BigEndian.PutUint32(hdr[:], uint32(n))
+	_, err := w.Write(hdr[:])
+	return err
+}
+
+// peekFrameType returns the type of frame b. If b does not parse, it skips to
+// the next embedded frame start (a frame truncated by a lost newline is
+// followed by a complete one) and tries again. Every skipped fragment is
+// counted as malformed. It returns the remaining valid frame, or nil.
+func (fr *frameReader) peekFrameType(b []byte) (string, []byte) {
+	var typ struct {
+		Type string `json:"type"`
+	}
+	for len(b) > 0 {
+		if json.Unmarshal(b, &typ) == nil {
+			return typ.Type, b
+		}
+		fr.malformed++
+		i := bytes.Index(b[1:], frameStart)
+		if i < 0 {
+			return "", nil
+		}
+		b = b[i+1:]
+	}
+	return "", nil
+}
+
+// takeMalformed returns and resets the malformed frame count.
+func (fr *frameReader) takeMalformed() int {
+	n := fr.malformed
+	fr.malformed = 0
+	return n
+}
+
+// clientHello is the hello sent by this package's clients.
+func clientHello() Hello {
+	return Hello{Type: "hello", Version: ProtocolVersion, Capabilities: []string{"lines", "counters", "viewers", framingLength, compressionGzip}, Client: "planeconsole"}
+}
+
+// writeHello sends clientHello to a broker, asking for lines after sinceUs.
+func writeHello(w io.Writer, sinceUs int64, token string) error {
+	h := clientHello()
+	h.SinceUs, h.Token = sinceUs, token
+	buf, _ := json.Marshal(h)
+	_, err := w.Write(append(buf, '\n'))
+	return err
+}
@@ -0,0 +1,654 @@
+// The gRPC transport served by grpcconsole.Register. Clients in other
+// languages can generate stubs from this file; consolepb holds the Go code
+// generated from it.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: consolepb/console.proto
+
+package consolepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Request struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Request:
+	//
+	//	*Request_Hello
+	//	*Request_Command
+	Request       isRequest_Request `protobuf_oneof:"request"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Request) Reset() {
+	*x = Request{}
+	mi := &file_consolepb_console_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Request) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Request) ProtoMessage() {}
+
+func (x *Request) ProtoReflect() protoreflect.Message {
+	mi := &file_consolepb_console_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Request.ProtoReflect.Descriptor instead.
+func (*Request) Descriptor() ([]byte, []int) {
+	return file_consolepb_console_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Request) GetRequest() isRequest_Request {
+	if x != nil {
+		return x.Request
+	}
+	return nil
+}
+
+func (x *Request) GetHello() *Hello {
+	if x != nil {
+		if x, ok := x.Request.(*Request_Hello); ok {
+			return x.Hello
+		}
+	}
+	return nil
+}
+
+func (x *Request) GetCommand() *Command {
+	if x != nil {
+		if x, ok := x.Request.(*Request_Command); ok {
+			return x.Command
+		}
+	}
+	return nil
+}
+
+type isRequest_Request interface {
+	isRequest_Request()
+}
+
+type Request_Hello struct {
+	Hello *Hello `protobuf:"bytes,1,opt,name=hello,proto3,oneof"`
+}
+
+type Request_Command struct {
+	Command *Command `protobuf:"bytes,2,opt,name=command,proto3,oneof"`
+}
+
+func (*Request_Hello) isRequest_Request() {}
+
+func (*Request_Command) isRequest_Request() {}
+
+type Hello struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Replay only lines stamped after this, in microseconds since the epoch.
+	SinceUs int64 `protobuf:"varint,1,opt,name=since_us,json=sinceUs,proto3" json:"since_us,omitempty"`
+	// Names the client software, for logs and stats.
+	Client string `protobuf:"bytes,2,opt,name=client,proto3" json:"client,omitempty"`
+	// Authenticates to a broker with BrokerOptions.Authenticate.
+	Token         string `protobuf:"bytes,3,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Hello) Reset() {
+	*x = Hello{}
+	mi := &file_consolepb_console_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Hello) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Hello) ProtoMessage() {}
+
+func (x *Hello) ProtoReflect() protoreflect.Message {
+	mi := &file_consolepb_console_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Hello.ProtoReflect.Descriptor instead.
+func (*Hello) Descriptor() ([]byte, []int) {
+	return file_consolepb_console_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Hello) GetSinceUs() int64 {
+	if x != nil {
+		return x.SinceUs
+	}
+	return 0
+}
+
+func (x *Hello) GetClient() string {
+	if x != nil {
+		return x.Client
+	}
+	return ""
+}
+
+func (x *Hello) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+// Command is operator input passed to BrokerOptions.OnCommand.
+type Command struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Command) Reset() {
+	*x = Command{}
+	mi := &file_consolepb_console_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Command) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Command) ProtoMessage() {}
+
+func (x *Command) ProtoReflect() protoreflect.Message {
+	mi := &file_consolepb_console_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Command.ProtoReflect.Descriptor instead.
+func (*Command) Descriptor() ([]byte, []int) {
+	return file_consolepb_console_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Command) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type Frame struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Frame:
+	//
+	//	*Frame_Meta
+	//	*Frame_Line
+	//	*Frame_Notice
+	Frame         isFrame_Frame `protobuf_oneof:"frame"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Frame) Reset() {
+	*x = Frame{}
+	mi := &file_consolepb_console_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Frame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Frame) ProtoMessage() {}
+
+func (x *Frame) ProtoReflect() protoreflect.Message {
+	mi := &file_consolepb_console_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Frame.ProtoReflect.Descriptor instead.
+func (*Frame) Descriptor() ([]byte, []int) {
+	return file_consolepb_console_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Frame) GetFrame() isFrame_Frame {
+	if x != nil {
+		return x.Frame
+	}
+	return nil
+}
+
+func (x *Frame) GetMeta() *Meta {
+	if x != nil {
+		if x, ok := x.Frame.(*Frame_Meta); ok {
+			return x.Meta
+		}
+	}
+	return nil
+}
+
+func (x *Frame) GetLine() *Line {
+	if x != nil {
+		if x, ok := x.Frame.(*Frame_Line); ok {
+			return x.Line
+		}
+	}
+	return nil
+}
+
+func (x *Frame) GetNotice() *Notice {
+	if x != nil {
+		if x, ok := x.Frame.(*Frame_Notice); ok {
+			return x.Notice
+		}
+	}
+	return nil
+}
+
+type isFrame_Frame interface {
+	isFrame_Frame()
+}
+
+type Frame_Meta struct {
+	Meta *Meta `protobuf:"bytes,1,opt,name=meta,proto3,oneof"`
+}
+
+type Frame_Line struct {
+	Line *Line `protobuf:"bytes,2,opt,name=line,proto3,oneof"`
+}
+
+type Frame_Notice struct {
+	Notice *Notice `protobuf:"bytes,3,opt,name=notice,proto3,oneof"`
+}
+
+func (*Frame_Meta) isFrame_Frame() {}
+
+func (*Frame_Line) isFrame_Frame() {}
+
+func (*Frame_Notice) isFrame_Frame() {}
+
+type Meta struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	MaxLines     int32                  `protobuf:"varint,1,opt,name=max_lines,json=maxLines,proto3" json:"max_lines,omitempty"`
+	ServerTimeUs int64                  `protobuf:"varint,2,opt,name=server_time_us,json=serverTimeUs,proto3" json:"server_time_us,omitempty"`
+	StartedUs    int64                  `protobuf:"varint,3,opt,name=started_us,json=startedUs,proto3" json:"started_us,omitempty"`
+	Version      int32                  `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
+	// The broker's Config.Counters and Config.Highlights, each a JSON array
+	// as in the meta frame of the NDJSON protocol; empty when there are none.
+	CountersJson   string `protobuf:"bytes,5,opt,name=counters_json,json=countersJson,proto3" json:"counters_json,omitempty"`
+	HighlightsJson string `protobuf:"bytes,6,opt,name=highlights_json,json=highlightsJson,proto3" json:"highlights_json,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Meta) Reset() {
+	*x = Meta{}
+	mi := &file_consolepb_console_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Meta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Meta) ProtoMessage() {}
+
+func (x *Meta) ProtoReflect() protoreflect.Message {
+	mi := &file_consolepb_console_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Meta.ProtoReflect.Descriptor instead.
+func (*Meta) Descriptor() ([]byte, []int) {
+	return file_consolepb_console_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Meta) GetMaxLines() int32 {
+	if x != nil {
+		return x.MaxLines
+	}
+	return 0
+}
+
+func (x *Meta) GetServerTimeUs() int64 {
+	if x != nil {
+		return x.ServerTimeUs
+	}
+	return 0
+}
+
+func (x *Meta) GetStartedUs() int64 {
+	if x != nil {
+		return x.StartedUs
+	}
+	return 0
+}
+
+func (x *Meta) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Meta) GetCountersJson() string {
+	if x != nil {
+		return x.CountersJson
+	}
+	return ""
+}
+
+func (x *Meta) GetHighlightsJson() string {
+	if x != nil {
+		return x.HighlightsJson
+	}
+	return ""
+}
+
+type Line struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TsUs          int64                  `protobuf:"varint,1,opt,name=ts_us,json=tsUs,proto3" json:"ts_us,omitempty"`
+	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	Level         string                 `protobuf:"bytes,3,opt,name=level,proto3" json:"level,omitempty"`
+	Seq           uint64                 `protobuf:"varint,4,opt,name=seq,proto3" json:"seq,omitempty"`
+	Source        string                 `protobuf:"bytes,5,opt,name=source,proto3" json:"source,omitempty"`
+	Channel       string                 `protobuf:"bytes,6,opt,name=channel,proto3" json:"channel,omitempty"`
+	Fields        map[string]string      `protobuf:"bytes,7,rep,name=fields,proto3" json:"fields,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Line) Reset() {
+	*x = Line{}
+	mi := &file_consolepb_console_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Line) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Line) ProtoMessage() {}
+
+func (x *Line) ProtoReflect() protoreflect.Message {
+	mi := &file_consolepb_console_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Line.ProtoReflect.Descriptor instead.
+func (*Line) Descriptor() ([]byte, []int) {
+	return file_consolepb_console_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Line) GetTsUs() int64 {
+	if x != nil {
+		return x.TsUs
+	}
+	return 0
+}
+
+func (x *Line) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Line) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *Line) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Line) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Line) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+func (x *Line) GetFields() map[string]string {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+// Notice is a message from the broker, such as lines dropped for a slow
+// client.
+type Notice struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Notice) Reset() {
+	*x = Notice{}
+	mi := &file_consolepb_console_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Notice) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Notice) ProtoMessage() {}
+
+func (x *Notice) ProtoReflect() protoreflect.Message {
+	mi := &file_consolepb_console_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Notice.ProtoReflect.Descriptor instead.
+func (*Notice) Descriptor() ([]byte, []int) {
+	return file_consolepb_console_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Notice) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+var File_consolepb_console_proto protoreflect.FileDescriptor
+
+const file_consolepb_console_proto_rawDesc = "" +
+	"\n" +
+	"\x17consolepb/console.proto\x12\x0fplaneconsole.v1\"z\n" +
+	"\aRequest\x12.\n" +
+	"\x05hello\x18\x01 \x01(\v2\x16.planeconsole.v1.HelloH\x00R\x05hello\x124\n" +
+	"\acommand\x18\x02 \x01(\v2\x18.planeconsole.v1.CommandH\x00R\acommandB\t\n" +
+	"\arequest\"P\n" +
+	"\x05Hello\x12\x19\n" +
+	"\bsince_us\x18\x01 \x01(\x03R\asinceUs\x12\x16\n" +
+	"\x06client\x18\x02 \x01(\tR\x06client\x12\x14\n" +
+	"\x05token\x18\x03 \x01(\tR\x05token\"\x1d\n" +
+	"\aCommand\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\"\x9d\x01\n" +
+	"\x05Frame\x12+\n" +
+	"\x04meta\x18\x01 \x01(\v2\x15.planeconsole.v1.MetaH\x00R\x04meta\x12+\n" +
+	"\x04line\x18\x02 \x01(\v2\x15.planeconsole.v1.LineH\x00R\x04line\x121\n" +
+	"\x06notice\x18\x03 \x01(\v2\x17.planeconsole.v1.NoticeH\x00R\x06noticeB\a\n" +
+	"\x05frame\"\xd0\x01\n" +
+	"\x04Meta\x12\x1b\n" +
+	"\tmax_lines\x18\x01 \x01(\x05R\bmaxLines\x12$\n" +
+	"\x0eserver_time_us\x18\x02 \x01(\x03R\fserverTimeUs\x12\x1d\n" +
+	"\n" +
+	"started_us\x18\x03 \x01(\x03R\tstartedUs\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\x05R\aversion\x12#\n" +
+	"\rcounters_json\x18\x05 \x01(\tR\fcountersJson\x12'\n" +
+	"\x0fhighlights_json\x18\x06 \x01(\tR\x0ehighlightsJson\"\xff\x01\n" +
+	"\x04Line\x12\x13\n" +
+	"\x05ts_us\x18\x01 \x01(\x03R\x04tsUs\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\x12\x14\n" +
+	"\x05level\x18\x03 \x01(\tR\x05level\x12\x10\n" +
+	"\x03seq\x18\x04 \x01(\x04R\x03seq\x12\x16\n" +
+	"\x06source\x18\x05 \x01(\tR\x06source\x12\x18\n" +
+	"\achannel\x18\x06 \x01(\tR\achannel\x129\n" +
+	"\x06fields\x18\a \x03(\v2!.planeconsole.v1.Line.FieldsEntryR\x06fields\x1a9\n" +
+	"\vFieldsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x1c\n" +
+	"\x06Notice\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text2I\n" +
+	"\aConsole\x12>\n" +
+	"\x06Attach\x12\x18.planeconsole.v1.Request\x1a\x16.planeconsole.v1.Frame(\x010\x01B=Z;github.com/network-plane/planeconsole/grpcconsole/consolepbb\x06proto3"
+
+var (
+	file_consolepb_console_proto_rawDescOnce sync.Once
+	file_consolepb_console_proto_rawDescData []byte
+)
+
+func file_consolepb_console_proto_rawDescGZIP() []byte {
+	file_consolepb_console_proto_rawDescOnce.Do(func() {
+		file_consolepb_console_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_consolepb_console_proto_rawDesc), len(file_consolepb_console_proto_rawDesc)))
+	})
+	return file_consolepb_console_proto_rawDescData
+}
+
+var file_consolepb_console_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_consolepb_console_proto_goTypes = []any{
+	(*Request)(nil), // 0: planeconsole.v1.Request
+	(*Hello)(nil),   // 1: planeconsole.v1.Hello
+	(*Command)(nil), // 2: planeconsole.v1.Command
+	(*Frame)(nil),   // 3: planeconsole.v1.Frame
+	(*Meta)(nil),    // 4: planeconsole.v1.Meta
+	(*Line)(nil),    // 5: planeconsole.v1.Line
+	(*Notice)(nil),  // 6: planeconsole.v1.Notice
+	nil,             // 7: planeconsole.v1.Line.FieldsEntry
+}
+var file_consolepb_console_proto_depIdxs = []int32{
+	1, // 0: planeconsole.v1.Request.hello:type_name -> planeconsole.v1.Hello
+	2, // 1: planeconsole.v1.Request.command:type_name -> planeconsole.v1.Command
+	4, // 2: planeconsole.v1.Frame.meta:type_name -> planeconsole.v1.Meta
+	5, // 3: planeconsole.v1.Frame.line:type_name -> planeconsole.v1.Line
+	6, // 4: planeconsole.v1.Frame.notice:type_name -> planeconsole.v1.Notice
+	7, // 5: planeconsole.v1.Line.fields:type_name -> planeconsole.v1.Line.FieldsEntry
+	0, // 6: planeconsole.v1.Console.Attach:input_type -> planeconsole.v1.Request
+	3, // 7: planeconsole.v1.Console.Attach:output_type -> planeconsole.v1.Frame
+	7, // [7:8] is the sub-list for method output_type
+	6, // [6:7] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_consolepb_console_proto_init() }
+func file_consolepb_console_proto_init() {
+	if File_consolepb_console_proto != nil {
+		return
+	}
+	file_consolepb_console_proto_msgTypes[0].OneofWrappers = []any{
+		(*Request_Hello)(nil),
+		(*Request_Command)(nil),
+	}
+	file_consolepb_console_proto_msgTypes[3].OneofWrappers = []any{
+		(*Frame_Meta)(nil),
+		(*Frame_Line)(nil),
+		(*Frame_Notice)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_consolepb_console_proto_rawDesc), len(file_consolepb_console_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_consolepb_console_proto_goTypes,
+		DependencyIndexes: file_consolepb_console_proto_depIdxs,
+		MessageInfos:      file_consolepb_console_proto_msgTypes,
+	}.Build()
+	File_consolepb_console_proto = out.File
+	file_consolepb_console_proto_goTypes = nil
+	file_consolepb_console_proto_depIdxs = nil
+}
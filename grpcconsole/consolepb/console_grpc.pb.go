@@ -0,0 +1,125 @@
+// The gRPC transport served by grpcconsole.Register. Clients in other
+// languages can generate stubs from this file; consolepb holds the Go code
+// generated from it.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: consolepb/console.proto
+
+package consolepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Console_Attach_FullMethodName = "/planeconsole.v1.Console/Attach"
+)
+
+// ConsoleClient is the client API for Console service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ConsoleClient interface {
+	// Attach makes the caller a broker client. The first request must be a
+	// Hello; later ones carry commands. The broker answers with its Meta,
+	// then the replayed ring and live lines, and notices.
+	Attach(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Request, Frame], error)
+}
+
+type consoleClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewConsoleClient(cc grpc.ClientConnInterface) ConsoleClient {
+	return &consoleClient{cc}
+}
+
+func (c *consoleClient) Attach(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Request, Frame], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Console_ServiceDesc.Streams[0], Console_Attach_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Request, Frame]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Console_AttachClient = grpc.BidiStreamingClient[Request, Frame]
+
+// ConsoleServer is the server API for Console service.
+// All implementations must embed UnimplementedConsoleServer
+// for forward compatibility.
+type ConsoleServer interface {
+	// Attach makes the caller a broker client. The first request must be a
+	// Hello; later ones carry commands. The broker answers with its Meta,
+	// then the replayed ring and live lines, and notices.
+	Attach(grpc.BidiStreamingServer[Request, Frame]) error
+	mustEmbedUnimplementedConsoleServer()
+}
+
+// UnimplementedConsoleServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedConsoleServer struct{}
+
+func (UnimplementedConsoleServer) Attach(grpc.BidiStreamingServer[Request, Frame]) error {
+	return status.Errorf(codes.Unimplemented, "method Attach not implemented")
+}
+func (UnimplementedConsoleServer) mustEmbedUnimplementedConsoleServer() {}
+func (UnimplementedConsoleServer) testEmbeddedByValue()                 {}
+
+// UnsafeConsoleServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ConsoleServer will
+// result in compilation errors.
+type UnsafeConsoleServer interface {
+	mustEmbedUnimplementedConsoleServer()
+}
+
+func RegisterConsoleServer(s grpc.ServiceRegistrar, srv ConsoleServer) {
+	// If the following call pancis, it indicates UnimplementedConsoleServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Console_ServiceDesc, srv)
+}
+
+func _Console_Attach_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ConsoleServer).Attach(&grpc.GenericServerStream[Request, Frame]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Console_AttachServer = grpc.BidiStreamingServer[Request, Frame]
+
+// Console_ServiceDesc is the grpc.ServiceDesc for Console service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Console_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "planeconsole.v1.Console",
+	HandlerType: (*ConsoleServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Attach",
+			Handler:       _Console_Attach_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "consolepb/console.proto",
+}
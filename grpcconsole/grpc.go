@@ -0,0 +1,289 @@
+// Package grpcconsole serves a console broker over gRPC and attaches
+// clients to one, with the Console service of consolepb/console.proto.
+//
+//	s := grpc.NewServer()
+//	grpcconsole.Register(s, b)
+//	go s.Serve(ln)
+//
+// and on the client side
+//
+//	console.Attach(console.AttachOptions{Dial: grpcconsole.Dialer("host:7071", nil)})
+package grpcconsole
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative consolepb/console.proto
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+
+	console "github.com/network-plane/planeconsole"
+	"github.com/network-plane/planeconsole/grpcconsole/consolepb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Register registers the Console service on s, serving b to gRPC clients,
+// which can be generated for any language. Each Attach call is an ordinary
+// broker client, so MaxClients, the connect callbacks, Authenticate and
+// Stats apply. Clients get the meta, lines and notices; they can send
+// commands.
+func Register(s grpc.ServiceRegistrar, b *console.Broker) {
+	consolepb.RegisterConsoleServer(s, &service{b: b})
+}
+
+type service struct {
+	consolepb.UnimplementedConsoleServer
+	b *console.Broker
+}
+
+// Attach connects a gRPC stream to the broker through an in-memory pipe,
+// translating frames to and from the broker's.
+func (s *service) Attach(stream consolepb.Console_AttachServer) error {
+	if !s.b.Running() {
+		return status.Error(codes.Unavailable, "console broker is not running")
+	}
+	// headers let the client's dial return before it says hello
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	hello := req.GetHello()
+	if hello == nil {
+		return status.Error(codes.InvalidArgument, "console: the first request must be a hello")
+	}
+	remote := "grpc"
+	if p, ok := peer.FromContext(stream.Context()); ok && p.Addr != nil {
+		remote = p.Addr.String()
+	}
+
+	server, conn := net.Pipe()
+	defer conn.Close()
+	s.b.ServeConn(server, console.ConnOptions{Transport: "grpc", RemoteAddr: remote, Authenticate: true})
+
+	// client to broker
+	go func() {
+		defer conn.Close()
+		h := console.Hello{Type: "hello", Version: console.ProtocolVersion, Capabilities: []string{"lines"}, Client: hello.GetClient(), SinceUs: hello.GetSinceUs(), Token: hello.GetToken()}
+		if writeFrame(conn, h) != nil {
+			return
+		}
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			cmd := req.GetCommand()
+			if cmd == nil {
+				continue
+			}
+			if writeFrame(conn, console.Command{Type: "command", Text: cmd.GetText()}) != nil {
+				return
+			}
+		}
+	}()
+
+	// broker to client
+	r := bufio.NewReader(conn)
+	for {
+		b, err := r.ReadBytes('\n')
+		if err != nil {
+			if err := stream.Context().Err(); err != nil {
+				return status.FromContextError(err).Err()
+			}
+			return status.Error(codes.Unavailable, "console broker closed the stream")
+		}
+		for _, f := range framesOf(b) {
+			if err := stream.Send(f); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// frameType returns the type of the NDJSON frame b, or "" if it is not one.
+func frameType(b []byte) string {
+	var head struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(b, &head) != nil {
+		return ""
+	}
+	return head.Type
+}
+
+// framesOf translates a broker frame to the Frames sent to gRPC clients;
+// frame types the service does not carry give none.
+func framesOf(b []byte) []*consolepb.Frame {
+	switch frameType(b) {
+	case "meta":
+		var m console.Meta
+		if json.Unmarshal(b, &m) != nil {
+			return nil
+		}
+		pm := &consolepb.Meta{MaxLines: int32(m.MaxLines), ServerTimeUs: m.ServerTimeUs, StartedUs: m.StartedUs, Version: int32(m.Version)}
+		if len(m.Counters) > 0 {
+			buf, _ := json.Marshal(m.Counters)
+			pm.CountersJson = string(buf)
+		}
+		if len(m.Highlights) > 0 {
+			buf, _ := json.Marshal(m.Highlights)
+			pm.HighlightsJson = string(buf)
+		}
+		return []*consolepb.Frame{{Frame: &consolepb.Frame_Meta{Meta: pm}}}
+	case "line":
+		var ev console.Line
+		if json.Unmarshal(b, &ev) != nil {
+			return nil
+		}
+		return []*consolepb.Frame{lineFrame(ev)}
+	case "lines":
+		var batch console.Lines
+		if json.Unmarshal(b, &batch) != nil {
+			return nil
+		}
+		out := make([]*consolepb.Frame, len(batch.Lines))
+		for i, ev := range batch.Lines {
+			out[i] = lineFrame(ev)
+		}
+		return out
+	case "notice":
+		var n console.Notice
+		if json.Unmarshal(b, &n) != nil {
+			return nil
+		}
+		return []*consolepb.Frame{{Frame: &consolepb.Frame_Notice{Notice: &consolepb.Notice{Text: n.Text}}}}
+	}
+	return nil
+}
+
+func lineFrame(ev console.Line) *consolepb.Frame {
+	return &consolepb.Frame{Frame: &consolepb.Frame_Line{Line: &consolepb.Line{TsUs: ev.TsUs, Text: ev.Text, Level: ev.Level, Seq: ev.Seq, Source: ev.Source, Channel: ev.Channel, Fields: ev.Fields}}}
+}
+
+// writeFrame writes v as one NDJSON frame.
+func writeFrame(w io.Writer, v any) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(buf, '\n'))
+	return err
+}
+
+// Dialer returns an AttachOptions.Dial attaching to the Console service at
+// target, secured with tlsOpts if it is not nil. The connection carries the
+// usual NDJSON frames, so a client reads it as it would a broker socket.
+// Only the meta, lines, notices and commands cross, so the meta lists the
+// "command" capability alone.
+func Dialer(target string, tlsOpts *console.TLSOptions) func(ctx context.Context) (net.Conn, error) {
+	return func(ctx context.Context) (net.Conn, error) {
+		return dial(ctx, target, tlsOpts)
+	}
+}
+
+func dial(ctx context.Context, target string, tlsOpts *console.TLSOptions) (net.Conn, error) {
+	creds := insecure.NewCredentials()
+	if tlsOpts != nil {
+		cfg, err := tlsOpts.ClientConfig(target)
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(cfg)
+	}
+	cc, err := grpc.NewClient(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+	sctx, cancel := context.WithCancel(context.Background())
+	stop := context.AfterFunc(ctx, cancel)
+	stream, err := consolepb.NewConsoleClient(cc).Attach(sctx)
+	if err == nil {
+		_, err = stream.Header()
+	}
+	stop()
+	if err != nil {
+		cancel()
+		_ = cc.Close()
+		return nil, fmt.Errorf("gRPC attach to %s: %w", target, err)
+	}
+
+	client, local := net.Pipe()
+	// client to service
+	go func() {
+		defer cancel()
+		r := bufio.NewReader(local)
+		for {
+			b, err := r.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+			var req consolepb.Request
+			switch frameType(b) {
+			case "hello":
+				var h console.Hello
+				if json.Unmarshal(b, &h) != nil {
+					continue
+				}
+				req.Request = &consolepb.Request_Hello{Hello: &consolepb.Hello{SinceUs: h.SinceUs, Client: h.Client, Token: h.Token}}
+			case "command":
+				var c console.Command
+				if json.Unmarshal(b, &c) != nil {
+					continue
+				}
+				req.Request = &consolepb.Request_Command{Command: &consolepb.Command{Text: c.Text}}
+			default:
+				continue
+			}
+			if stream.Send(&req) != nil {
+				return
+			}
+		}
+	}()
+	// service to client
+	go func() {
+		defer cc.Close()
+		defer cancel()
+		defer local.Close()
+		for {
+			f, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			var v any
+			switch {
+			case f.GetMeta() != nil:
+				pm := f.GetMeta()
+				m := console.Meta{Type: "meta", MaxLines: int(pm.MaxLines), ServerTimeUs: pm.ServerTimeUs, StartedUs: pm.StartedUs, Version: int(pm.Version), Capabilities: []string{"command"}}
+				if pm.CountersJson != "" {
+					_ = json.Unmarshal([]byte(pm.CountersJson), &m.Counters)
+				}
+				if pm.HighlightsJson != "" {
+					_ = json.Unmarshal([]byte(pm.HighlightsJson), &m.Highlights)
+				}
+				v = m
+			case f.GetLine() != nil:
+				l := f.GetLine()
+				v = console.Line{Type: "line", TsUs: l.TsUs, Text: l.Text, Level: l.Level, Seq: l.Seq, Source: l.Source, Channel: l.Channel, Fields: l.Fields}
+			case f.GetNotice() != nil:
+				v = console.Notice{Type: "notice", Text: f.GetNotice().Text}
+			default:
+				continue
+			}
+			if writeFrame(local, v) != nil {
+				return
+			}
+		}
+	}()
+	return client, nil
+}
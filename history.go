@@ -0,0 +1,91 @@
+package console
+
+import (
+	"bufio"
+	"os"
+	"slices"
+	"strings"
+)
+
+// maxInputHistory is how many filters and commands the input field
+// remembers.
+const maxInputHistory = 500
+
+// loadHistory reads the entries saved at path, one per line, oldest first.
+// A missing file is an empty history.
+func loadHistory(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var out []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := sc.Text(); line != "" {
+			out = append(out, line)
+		}
+	}
+	if len(out) > maxInputHistory {
+		out = out[len(out)-maxInputHistory:]
+	}
+	return out
+}
+
+// saveHistory replaces the file at path with entries.
+func saveHistory(path string, entries []string) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(entries, "\n")+"\n"), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// addHistoryDirect records text, a filter or command entered in the input
+// field, as the newest history entry and stops browsing. An earlier copy is
+// dropped, so repeating an entry moves it to the end.
+func (u *UI) addHistoryDirect(text string) {
+	u.historyPos, u.historyDraft = -1, ""
+	if strings.TrimSpace(text) == "" || strings.ContainsAny(text, "\r\n") {
+		return
+	}
+	u.history = slices.DeleteFunc(u.history, func(s string) bool { return s == text })
+	u.history = append(u.history, text)
+	if len(u.history) > maxInputHistory {
+		u.history = slices.Delete(u.history, 0, len(u.history)-maxInputHistory)
+	}
+	if u.historyPath == "" {
+		return
+	}
+	if err := saveHistory(u.historyPath, u.history); err != nil {
+		u.setStatusMessage(u.msg("history.error", err))
+	}
+}
+
+// historyStepDirect puts the previous (delta -1) or next (delta 1) history
+// entry in the input field. Stepping past the newest entry restores the text
+// typed before browsing began.
+func (u *UI) historyStepDirect(delta int) {
+	if len(u.history) == 0 {
+		return
+	}
+	pos := u.historyPos
+	if pos < 0 {
+		if delta > 0 {
+			return
+		}
+		u.historyDraft = u.inputField.GetText()
+		pos = len(u.history)
+	}
+	pos += delta
+	switch {
+	case pos < 0:
+		return
+	case pos >= len(u.history):
+		u.historyPos = -1
+		u.inputField.SetText(u.historyDraft)
+		return
+	}
+	u.historyPos = pos
+	u.inputField.SetText(u.history[pos])
+}
@@ -0,0 +1,319 @@
+package console
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed httpbridge.html
+var bridgePage string
+
+var bridgeTemplate = template.Must(template.New("viewer").Parse(bridgePage))
+
+// HTTPBridgeOptions configure a bridge created by NewHTTPBridge.
+type HTTPBridgeOptions struct {
+	// Title is shown by the browser viewer; default "Console".
+	Title string
+	// Upgrade, if set, upgrades a request for the /ws path to a WebSocket,
+	// answering the request itself when it cannot; wsconsole.Upgrade
+	// returns one. Without it that path answers 501 and the viewer page
+	// cannot connect.
+	Upgrade func(w http.ResponseWriter, r *http.Request) (MessageConn, error)
+	// AllowInput forwards publish and command frames from browsers. By
+	// default browsers can only watch and ask for history and stats.
+	AllowInput bool
+}
+
+// MessageConn is a connection carrying one frame per message, such as a
+// WebSocket.
+type MessageConn interface {
+	// Read returns the next message.
+	Read(ctx context.Context) ([]byte, error)
+	// Write sends b as one message.
+	Write(ctx context.Context, b []byte) error
+	// Close closes the connection; reason, if not empty, tells the peer
+	// why.
+	Close(reason string) error
+}
+
+// HTTPBridge is an http.Handler exposing a broker to browsers. A request for
+// a path ending in "/ws" is upgraded to a WebSocket, by
+// HTTPBridgeOptions.Upgrade, carrying the usual NDJSON frames, one per
+// message; one ending in "/stream" gets them as
+// Server-Sent Events; one ending in "/lines" searches the ring and answers
+// with JSON; other paths ending in "/" serve a minimal viewer for it. Each
+// WebSocket or event stream is an ordinary broker client, so MaxClients,
+// the connect callbacks and Stats apply. When the broker has
+// BrokerOptions.Authenticate, streams and queries need a token, sent as
+// "Authorization: Bearer <token>" or in the token query parameter; the
+// viewer passes on the token parameter of its own URL.
+//
+//	http.Handle("/console/", http.StripPrefix("/console", console.NewHTTPBridge(b, nil)))
+type HTTPBridge struct {
+	b    *Broker
+	opts HTTPBridgeOptions
+}
+
+// NewHTTPBridge returns a bridge to b. opts may be nil.
+func NewHTTPBridge(b *Broker, opts *HTTPBridgeOptions) *HTTPBridge {
+	h := &HTTPBridge{b: b}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.Title == "" {
+		h.opts.Title = "Console"
+	}
+	return h
+}
+
+// ServeHTTP serves the viewer page, a stream or a query.
+func (h *HTTPBridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/ws") || r.URL.Path == "ws":
+		h.serveWS(w, r)
+	case strings.HasSuffix(r.URL.Path, "/stream") || r.URL.Path == "stream":
+		h.serveSSE(w, r)
+	case strings.HasSuffix(r.URL.Path, "/lines") || r.URL.Path == "lines":
+		h.serveLines(w, r)
+	case strings.HasSuffix(r.URL.Path, "/"):
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = bridgeTemplate.Execute(w, struct{ Title string }{h.opts.Title})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// authorize asks BrokerOptions.Authenticate, if set, about the request's
+// bearer token or token query parameter, answering 401 and reporting false
+// if it is refused.
+func (h *HTTPBridge) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if h.b.authenticate == nil {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		token = r.URL.Query().Get("token")
+	}
+	info := ClientInfo{RemoteAddr: r.RemoteAddr, ConnectedAt: time.Now(), Transport: "http", UID: -1, GID: -1}
+	if token == "" || !h.b.authenticate(info, token) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "auth failed", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// serveWS connects a WebSocket to the broker through an in-memory pipe.
+func (h *HTTPBridge) serveWS(w http.ResponseWriter, r *http.Request) {
+	if h.opts.Upgrade == nil {
+		http.Error(w, "WebSocket not supported", http.StatusNotImplemented)
+		return
+	}
+	if !h.authorize(w, r) {
+		return
+	}
+	if !h.b.Running() {
+		http.Error(w, "console broker is not running", http.StatusServiceUnavailable)
+		return
+	}
+	ws, err := h.opts.Upgrade(w, r)
+	if err != nil {
+		return // Upgrade has answered the request
+	}
+	defer ws.Close("")
+	ctx := r.Context()
+
+	server, conn := net.Pipe()
+	defer conn.Close()
+	h.b.ServeConn(server, ConnOptions{Transport: "http", RemoteAddr: r.RemoteAddr})
+	// the viewer page understands what this package's clients do
+	if err := writeHello(conn, 0, ""); err != nil {
+		return
+	}
+
+	// broker to browser
+	go func() {
+		defer ws.Close("")
+		fr := newConnFrameReader(conn)
+		for {
+			b, err := fr.next()
+			if err != nil {
+				_ = ws.Close("console broker closed the stream")
+				return
+			}
+			if err := ws.Write(ctx, b); err != nil {
+				_ = conn.Close()
+				return
+			}
+		}
+	}()
+
+	// browser to broker
+	fr := &frameReader{}
+	for {
+		b, err := ws.Read(ctx)
+		if err != nil {
+			return
+		}
+		typ, b := fr.peekFrameType(b)
+		_ = fr.takeMalformed()
+		switch typ {
+		case "history_request", "stats_request", "filter":
+		case "publish", "command":
+			if !h.opts.AllowInput {
+				continue
+			}
+		default:
+			continue
+		}
+		if _, err := conn.Write(append(bytes.TrimSpace(b), '\n')); err != nil {
+			return
+		}
+	}
+}
+
+// sseKeepAlive is how often an idle event stream gets a comment, so proxies
+// do not time it out.
+const sseKeepAlive = 15 * time.Second
+
+// serveSSE streams the broker as Server-Sent Events: the replayed ring, then
+// live lines, each a "line" event whose data is the Line frame and whose ID
+// is its timestamp, so a reconnecting EventSource resumes after the last
+// line it got. Meta, notice, counters and viewers frames are events of
+// their type. The query parameters since_us and filter, a filter as typed
+// in the UI, shape the stream, e.g.
+//
+//	curl -N 'http://localhost:8080/stream?filter=OFFER'
+func (h *HTTPBridge) serveSSE(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.authorize(w, r) {
+		return
+	}
+	rc := http.NewResponseController(w)
+	if !h.b.Running() {
+		http.Error(w, "console broker is not running", http.StatusServiceUnavailable)
+		return
+	}
+	sinceUs, _ := strconv.ParseInt(cmp.Or(r.URL.Query().Get("since_us"), r.Header.Get("Last-Event-ID")), 10, 64)
+	ctx := r.Context()
+
+	server, conn := net.Pipe()
+	defer conn.Close()
+	h.b.ServeConn(server, ConnOptions{Transport: "http", RemoteAddr: r.RemoteAddr})
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+	go func() { _ = writeHello(conn, sinceUs, "") }()
+	var filter *filterExpr
+	if e, ok := parseFilter(r.URL.Query().Get("filter"), false, false); ok {
+		filter = &e
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if rc.Flush() != nil {
+		return
+	}
+
+	type frame struct {
+		b   []byte
+		err error
+	}
+	frames := make(chan frame)
+	go func() {
+		fr := newConnFrameReader(conn)
+		for {
+			b, err := fr.next()
+			select {
+			case frames <- frame{b, err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	tick := time.NewTicker(sseKeepAlive)
+	defer tick.Stop()
+	fr := &frameReader{}
+	for {
+		var err error
+		select {
+		case f := <-frames:
+			if f.err != nil {
+				return
+			}
+			typ, b := fr.peekFrameType(f.b)
+			_ = fr.takeMalformed()
+			err = writeSSE(w, typ, b, filter)
+		case <-tick.C:
+			_, err = io.WriteString(w, ": keepalive\n\n")
+		case <-ctx.Done():
+			return
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// writeSSE writes a broker frame of type typ as events, splitting batches
+// into one event per line and leaving out lines not passing filter.
+func writeSSE(w io.Writer, typ string, b []byte, filter *filterExpr) error {
+	switch typ {
+	case "line":
+		var ev Line
+		if json.Unmarshal(b, &ev) != nil || !filter.pass(filterText(ev), time.UnixMicro(ev.TsUs)) {
+			return nil
+		}
+		return writeSSELine(w, ev)
+	case "lines":
+		var batch Lines
+		if json.Unmarshal(b, &batch) != nil {
+			return nil
+		}
+		for _, ev := range batch.Lines {
+			if !filter.pass(filterText(ev), time.UnixMicro(ev.TsUs)) {
+				continue
+			}
+			if err := writeSSELine(w, ev); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "meta", "notice", "counters", "viewers":
+		_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typ, bytes.TrimSpace(b))
+		return err
+	}
+	return nil
+}
+
+func writeSSELine(w io.Writer, ev Line) error {
+	ev.Type = "line"
+	buf, _ := json.Marshal(ev)
+	_, err := fmt.Fprintf(w, "event: line\nid: %d\ndata: %s\n\n", ev.TsUs, buf)
+	return err
+}
@@ -0,0 +1,155 @@
+package console
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// inspectedLine is a copy of the line shown by the inspector.
+type inspectedLine struct {
+	text    string
+	when    time.Time
+	tsUs    int64
+	seq     uint64
+	level   string
+	source  string
+	channel string
+	fields  map[string]string
+	cont    bool // a continuation line, stamped with its parent's time
+}
+
+// inspectTarget returns the line at the selection cursor, or the current
+// line if nothing is selected.
+func (u *UI) inspectTarget() (inspectedLine, bool) {
+	u.mu.Lock()
+	key, selecting := u.selCursor, u.selecting
+	u.mu.Unlock()
+	if !selecting {
+		row, ok := u.currentRow()
+		if !ok {
+			return inspectedLine{}, false
+		}
+		key = row.key
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for i := range u.lines {
+		l := &u.lines[i]
+		if l.ord != key.ord {
+			continue
+		}
+		out := inspectedLine{text: l.text, when: l.when, tsUs: l.tsUs, seq: l.seq, level: l.level, source: l.source, channel: l.channel, fields: maps.Clone(l.fields)}
+		if key.sub >= 0 && key.sub < len(l.cont) {
+			c := l.cont[key.sub]
+			out.text, out.seq, out.fields, out.cont = c.text, c.seq, nil, true
+		}
+		return out, true
+	}
+	return inspectedLine{}, false
+}
+
+// showInspectModal shows everything known about the selected or current
+// line: its raw text, a JSON payload pretty-printed, its time, level and ID,
+// and the highlight and counter rules it matches.
+func (u *UI) showInspectModal() {
+	if u.rowsWrapped() {
+		u.setStatusMessage(u.msg("wrap.rows", "inspect"))
+		return
+	}
+	l, ok := u.inspectTarget()
+	if !ok {
+		u.setStatusMessage(u.msg("inspect.none"))
+		return
+	}
+
+	esc := tview.Escape
+	if u.noColour {
+		esc = func(s string) string { return s } // the modal shows tags as text
+	}
+	var b strings.Builder
+	row := func(label, value string) {
+		fmt.Fprintf(&b, "%-10s %s\n", label, esc(value))
+	}
+	row(u.msg("inspect.time"), l.when.Local().Format("2006-01-02 15:04:05.000000 MST"))
+	if l.tsUs != 0 {
+		row(u.msg("inspect.server"), time.UnixMicro(l.tsUs).UTC().Format("2006-01-02T15:04:05.000000Z")+fmt.Sprintf(" (%d µs)", l.tsUs))
+	}
+	if l.cont {
+		row("", u.msg("inspect.cont"))
+	}
+	row(u.msg("inspect.level"), l.level)
+	if l.seq > 0 {
+		row(u.msg("inspect.id"), fmt.Sprintf("#%d", l.seq))
+	}
+	if l.source != "" {
+		row(u.msg("inspect.source"), l.source)
+	}
+	if l.channel != "" {
+		row(u.msg("inspect.channel"), l.channel)
+	}
+	for i, k := range slices.Sorted(maps.Keys(l.fields)) {
+		label := ""
+		if i == 0 {
+			label = u.msg("inspect.fields")
+		}
+		row(label, k+"="+l.fields[k])
+	}
+
+	var highlights, counters []string
+	u.hlMu.Lock()
+	for _, h := range u.highlights {
+		if h.matcher != nil && h.matcher.match(l.text) {
+			highlights = append(highlights, h.match)
+		}
+	}
+	u.hlMu.Unlock()
+	u.counterMu.Lock()
+	for _, c := range u.counters {
+		if c.matches(l.text) {
+			counters = append(counters, cmp.Or(c.label, c.match))
+		}
+	}
+	u.counterMu.Unlock()
+	none := u.msg("inspect.nomatch")
+	row(u.msg("inspect.highlights"), cmp.Or(strings.Join(highlights, ", "), none))
+	row(u.msg("inspect.counters"), cmp.Or(strings.Join(counters, ", "), none))
+
+	fmt.Fprintf(&b, "\n%s\n%s\n", u.msg("inspect.raw"), esc(l.text))
+	if pretty, ok := jsonPayload(l.text); ok {
+		fmt.Fprintf(&b, "\n%s\n%s\n", u.msg("inspect.json"), esc(pretty))
+	}
+
+	title := u.msg("modal.inspect", "")
+	if l.seq > 0 {
+		title = u.msg("modal.inspect", fmt.Sprintf("#%d", l.seq))
+	}
+	tv := u.showTextModal(strings.TrimSpace(title), b.String())
+	tv.SetWrap(true)
+}
+
+// jsonPayload returns the JSON object or array ending text, indented. It
+// starts at the first '{' or '[' from which the rest of text parses, so
+// prefixes such as "[source] " or "[INFO]" are passed over. Only the first
+// few openings are tried, so a line full of brackets stays cheap.
+func jsonPayload(text string) (string, bool) {
+	tries := 0
+	for i := 0; i < len(text) && tries < 8; i++ {
+		if text[i] != '{' && text[i] != '[' {
+			continue
+		}
+		tries++
+		var out bytes.Buffer
+		if json.Indent(&out, []byte(strings.TrimSpace(text[i:])), "", "  ") == nil {
+			return out.String(), true
+		}
+	}
+	return "", false
+}
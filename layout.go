@@ -0,0 +1,96 @@
+package console
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Terminal sizes at which the layout degrades instead of drawing wrapped or
+// overlapping bars.
+const (
+	minUsableWidth  = 20 // below this width or minUsableHeight only a hint is drawn
+	minUsableHeight = 4
+	compactHeight   = 10 // below this the top bar and separator collapse
+	tinyHeight      = 6  // below this the status bar collapses as well
+)
+
+// sizeClass is how much chrome the current terminal size leaves room for.
+type sizeClass int
+
+const (
+	sizeFull sizeClass = iota
+	sizeCompact
+	sizeTiny
+	sizeTooSmall
+)
+
+func classifySize(w, h int) sizeClass {
+	switch {
+	case w < minUsableWidth || h < minUsableHeight:
+		return sizeTooSmall
+	case h < tinyHeight:
+		return sizeTiny
+	case h < compactHeight:
+		return sizeCompact
+	default:
+		return sizeFull
+	}
+}
+
+// layoutForSize is the root flex's draw func. It collapses bars on small
+// terminals and replaces the layout with a hint when nothing usable fits.
+// It runs on the event goroutine before the root lays out its items.
+func (u *UI) layoutForSize(screen tcell.Screen, x, y, w, h int) (int, int, int, int) {
+	class := classifySize(w, h)
+	u.mu.Lock()
+	resized := u.width != w || u.sizeClass != class
+	changed := u.sizeClass != class
+	u.width = w
+	u.sizeClass = class
+	u.screen = screen
+	u.mu.Unlock()
+
+	if changed {
+		top := tview.Primitive(u.topSep)
+		if u.topBarEnabled {
+			top = u.topBar
+		}
+		bars := 1
+		if class != sizeFull {
+			bars = 0
+		}
+		u.root.ResizeItem(top, bars, 0)
+		u.root.ResizeItem(u.bottomSep, bars, 0)
+		status := 1
+		if class == sizeTiny || class == sizeTooSmall {
+			status = 0
+		}
+		u.footer.ResizeItem(u.statusText, status, 0)
+		u.root.ResizeItem(u.footer, 1+status, 0)
+	}
+	if resized {
+		// bar contents are padded to the width
+		if u.topBarEnabled {
+			u.updateTopBarDirect()
+		}
+		u.updateBottomBarDirect()
+	}
+
+	if class == sizeTooSmall {
+		hint := u.msg("layout.too_small")
+		if w < visualLen(hint) {
+			hint = u.msg("layout.tiny")
+		}
+		tview.Print(screen, hint, x, y+h/2, w, tview.AlignCenter, tcell.ColorYellow)
+		return x, y, 0, 0
+	}
+	return x, y, w, h
+}
+
+// barWidth returns the width available to the top and bottom bars, or 0 if
+// the UI has not been drawn yet.
+func (u *UI) barWidth() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.width
+}
@@ -0,0 +1,228 @@
+package console
+
+import (
+	"hash/fnv"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// levels are the level classes the UI filters and colours by, in the order
+// of their toggle keys 1 to 4.
+var levels = []string{"error", "warn", "info", "debug"}
+
+// LevelClass maps a level name, such as a Line.Level, to its class:
+// "error", "warn", "info" or "debug".
+func LevelClass(level string) string {
+	switch strings.ToLower(level) {
+	case "error", "fatal", "panic", "crit", "critical", "alert", "emerg":
+		return "error"
+	case "warn", "warning":
+		return "warn"
+	case "debug", "trace":
+		return "debug"
+	}
+	return "info"
+}
+
+// lineLevel returns the level class of ev, detecting it from the text when
+// the broker did not send one.
+func lineLevel(ev Line) string {
+	if ev.Level == "" {
+		return LevelClass(LevelOf(ev.Text))
+	}
+	return LevelClass(ev.Level)
+}
+
+// levelOf classifies text with the broker's LevelFunc, falling back to
+// LevelOf.
+func (b *Broker) levelOf(text string) string {
+	if b.levelFunc != nil {
+		if l := b.levelFunc(text); l != "" {
+			return l
+		}
+	}
+	return LevelOf(text)
+}
+
+// isLevelName reports whether LevelClass recognises l, rather than
+// defaulting it to info.
+func isLevelName(l string) bool {
+	return LevelClass(l) != "info" || strings.EqualFold(l, "info")
+}
+
+// toggleLevelDirect shows or hides lines of level.
+func (u *UI) toggleLevelDirect(level string) {
+	u.mu.Lock()
+	u.preset = ""
+	if u.hiddenLevels[level] {
+		delete(u.hiddenLevels, level)
+	} else {
+		u.hiddenLevels[level] = true
+	}
+	u.mu.Unlock()
+	u.repaintLogDirect()
+	u.updateBottomBarDirect()
+}
+
+// styleRow styles a row's text with the highlight rules on top of its
+// level's palette colour, tinting the "[source]" prefix of lines from a
+// Source. Info rows keep the terminal's colours.
+func (u *UI) styleRow(level, source, text string) string {
+	styled := u.colourSource(source, u.styleLine(text))
+	if u.noColour || !u.levelColours || level == "" || level == "info" {
+		return styled
+	}
+	st := u.currentPalette().LevelStyle(level)
+	open := "[" + st.FG + ":" + st.BG + ":" + st.Attrs + "]"
+	// highlights end by resetting; return to the level colour instead
+	return open + strings.ReplaceAll(styled, "[-:-:-]", open) + "[-:-:-]"
+}
+
+// sourceColours are the tints given to "[source]" prefixes, so lines from
+// different upstreams of a multi-broker attach tell apart at a glance.
+var sourceColours = []string{"#5fafff", "#87d75f", "#d787ff", "#ffaf5f", "#5fd7d7", "#ff87af", "#afaf5f", "#8787ff"}
+
+// sourceColour picks a stable colour for source.
+func sourceColour(source string) string {
+	h := fnv.New32a()
+	h.Write([]byte(source))
+	return sourceColours[h.Sum32()%uint32(len(sourceColours))]
+}
+
+// colourSource tints the "[source]" prefix in text, which follows any
+// columns, unless a highlight already split it.
+func (u *UI) colourSource(source, text string) string {
+	if u.noColour || source == "" {
+		return text
+	}
+	prefix := tview.Escape("[" + source + "]")
+	i := strings.Index(text, prefix)
+	if i < 0 {
+		return text
+	}
+	return text[:i] + tagStyle(prefix, Style{FG: sourceColour(source)}, false) + text[i+len(prefix):]
+}
+
+// levelsBadge renders the level toggles, E W I D, each active while its
+// lines are shown.
+func (u *UI) levelsBadge(hidden map[string]bool, pal Palette, short bool) string {
+	label := u.msg("badge.levels")
+	if short {
+		label = u.msg("badge.levels.s")
+	}
+	parts := []string{label}
+	for _, l := range levels {
+		letter, st := strings.ToUpper(l[:1]), pal.Active
+		if hidden[l] {
+			st = pal.Inactive
+			if u.noColour {
+				letter = "-"
+			}
+		}
+		parts = append(parts, tagStyle(letter, st, u.noColour))
+	}
+	return strings.Join(parts, " ")
+}
+
+// LevelFunc classifies a line as "error", "warn", "info" or "debug". It
+// returns "" when it does not recognise the format; see
+// BrokerOptions.LevelFunc.
+type LevelFunc func(line string) string
+
+// logrusLevels maps the four-letter levels of logrus's text formatter.
+var logrusLevels = map[string]string{
+	"PANI": "error", "FATA": "error", "ERRO": "error",
+	"WARN": "warn", "INFO": "info", "DEBU": "debug", "TRAC": "debug",
+}
+
+// LogrusLevel recognises logrus text output, both "ERRO[0001] msg" and the
+// logfmt form with level=error, and JSON output with "level":"error".
+func LogrusLevel(line string) string {
+	if len(line) > 5 && line[4] == '[' {
+		if l, ok := logrusLevels[line[:4]]; ok {
+			return l
+		}
+	}
+	for _, key := range []string{"level=", `"level":"`} {
+		i := strings.Index(line, key)
+		if i < 0 {
+			continue
+		}
+		v := line[i+len(key):]
+		v = strings.TrimPrefix(v, `"`)
+		if j := strings.IndexAny(v, "\" \t"); j >= 0 {
+			v = v[:j]
+		}
+		if l := knownLevel(v); l != "" {
+			return l
+		}
+	}
+	return ""
+}
+
+// ZapLevel recognises zap's console encoder, which separates the timestamp,
+// level, caller and message with tabs: "2024-05-01T10:00:00Z\tERROR\tmsg".
+func ZapLevel(line string) string {
+	fields := strings.SplitN(line, "\t", 3)
+	for _, f := range fields[:min(len(fields), 2)] {
+		if l := knownLevel(f); l != "" {
+			return l
+		}
+	}
+	return ""
+}
+
+// SyslogLevel recognises a syslog priority prefix such as "<3>" or
+// "<134>", classifying by its severity.
+func SyslogLevel(line string) string {
+	if len(line) < 3 || line[0] != '<' {
+		return ""
+	}
+	end := strings.IndexByte(line, '>')
+	if end < 2 || end > 4 {
+		return ""
+	}
+	pri := 0
+	for _, c := range line[1:end] {
+		if c < '0' || c > '9' {
+			return ""
+		}
+		pri = pri*10 + int(c-'0')
+	}
+	switch sev := pri % 8; {
+	case sev <= 3: // emerg, alert, crit, err
+		return "error"
+	case sev == 4:
+		return "warn"
+	case sev == 7:
+		return "debug"
+	}
+	return "info"
+}
+
+// DetectLevel tries the syslog, logrus and zap formats in turn and falls
+// back to LevelOf.
+func DetectLevel(line string) string {
+	for _, f := range []LevelFunc{SyslogLevel, LogrusLevel, ZapLevel} {
+		if l := f(line); l != "" {
+			return l
+		}
+	}
+	return LevelOf(line)
+}
+
+// knownLevel returns the level class of a level name, or "" if s is not one.
+func knownLevel(s string) string {
+	switch strings.ToLower(s) {
+	case "error", "err", "fatal", "panic", "dpanic", "crit", "critical", "alert", "emerg":
+		return "error"
+	case "warn", "warning":
+		return "warn"
+	case "info", "notice":
+		return "info"
+	case "debug", "trace":
+		return "debug"
+	}
+	return ""
+}
@@ -0,0 +1,169 @@
+package console
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// tagPattern matches tview colour/attribute tags such as [red], [::b],
+// [#ff0000:black:bu] and [-:-:-].
+var tagPattern = regexp.MustCompile(`\[([a-zA-Z]+|#[0-9a-fA-F]{6}|-)?(:([a-zA-Z]+|#[0-9a-fA-F]{6}|-)?(:([bdilrsuBDILRSU]+|-)?)?)?\]`)
+
+// findTags returns the index pairs of tags in s. The empty "[]" closing a
+// tview escape sequence is text, not a tag.
+func findTags(s string) [][]int {
+	all := tagPattern.FindAllStringIndex(s, -1)
+	out := all[:0]
+	for _, t := range all {
+		if t[1]-t[0] > 2 {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// markupStyle is the style in effect at a point of tview-tagged text.
+// Empty colours mean the default; attrs holds lowercase attribute letters.
+type markupStyle struct {
+	fg, bg string
+	attrs  string
+}
+
+// apply updates st with one tag such as "[red::b]" or "[-:-:-]".
+func (st *markupStyle) apply(tag string) {
+	parts := strings.SplitN(tag[1:len(tag)-1], ":", 3)
+	colour := func(cur *string, v string) {
+		switch v {
+		case "":
+		case "-":
+			*cur = ""
+		default:
+			*cur = v
+		}
+	}
+	colour(&st.fg, parts[0])
+	if len(parts) > 1 {
+		colour(&st.bg, parts[1])
+	}
+	if len(parts) > 2 {
+		switch a := parts[2]; a {
+		case "":
+		case "-":
+			st.attrs = ""
+		default:
+			for _, r := range a {
+				lower := strings.ToLower(string(r))
+				if r >= 'a' && r <= 'z' {
+					if !strings.Contains(st.attrs, lower) {
+						st.attrs += lower
+					}
+				} else {
+					st.attrs = strings.ReplaceAll(st.attrs, lower, "")
+				}
+			}
+		}
+	}
+}
+
+// walkMarkup calls fn for every run of visible text in s with the style in
+// effect for it. Escaped tags ("[red[]") are unescaped.
+func walkMarkup(s string, fn func(text string, st markupStyle)) {
+	var st markupStyle
+	prev := 0
+	for _, t := range append(findTags(s), []int{len(s), len(s)}) {
+		if t[0] > prev {
+			fn(tview.Unescape(s[prev:t[0]]), st)
+		}
+		if t[1] > t[0] {
+			st.apply(s[t[0]:t[1]])
+		}
+		prev = t[1]
+	}
+}
+
+// markVisible wraps every occurrence of match in the visible text of s (the
+// text with tview tags removed) with open/close.
+func markVisible(s, match string, caseSensitive bool, open, close string) string {
+	if match == "" {
+		return s
+	}
+	m, _ := newTextMatcher(match, caseSensitive, false)
+	return markVisibleWith(s, m, open, close)
+}
+
+// markVisibleWith wraps every match of m in the visible text of s with
+// open/close. Matches may span tags that were inserted by earlier styling;
+// open is re-emitted after each such tag so a reset inside the match does
+// not cut the mark short.
+func markVisibleWith(s string, m *textMatcher, open, close string) string {
+	if s == "" {
+		return s
+	}
+	vis, pos := visibleText(s)
+	return wrapVisible(s, pos, m.findAll(vis), open, close)
+}
+
+// visibleText returns s without its tags and, for each byte of that, its
+// offset in s.
+func visibleText(s string) (string, []int) {
+	var vis strings.Builder
+	pos := make([]int, 0, len(s))
+	prev := 0
+	for _, t := range append(findTags(s), []int{len(s), len(s)}) {
+		vis.WriteString(s[prev:t[0]])
+		for i := prev; i < t[0]; i++ {
+			pos = append(pos, i)
+		}
+		prev = t[1]
+	}
+	return vis.String(), pos
+}
+
+// markVisibleRanges wraps the byte ranges locs, ascending and measured in
+// the visible text of s, with open/close, as markVisibleWith does its
+// matches.
+func markVisibleRanges(s string, locs [][]int, open, close string) string {
+	if len(locs) == 0 {
+		return s
+	}
+	_, pos := visibleText(s)
+	return wrapVisible(s, pos, locs, open, close)
+}
+
+// wrapVisible wraps locs, ranges of the visible text of s whose bytes are
+// at offsets pos in s, with open/close.
+func wrapVisible(s string, pos []int, locs [][]int, open, close string) string {
+	type span struct{ start, end int }
+	spans := make([]span, 0, len(locs))
+	for _, loc := range locs {
+		if loc[0] >= loc[1] || loc[1] > len(pos) {
+			continue
+		}
+		spans = append(spans, span{pos[loc[0]], pos[loc[1]-1] + 1})
+	}
+	if len(spans) == 0 {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s) + len(spans)*(len(open)+len(close)))
+	last := 0
+	for _, sp := range spans {
+		b.WriteString(s[last:sp.start])
+		b.WriteString(open)
+		inner := s[sp.start:sp.end]
+		cur := 0
+		for _, t := range findTags(inner) {
+			b.WriteString(inner[cur:t[1]])
+			b.WriteString(open)
+			cur = t[1]
+		}
+		b.WriteString(inner[cur:])
+		b.WriteString(close)
+		last = sp.end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
@@ -0,0 +1,95 @@
+package console
+
+import (
+	"regexp"
+	"strings"
+)
+
+// textMatcher finds a pattern in text, either as a substring or as a regular
+// expression.
+type textMatcher struct {
+	sub           string
+	caseSensitive bool
+	re            *regexp.Regexp
+}
+
+// newTextMatcher returns a matcher for pattern. With regex set, pattern is a
+// Go regular expression, made case-insensitive unless caseSensitive.
+func newTextMatcher(pattern string, caseSensitive, regex bool) (*textMatcher, error) {
+	if !regex {
+		if !caseSensitive {
+			pattern = strings.ToLower(pattern)
+		}
+		return &textMatcher{sub: pattern, caseSensitive: caseSensitive}, nil
+	}
+	if !caseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &textMatcher{re: re, caseSensitive: caseSensitive}, nil
+}
+
+// match reports whether s contains the pattern.
+func (m *textMatcher) match(s string) bool {
+	if m.re != nil {
+		return m.re.MatchString(s)
+	}
+	if m.caseSensitive {
+		return strings.Contains(s, m.sub)
+	}
+	return strings.Contains(strings.ToLower(s), m.sub)
+}
+
+// findAll returns the byte ranges of non-overlapping, non-empty matches in s.
+// A case-insensitive substring search returns nil if case folding changes
+// byte offsets in s.
+func (m *textMatcher) findAll(s string) [][]int {
+	if m.re != nil {
+		var out [][]int
+		for _, loc := range m.re.FindAllStringIndex(s, -1) {
+			if loc[1] > loc[0] {
+				out = append(out, loc)
+			}
+		}
+		return out
+	}
+	if m.sub == "" {
+		return nil
+	}
+	hay := s
+	if !m.caseSensitive {
+		hay = strings.ToLower(s)
+		if len(hay) != len(s) {
+			return nil
+		}
+	}
+	var out [][]int
+	for i := 0; ; {
+		j := strings.Index(hay[i:], m.sub)
+		if j < 0 {
+			return out
+		}
+		out = append(out, []int{i + j, i + j + len(m.sub)})
+		i += j + len(m.sub)
+	}
+}
+
+// replaceAll replaces every match in s with rep applied to the matched text.
+func (m *textMatcher) replaceAll(s string, rep func(string) string) string {
+	locs := m.findAll(s)
+	if len(locs) == 0 {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range locs {
+		b.WriteString(s[last:loc[0]])
+		b.WriteString(rep(s[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
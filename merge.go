@@ -0,0 +1,138 @@
+package console
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+)
+
+// Source appends lines to a broker tagged with a producer name, so sidecar
+// processes and subsystems can share one console. Combine with
+// BrokerOptions.MergeWindow to order their lines by timestamp.
+type Source struct {
+	b    *Broker
+	name string
+}
+
+// Source returns a producer handle whose lines carry name.
+func (b *Broker) Source(name string) *Source {
+	return &Source{b: b, name: name}
+}
+
+// Name returns the source name.
+func (s *Source) Name() string { return s.name }
+
+// Append adds a line timestamped now.
+func (s *Source) Append(line string) {
+	s.AppendAt(time.Now(), line)
+}
+
+// Appendf adds a formatted line timestamped now.
+func (s *Source) Appendf(format string, args ...any) {
+	s.Append(fmt.Sprintf(format, args...))
+}
+
+// AppendAt adds a line with the producer's own timestamp.
+func (s *Source) AppendAt(when time.Time, line string) {
+	s.b.submit(Line{Type: "line", TsUs: when.UnixMicro(), Text: line, Source: s.name})
+}
+
+// pendingLine is a line held in the merge window.
+type pendingLine struct {
+	ev      Line
+	arrived time.Time
+	order   uint64 // arrival order, breaking timestamp ties
+}
+
+type mergeHeap []pendingLine
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].ev.TsUs != h[j].ev.TsUs {
+		return h[i].ev.TsUs < h[j].ev.TsUs
+	}
+	return h[i].order < h[j].order
+}
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x any)   { *h = append(*h, x.(pendingLine)) }
+func (h *mergeHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// submit commits evs directly, or holds them in the merge window.
+func (b *Broker) submit(evs ...Line) {
+	if b.mergeWindow <= 0 {
+		b.commit(evs...)
+		return
+	}
+	now := time.Now()
+	b.mergeMu.Lock()
+	for _, ev := range evs {
+		b.mergeOrder++
+		heap.Push(&b.pending, pendingLine{ev: ev, arrived: now, order: b.mergeOrder})
+	}
+	start := !b.flushing
+	b.flushing = true
+	b.mergeMu.Unlock()
+	if start {
+		go b.flushLoop()
+	}
+}
+
+// flushLoop releases held lines in timestamp order once they are older than
+// the merge window. A line with a timestamp in the future is released when it
+// has been held for the window. The loop exits when nothing is pending.
+func (b *Broker) flushLoop() {
+	tick := b.mergeWindow / 4
+	if tick < 5*time.Millisecond {
+		tick = 5 * time.Millisecond
+	}
+	for {
+		time.Sleep(tick)
+		now := time.Now()
+		cutoff := now.Add(-b.mergeWindow)
+		var ready []Line
+		b.releaseMu.Lock()
+		b.mergeMu.Lock()
+		for b.pending.Len() > 0 {
+			p := b.pending[0]
+			if p.ev.TsUs > cutoff.UnixMicro() && p.arrived.After(cutoff) {
+				break
+			}
+			heap.Pop(&b.pending)
+			ready = append(ready, p.ev)
+		}
+		idle := b.pending.Len() == 0
+		if idle {
+			b.flushing = false
+		}
+		b.mergeMu.Unlock()
+
+		if len(ready) > 0 {
+			b.commit(ready...)
+		}
+		b.releaseMu.Unlock()
+		if idle {
+			return
+		}
+	}
+}
+
+// drainPending commits every line held in the merge window at once, in
+// timestamp order, so Stop stores and sends them before flushing.
+func (b *Broker) drainPending() {
+	b.releaseMu.Lock()
+	defer b.releaseMu.Unlock()
+	b.mergeMu.Lock()
+	ready := make([]Line, 0, b.pending.Len())
+	for b.pending.Len() > 0 {
+		ready = append(ready, heap.Pop(&b.pending).(pendingLine).ev)
+	}
+	b.mergeMu.Unlock()
+	if len(ready) > 0 {
+		b.commit(ready...)
+	}
+}
@@ -0,0 +1,269 @@
+package console
+
+import (
+	"fmt"
+	"maps"
+)
+
+// DefaultMessages is the catalog of user-facing UI strings, keyed by message
+// ID. Values are fmt formats where the message takes arguments. Override
+// entries with UIOptions.Messages to translate or re-word the console;
+// missing IDs fall back to these defaults.
+var DefaultMessages = map[string]string{
+	// status bar keys and badges
+	"key.help":             "help",
+	"key.switch":           "switch",
+	"key.quit":             "quit",
+	"badge.filter":         "Filter",
+	"badge.filter.s":       "Flt",
+	"badge.exclude":        "Exclude",
+	"badge.exclude.s":      "Excl",
+	"badge.case":           "Case Sensitive",
+	"badge.case.s":         "Case",
+	"badge.smart":          "Smart Case",
+	"badge.smart.s":        "Smart",
+	"badge.mouse":          "Mouse",
+	"badge.mouse.s":        "Mse",
+	"badge.running":        "Running",
+	"badge.running.s":      "Run",
+	"badge.regex":          "Regex",
+	"badge.regex.s":        "Re",
+	"badge.newest":         "Newest First",
+	"badge.newest.s":       "New",
+	"badge.folded":         "Folded",
+	"badge.folded.s":       "Fold",
+	"badge.diff":           "Diff",
+	"badge.diff.s":         "Diff",
+	"badge.burst":          "ERROR BURST - paused",
+	"badge.burst.s":        "ERR",
+	"badge.where":          "WHERE",
+	"badge.where.s":        "W",
+	"badge.server":         "Server Filter",
+	"badge.server.s":       "Srv",
+	"badge.levels":         "Levels",
+	"badge.levels.s":       "Lv",
+	"badge.pending":        "+%d new lines",
+	"badge.scrolled":       "SCROLLED",
+	"badge.scrolled.s":     "SCR",
+	"badge.pending.s":      "+%d",
+	"badge.channel":        "ch:%s",
+	"badge.preset":         "preset:%s",
+	"badge.wrap":           "Wrap",
+	"badge.wrap.s":         "Wrp",
+	"badge.column":         "→%d",
+	"badge.viewers":        "viewers: %d",
+	"badge.reconnecting":   "reconnecting…",
+	"badge.reconnecting.s": "RECON",
+	// %s is the session time played, then the speed factor
+	"badge.playback":        "▶ %s %sx",
+	"badge.playback_paused": "⏸ %s",
+	"layout.too_small":      "terminal too small",
+	"layout.tiny":           "too small",
+
+	// help modal
+	"help.close": "Close",
+	"help.focus": `Focus & Quit
+  Tab / Shift+Tab     Switch focus (Log ↔ Input)
+  Ctrl+C              Quit immediately
+  q (log focus)       Quit`,
+	"help.log": `Log View (when focused)
+  Up/Down             Scroll one line
+  PgUp/PgDn           Scroll one page
+  Home/End            Jump to top/bottom; scrolling up stops autoscroll until End
+  Space               Pause/Resume autoscroll
+  c                   Toggle case sensitivity for filter
+  C                   Toggle smart case (uppercase in filter = case-sensitive)
+  e                   Toggle regular expression filtering
+  F                   Filter on the server: receive only matching lines (attached)
+  r                   Toggle newest-first order (follows the top)
+  z                   Fold/unfold continuation lines and stack traces
+  #                   Show/hide line IDs
+  t                   Cycle the timestamp column: absolute, relative, delta, off
+  W                   Wrap long lines, or cut them off at the edge
+                      (selection, n/N, :goto, i and x need them cut off)
+  Left/Right          Scroll unwrapped lines sideways (Shift: half a screen)
+  !                   Send a command to the server (attached)
+  /                   Search: mark matches in place, keeping other lines
+  n / N               Jump to the next/previous search match
+  [ / ]               Show the previous/next channel
+  1 2 3 4             Show/hide error, warning, info and debug lines
+  < / >               Playback: slower/faster (Space pauses)
+  d                   Diff mode: mark fields changed since the previous similar line
+  m                   Toggle mouse mode (green = terminal selection enabled)
+  p                   Show most frequent line patterns
+  s                   Show/hide statistics: lines/sec, bytes, levels, counter history
+  w                   Export the filtered or whole buffer to a file
+  Shift+Up/Down       Select rows (or drag with the mouse); Esc clears
+  y                   Copy the selected rows through the terminal (OSC 52)
+  x                   Trace the MAC/XID/IP of the current line through the buffer
+  i                   Inspect the selected or current line: raw text, JSON, time, rules
+  ?                   Toggle this help`,
+	// %s is the list of palette names
+	"help.filter": `Filter (Input line)
+  Type text to set filter pattern
+  -text               Hide lines matching text instead (\-text for a leading -)
+  a && b  /  a || b   Lines matching both / either; && binds tighter
+  since:10m           Lines from the last 10 minutes; also since:14:30, until:14:45
+  Enter               Enable/Disable filter (keeps text)
+  Esc                 Clear & disable filter
+  Up/Down             Previous/next filter, search or command entered
+  :goto <id>          Jump to the line with that ID
+  :channel [name]     Show one channel, or all without a name
+  :columns [f1,f2]    Show those line fields as columns; none for key=value pairs
+  :where [key=value]  Show only lines with that field value; none to clear
+  :speed <factor>     Playback speed, 1 being real time
+  :jump <duration>    Skip playback ahead, e.g. 30s or 5m
+  :stats              Show broker delivery stats per viewer (attached)
+  :copy [file]        Copy all filtered lines (to a temp file if large)
+  :palette <name>     Switch colours: %s (also :theme)
+  :filter [level=a,b] [text]  Set the filter and shown levels; none to clear
+  :maxlines <n>       Keep at most n lines in the buffer
+  :clear              Empty the buffer
+  :save [path]        Same as :export text
+  :export html [path] Save the filtered buffer as coloured HTML
+  :export ansi [path] Save it with ANSI colours (less -R)
+  :export text [path] Save it as plain text; ndjson for line frames
+  :export all <fmt>   Export every buffered line, not only filtered ones
+  Tab                 Complete the word: commands after ':', else the application's words
+  Matching text is shown in reverse video while the filter is active`,
+	"help.presets": "Filter presets (Log view; press again to clear)",
+	"help.topbar": `Top Bar
+  Shows Title (left), the number of viewers when attached and registered counters (right).`,
+	"help.legacy": `Bottom Status
+  Shows keys and counters (legacy mode).`,
+	"help.status": `Status Bar
+  Shows keys on the left and toggles on the right. Mouse badge is green when you can
+  select with the mouse (i.e., tview mouse handling is OFF).`,
+
+	// modal titles
+	"modal.patterns": "Patterns",
+	"modal.trace":    "Trace %s",
+	"modal.inspect":  "Line %s",
+	"modal.stats":    "Broker stats",
+	"modal.stream":   "Statistics",
+
+	// statistics panel and broker stats
+	"stats.received":  "Lines received",
+	"stats.since":     "since %s (%s ago)",
+	"stats.rate":      "Lines/sec",
+	"stats.rate_now":  "%s now, %d peak",
+	"stats.bytes":     "Bytes",
+	"stats.buffered":  "Buffered",
+	"stats.lines":     "%d lines",
+	"stats.levels":    "Levels",
+	"stats.counters":  "Counters",
+	"stats.counter":   "%6d in %-6s %8d total",
+	"stats.appended":  "Lines appended: %d",
+	"stats.viewers":   "Viewers: %d",
+	"stats.slow":      "Slow viewers disconnected: %d",
+	"stats.id":        "ID",
+	"stats.remote":    "Remote",
+	"stats.connected": "Connected",
+	"stats.dropped":   "Dropped",
+	"stats.queue":     "Queue/High",
+	"counter.invalid": "invalid pattern",
+
+	// line inspector
+	"inspect.time":       "Time",
+	"inspect.server":     "Server",
+	"inspect.cont":       "continuation line; time of the line it follows",
+	"inspect.level":      "Level",
+	"inspect.id":         "ID",
+	"inspect.source":     "Source",
+	"inspect.channel":    "Channel",
+	"inspect.fields":     "Fields",
+	"inspect.highlights": "Highlights",
+	"inspect.counters":   "Counters",
+	"inspect.nomatch":    "none",
+	"inspect.raw":        "Raw",
+	"inspect.json":       "JSON",
+
+	// command feedback
+	"cmd.unknown":          "unknown command: %s",
+	"history.error":        "history: %v",
+	"timestamps.mode":      "timestamps: %s",
+	"timestamps.off":       "off",
+	"goto.usage":           "usage: :goto <id>",
+	"goto.invalid":         "goto: invalid line id %s",
+	"goto.missing":         "goto: line #%d is not in view",
+	"playback.unavailable": "not replaying a session",
+	"speed.usage":          "usage: :speed <factor>, e.g. 2 or 0.5",
+	"jump.usage":           "usage: :jump <duration>, e.g. 30s or 5m",
+	"stats.unavailable":    "stats: not attached to a broker",
+	"copy.usage":           "usage: :copy [file]",
+	"copy.empty":           "copy: nothing to copy",
+	"copy.clipboard":       "copied %d lines to clipboard",
+	"copy.file":            "wrote %d lines to %s%s",
+	"copy.over_limit":      " (over %d bytes)",
+	"copy.error":           "copy: %v",
+	"select.none":          "nothing selected; use Shift+Up/Down",
+	"select.copied":        "copied %d lines",
+	"select.too_large":     "selection is over %d bytes; use :copy",
+	"command.unavailable":  "commands: not attached to a broker",
+	"command.sent":         "sent: %s",
+	"filter.server_on":     "filtering on the server",
+	"filter.server_off":    "receiving every line",
+	"filter.unavailable":   "server filter: not attached to a broker",
+	"server.unsupported":   "%s: not supported by this server",
+	"search.none":          "no search; press / to start one",
+	"search.no_match":      "search: no match for %s",
+	"search.position":      "match %d of %d",
+	"where.usage":          "usage: :where [key=value]",
+	"channel.unknown":      "unknown channel %s; choose %s",
+	"channel.all":          "all",
+	"palette.usage":        "usage: :palette %s",
+	"palette.unknown":      "unknown palette; choose %s",
+	"palette.set":          "palette %s",
+	"clear.done":           "cleared %d lines",
+	"maxlines.usage":       "usage: :maxlines <n>",
+	"maxlines.set":         "keeping %d lines",
+	"filter.level_unknown": "unknown level %s; choose %s",
+	"export.usage":         "usage: :export [all] text|ndjson|html|ansi [path]",
+	"export.format":        "export: unknown format %s",
+	"export.error":         "export: %v",
+	"export.done":          "exported %d lines to %s",
+	"export.prompt":        "Export to a file in the working directory",
+	"export.text":          "Filtered text",
+	"export.ndjson":        "Filtered NDJSON",
+	"export.all_text":      "All as text",
+	"export.all_ndjson":    "All as NDJSON",
+	"export.cancel":        "Cancel",
+	"export.title":         "Console export",
+	"correlate.none":       "correlate: no line selected",
+	"inspect.none":         "inspect: no line selected",
+	"wrap.rows":            "%s: not while lines wrap; press W to cut them off",
+	"correlate.no_token":   "correlate: no MAC, XID or IP in the current line",
+	"title.attached":       "Console (attached)",
+	"title.offline":        "Console (offline)",
+	"title.file":           "Console (%s)",
+	"title.playback":       "Console (playback %s)",
+	"notice.disconnected":  "[notice] disconnected from server",
+	"notice.reconnected":   "[notice] reconnected to server",
+	"notice.clock_skew":    "[notice] viewer clock differs from server by %s; adjusting timestamps",
+	"notice.malformed":     "[notice] skipped malformed frames (%d total)",
+	"notice.read_error":    "[notice] read error: %v",
+	"notice.end":           "[notice] end of input (%d lines)",
+	"notice.gap":           "[notice] %d lines missing",
+	"notice.protocol":      "[notice] server speaks protocol v%d, this client v%d; some features may be missing",
+	"notice.playback_end":  "[notice] end of session (%d lines)",
+}
+
+// mergeMessages returns DefaultMessages with overrides applied.
+func mergeMessages(overrides map[string]string) map[string]string {
+	m := maps.Clone(DefaultMessages)
+	maps.Copy(m, overrides)
+	return m
+}
+
+// msg returns the message id, formatted with args if any. Unknown IDs are
+// returned as is so a missing entry is visible rather than blank.
+func (u *UI) msg(id string, args ...any) string {
+	s, ok := u.messages[id]
+	if !ok {
+		s = id
+	}
+	if len(args) == 0 {
+		return s
+	}
+	return fmt.Sprintf(s, args...)
+}
@@ -0,0 +1,120 @@
+package console
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// observeCounters runs the broker's counters over one appended line.
+func (b *Broker) observeCounters(text string) {
+	now := time.Now()
+	b.counterMu.Lock()
+	for _, c := range b.counters {
+		c.observe(text, now)
+		c.prune(now.Add(-c.retention()))
+	}
+	alerts := thresholdAlerts(b.counters, now)
+	b.counterMu.Unlock()
+	if b.onThreshold != nil {
+		for _, a := range alerts {
+			b.onThreshold(a.spec, a.count)
+		}
+	}
+}
+
+// DefaultCounterInterval is how often a broker sends clients its counter
+// values by default.
+const DefaultCounterInterval = time.Second
+
+// countersFrame encodes the current value of every counter, or returns nil
+// if there are none.
+func (b *Broker) countersFrame() []byte {
+	now := time.Now()
+	b.counterMu.Lock()
+	if len(b.counters) == 0 {
+		b.counterMu.Unlock()
+		return nil
+	}
+	vals := make([]CounterValue, 0, len(b.counters))
+	for _, c := range b.counters {
+		c.prune(now.Add(-c.retention()))
+		_, over := c.overThreshold(now)
+		vals = append(vals, CounterValue{
+			Label:   c.label,
+			Match:   c.match,
+			Count:   c.windowCount(now),
+			Total:   c.total,
+			Display: c.display(now, DefaultMessages["counter.invalid"]),
+			Alert:   over,
+		})
+	}
+	b.counterMu.Unlock()
+	buf, _ := json.Marshal(CounterValues{Type: "counters", Counters: vals})
+	return append(buf, '\n')
+}
+
+// sendCounters sends counter values to the clients asking for them every
+// counterInterval until stopCh closes.
+func (b *Broker) sendCounters(stopCh chan struct{}) {
+	t := time.NewTicker(b.counterInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			buf := b.countersFrame()
+			if buf == nil {
+				continue
+			}
+			b.ringMu.Lock()
+			for cli := range b.clients {
+				if cli.counters.Load() {
+					b.trySend(cli, buf)
+				}
+			}
+			b.ringMu.Unlock()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// CounterSample is the state of one of Config.Counters, for metrics
+// exporters such as promconsole's.
+type CounterSample struct {
+	Label string
+	// Count is the counter's matches within its window, Total those since
+	// the broker started.
+	Count int
+	Total uint64
+	// Top holds the busiest captured values of a grouping counter, with
+	// their matches within the window, busiest first.
+	Top []KeyCount
+}
+
+// KeyCount is a captured value of a grouping counter and its matches.
+type KeyCount struct {
+	Key   string
+	Count int
+}
+
+// CounterSamples returns the state of each counter whose pattern compiled.
+func (b *Broker) CounterSamples() []CounterSample {
+	now := time.Now()
+	b.counterMu.Lock()
+	defer b.counterMu.Unlock()
+	out := make([]CounterSample, 0, len(b.counters))
+	for _, cr := range b.counters {
+		if cr.invalid {
+			continue
+		}
+		cs := CounterSample{Label: cr.label, Count: cr.windowCount(now), Total: cr.total}
+		if cr.byKey != nil {
+			keys, counts, _ := cr.topKeys(now.Add(-cr.window), cr.topN)
+			for i, k := range keys {
+				cs.Top = append(cs.Top, KeyCount{Key: k, Count: counts[i]})
+			}
+		}
+		out = append(out, cs)
+	}
+	return out
+}
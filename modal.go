@@ -0,0 +1,49 @@
+package console
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// modalPage is the page name used for all modal overlays.
+const modalPage = "modal"
+
+// showTextModal shows a scrollable, bordered text panel over the console and
+// returns its text view. Esc, Enter or q closes it. Must be called on the UI
+// goroutine.
+func (u *UI) showTextModal(title, text string) *tview.TextView {
+	tv := tview.NewTextView().
+		SetDynamicColors(!u.noColour).
+		SetScrollable(true).
+		SetWrap(false).
+		SetText(text)
+	tv.SetBorder(true).SetTitle(" " + title + " ")
+	tv.SetDoneFunc(func(tcell.Key) { u.closeModal() })
+	u.showModal(centered(tv, 8, 8))
+	return tv
+}
+
+// showModal places p over the console and focuses it.
+func (u *UI) showModal(p tview.Primitive) {
+	if u.modal != nil {
+		u.pages.RemovePage(modalPage)
+	} else {
+		u.prevFocus = u.app.GetFocus()
+	}
+	u.modal = p
+	u.pages.AddPage(modalPage, p, true, true)
+	u.app.SetFocus(p)
+}
+
+// centered wraps p so it takes w/10 of the width and h/10 of the height.
+func centered(p tview.Primitive, w, h int) tview.Primitive {
+	side := (10 - w) / 2
+	top := (10 - h) / 2
+	return tview.NewFlex().
+		AddItem(nil, 0, side, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, top, false).
+			AddItem(p, 0, h, true).
+			AddItem(nil, 0, top, false), 0, w, true).
+		AddItem(nil, 0, side, false)
+}
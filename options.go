@@ -0,0 +1,243 @@
+package console
+
+import (
+	"net"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// UIOption configures a UI created with NewUIWith.
+type UIOption func(*UIOptions)
+
+// BrokerOption configures a Broker created with NewBrokerWith.
+type BrokerOption func(*BrokerOptions)
+
+// NewUIWith creates a console UI from functional options.
+// It is equivalent to NewUI with the resulting UIOptions.
+func NewUIWith(opts ...UIOption) *UI {
+	var o UIOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return NewUI(o)
+}
+
+// NewBrokerWith creates a broker from functional options.
+// It is equivalent to NewBroker with the resulting BrokerOptions.
+func NewBrokerWith(opts ...BrokerOption) *Broker {
+	var o BrokerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return NewBroker(o)
+}
+
+// ---- UI options ----
+
+// WithHelpExtra appends lines to the help modal.
+func WithHelpExtra(lines ...string) UIOption {
+	return func(o *UIOptions) { o.HelpExtra = append(o.HelpExtra, lines...) }
+}
+
+// WithMaxLines overrides the number of lines kept by the UI.
+func WithMaxLines(n int) UIOption {
+	return func(o *UIOptions) { o.MaxLines = n }
+}
+
+// WithOnExit sets the function called when the user quits.
+func WithOnExit(fn func(code int)) UIOption {
+	return func(o *UIOptions) { o.OnExit = fn }
+}
+
+// WithRules sets the initial counters, highlights and max lines.
+func WithRules(cfg Config) UIOption {
+	return func(o *UIOptions) { o.Rules = cfg }
+}
+
+// WithNoColour disables all colour output.
+func WithNoColour() UIOption {
+	return func(o *UIOptions) { o.NoColour = true }
+}
+
+// WithMouse enables or disables tview mouse handling at start.
+func WithMouse(enabled bool) UIOption {
+	return func(o *UIOptions) { o.MouseEnabled = enabled }
+}
+
+// WithoutTopBar selects the legacy layout with counters in the bottom bar.
+func WithoutTopBar() UIOption {
+	return func(o *UIOptions) { o.DisableTopBar = true }
+}
+
+// WithOnFilterChange sets the filter change callback.
+func WithOnFilterChange(fn func(filter string, active, caseSensitive bool)) UIOption {
+	return func(o *UIOptions) { o.OnFilterChange = fn }
+}
+
+// WithApplication embeds the UI into an application owned by the caller.
+func WithApplication(app *tview.Application) UIOption {
+	return func(o *UIOptions) { o.Application = app }
+}
+
+// WithSmartCase starts the UI in smart-case filtering mode.
+func WithSmartCase() UIOption {
+	return func(o *UIOptions) { o.SmartCase = true }
+}
+
+// WithNewestFirst renders the newest line at the top.
+func WithNewestFirst() UIOption {
+	return func(o *UIOptions) { o.NewestFirst = true }
+}
+
+// WithFoldGroups starts with continuation lines collapsed.
+func WithFoldGroups() UIOption {
+	return func(o *UIOptions) { o.FoldGroups = true }
+}
+
+// WithTimestampLayouts enables timestamp extraction from line prefixes.
+func WithTimestampLayouts(layouts ...string) UIOption {
+	return func(o *UIOptions) { o.TimestampLayouts = append(o.TimestampLayouts, layouts...) }
+}
+
+// WithPalette selects a built-in palette by name.
+func WithPalette(name string) UIOption {
+	return func(o *UIOptions) { o.Palette = name }
+}
+
+// WithAutoPauseOnErrors pauses autoscroll when more than n error lines
+// arrive within window.
+func WithAutoPauseOnErrors(n int, window time.Duration) UIOption {
+	return func(o *UIOptions) {
+		o.AutoPauseErrors = n
+		o.AutoPauseWindow = window
+	}
+}
+
+// WithCopyMaxBytes caps the text :copy puts on the clipboard.
+func WithCopyMaxBytes(n int) UIOption {
+	return func(o *UIOptions) { o.CopyMaxBytes = n }
+}
+
+// WithMessages overrides UI strings by message ID; see DefaultMessages.
+func WithMessages(m map[string]string) UIOption {
+	return func(o *UIOptions) { o.Messages = m }
+}
+
+// WithFilterRegex starts with regular expression filtering on.
+func WithFilterRegex() UIOption {
+	return func(o *UIOptions) { o.FilterRegex = true }
+}
+
+// WithColumns shows the named line fields as aligned columns.
+func WithColumns(fields ...string) UIOption {
+	return func(o *UIOptions) { o.Columns = append(o.Columns, fields...) }
+}
+
+// WithCustomPalette uses p instead of a built-in palette.
+func WithCustomPalette(p Palette) UIOption {
+	return func(o *UIOptions) { o.CustomPalette = &p }
+}
+
+// WithKeys rebinds log view actions; see KeyActions.
+func WithKeys(keys map[string]rune) UIOption {
+	return func(o *UIOptions) { o.Keys = keys }
+}
+
+// ---- Broker options ----
+
+// WithConfig sets the presentation rules sent to clients.
+func WithConfig(cfg Config) BrokerOption {
+	return func(o *BrokerOptions) { o.Config = cfg }
+}
+
+// WithSocketCandidates sets the UNIX socket paths tried in order.
+func WithSocketCandidates(paths ...string) BrokerOption {
+	return func(o *BrokerOptions) { o.SocketCandidates = append(o.SocketCandidates, paths...) }
+}
+
+// WithListenerFactory replaces socket candidate probing with a custom listener.
+func WithListenerFactory(fn func() (string, net.Listener, error)) BrokerOption {
+	return func(o *BrokerOptions) { o.ListenerFactory = fn }
+}
+
+// WithListenAddr makes the broker also accept clients on a TCP address.
+func WithListenAddr(addr string) BrokerOption {
+	return func(o *BrokerOptions) { o.ListenAddr = addr }
+}
+
+// WithTLS secures the ListenAddr listener with TLS.
+func WithTLS(o TLSOptions) BrokerOption {
+	return func(opts *BrokerOptions) { opts.TLS = &o }
+}
+
+// WithHTTPAddr serves the browser viewer and the /stream event stream on
+// an HTTP address.
+func WithHTTPAddr(addr string) BrokerOption {
+	return func(o *BrokerOptions) { o.HTTPAddr = addr }
+}
+
+// WithHTTPBridge configures the HTTPAddr bridge.
+func WithHTTPBridge(o HTTPBridgeOptions) BrokerOption {
+	return func(opts *BrokerOptions) { opts.HTTPBridge = &o }
+}
+
+// WithPersistPath saves the ring to path and restores it on startup.
+func WithPersistPath(path string) BrokerOption {
+	return func(o *BrokerOptions) { o.PersistPath = path }
+}
+
+// WithFileSink also writes every line to rotated NDJSON files.
+func WithFileSink(o FileSinkOptions) BrokerOption {
+	return func(opts *BrokerOptions) { opts.FileSink = &o }
+}
+
+// WithSyslog forwards every line to a syslog daemon.
+func WithSyslog(o SyslogOptions) BrokerOption {
+	return func(opts *BrokerOptions) { opts.Syslog = &o }
+}
+
+// WithOnLine sets the callback for every line added to the ring.
+func WithOnLine(fn func(Line)) BrokerOption {
+	return func(o *BrokerOptions) { o.OnLine = fn }
+}
+
+// WithOnCommand sets the callback for commands typed into clients.
+func WithOnCommand(fn func(clientID uint64, cmd string)) BrokerOption {
+	return func(o *BrokerOptions) { o.OnCommand = fn }
+}
+
+// WithMaxClients caps the number of attached clients.
+func WithMaxClients(n int) BrokerOption {
+	return func(o *BrokerOptions) { o.MaxClients = n }
+}
+
+// WithOnClientConnect sets the client connect callback.
+func WithOnClientConnect(fn func(ClientInfo)) BrokerOption {
+	return func(o *BrokerOptions) { o.OnClientConnect = fn }
+}
+
+// WithOnClientDisconnect sets the client disconnect callback.
+func WithOnClientDisconnect(fn func(ClientInfo)) BrokerOption {
+	return func(o *BrokerOptions) { o.OnClientDisconnect = fn }
+}
+
+// WithLevelFunc sets how the broker classifies line levels.
+func WithLevelFunc(fn LevelFunc) BrokerOption {
+	return func(o *BrokerOptions) { o.LevelFunc = fn }
+}
+
+// WithComputeSpans makes the broker attach highlight spans to every line.
+func WithComputeSpans() BrokerOption {
+	return func(o *BrokerOptions) { o.ComputeSpans = true }
+}
+
+// WithMergeWindow orders lines by timestamp within window before delivery.
+func WithMergeWindow(window time.Duration) BrokerOption {
+	return func(o *BrokerOptions) { o.MergeWindow = window }
+}
+
+// WithAuthorizePublish lets clients accepted by fn append lines.
+func WithAuthorizePublish(fn func(ClientInfo) bool) BrokerOption {
+	return func(o *BrokerOptions) { o.AuthorizePublish = fn }
+}
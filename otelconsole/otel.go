@@ -0,0 +1,135 @@
+// Package otelconsole emits the lines of a console broker as OpenTelemetry
+// log records, for export to a collector:
+//
+//	x := otelconsole.New(otelconsole.Options{LoggerProvider: lp})
+//	defer x.Close()
+//	b := console.NewBroker(console.BrokerOptions{OnLine: x.Export})
+package otelconsole
+
+import (
+	"cmp"
+	"context"
+	"sync"
+	"time"
+
+	console "github.com/network-plane/planeconsole"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+)
+
+// Options configure an Exporter.
+type Options struct {
+	// LoggerProvider creates the logger records are emitted to, typically an
+	// SDK provider (go.opentelemetry.io/otel/sdk/log) with an OTLP exporter;
+	// default the global provider.
+	LoggerProvider log.LoggerProvider
+	// Name is the instrumentation scope; default the console package's
+	// import path.
+	Name string
+}
+
+// queueLen is how many lines the exporter holds while the provider is
+// slow; further lines are not emitted.
+const queueLen = 1024
+
+// Exporter emits lines from its own goroutine, so a provider exporting
+// synchronously never holds up Append.
+type Exporter struct {
+	logger log.Logger
+	lines  chan console.Line
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+// New returns an exporter emitting the lines passed to Export until Close.
+func New(opts Options) *Exporter {
+	lp := opts.LoggerProvider
+	if lp == nil {
+		lp = global.GetLoggerProvider()
+	}
+	name := cmp.Or(opts.Name, "github.com/network-plane/planeconsole")
+	x := &Exporter{
+		logger: lp.Logger(name),
+		lines:  make(chan console.Line, queueLen),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go x.run()
+	return x
+}
+
+// Export queues ev, dropping it if the queue is full. It is a
+// BrokerOptions.OnLine.
+func (x *Exporter) Export(ev console.Line) {
+	select {
+	case x.lines <- ev:
+	default:
+	}
+}
+
+// Close emits the lines still queued and stops the exporter. Close it
+// after stopping the broker.
+func (x *Exporter) Close() {
+	x.once.Do(func() { close(x.stop) })
+	<-x.done
+}
+
+// run emits queued lines until Close, then the ones still queued.
+func (x *Exporter) run() {
+	defer close(x.done)
+	ctx := context.Background()
+	for {
+		select {
+		case ev := <-x.lines:
+			x.logger.Emit(ctx, record(ev))
+		case <-x.stop:
+			for {
+				select {
+				case ev := <-x.lines:
+					x.logger.Emit(ctx, record(ev))
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// record converts ev to a log record: its text is the body, its level the
+// severity, and its source, channel, ID and fields attributes.
+func record(ev console.Line) log.Record {
+	var r log.Record
+	r.SetTimestamp(time.UnixMicro(ev.TsUs))
+	r.SetObservedTimestamp(time.Now())
+	level := console.LevelClass(cmp.Or(ev.Level, console.LevelOf(ev.Text)))
+	r.SetSeverity(severity(level))
+	r.SetSeverityText(level)
+	r.SetBody(log.StringValue(ev.Text))
+	attrs := make([]log.KeyValue, 0, 3+len(ev.Fields))
+	if ev.Source != "" {
+		attrs = append(attrs, log.String("console.source", ev.Source))
+	}
+	if ev.Channel != "" {
+		attrs = append(attrs, log.String("console.channel", ev.Channel))
+	}
+	attrs = append(attrs, log.Int64("console.seq", int64(ev.Seq)))
+	for k, v := range ev.Fields {
+		attrs = append(attrs, log.String(k, v))
+	}
+	r.AddAttributes(attrs...)
+	return r
+}
+
+// severity maps a level class to its OpenTelemetry severity.
+func severity(class string) log.Severity {
+	switch class {
+	case "error":
+		return log.SeverityError
+	case "warn":
+		return log.SeverityWarn
+	case "debug":
+		return log.SeverityDebug
+	}
+	return log.SeverityInfo
+}
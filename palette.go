@@ -0,0 +1,234 @@
+package console
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Palette is a named set of styles for the UI's own chrome and for level
+// highlighting, the console's theme. Select one with UIOptions.Palette or
+// Config.Palette.
+//
+// Text, Bar and Input only use colours. A zero style leaves that part of the
+// UI in the terminal's colours, as the default palette does.
+type Palette struct {
+	Name     string
+	Key      Style // key hints in the status bar
+	Active   Style // enabled toggle badges
+	Inactive Style // disabled toggle badges
+	Message  Style // transient status messages
+	Error    Style // error lines and the ERROR level
+	Warn     Style // warnings
+	Info     Style // informational levels
+	Debug    Style // debug and trace levels
+
+	Text      Style // the log view's text and background
+	Bar       Style // the top and status bars
+	Title     Style // the title in the top bar
+	Counter   Style // counters in the top bar
+	Separator Style // the lines around the log view
+	Focus     Style // the separators while the log view has focus
+	Input     Style // the input line
+	Selection Style // selected rows; reverse video when zero
+}
+
+// Built-in palettes.
+var (
+	PaletteDefault = Palette{
+		Name:     "default",
+		Key:      Style{FG: "blue", Attrs: "b"},
+		Active:   Style{FG: "green", Attrs: "b"},
+		Inactive: Style{FG: "yellow"},
+		Message:  Style{FG: "yellow", Attrs: "b"},
+		Error:    Style{FG: "red", Attrs: "b"},
+		Warn:     Style{FG: "yellow"},
+		Info:     Style{FG: "green"},
+		Debug:    Style{FG: "gray"},
+	}
+	// PaletteDark sets a dark background for the whole UI, whatever the
+	// terminal's.
+	PaletteDark = Palette{
+		Name:      "dark",
+		Key:       Style{FG: "#87afff", Attrs: "b"},
+		Active:    Style{FG: "#87d787", Attrs: "b"},
+		Inactive:  Style{FG: "#808080"},
+		Message:   Style{FG: "#ffd75f", Attrs: "b"},
+		Error:     Style{FG: "#ff5f5f", Attrs: "b"},
+		Warn:      Style{FG: "#ffd75f"},
+		Info:      Style{FG: "#87d787"},
+		Debug:     Style{FG: "#808080"},
+		Text:      Style{FG: "#d0d0d0", BG: "#121212"},
+		Bar:       Style{FG: "#bcbcbc", BG: "#262626"},
+		Title:     Style{FG: "#ffffff", Attrs: "b"},
+		Counter:   Style{FG: "#87d7ff"},
+		Separator: Style{FG: "#444444"},
+		Focus:     Style{FG: "#87afff"},
+		Input:     Style{FG: "#ffffff", BG: "#1c1c1c"},
+		Selection: Style{FG: "#ffffff", BG: "#005f87"},
+	}
+	// PaletteLight suits light backgrounds, with darker level colours.
+	PaletteLight = Palette{
+		Name:      "light",
+		Key:       Style{FG: "#005fd7", Attrs: "b"},
+		Active:    Style{FG: "#008700", Attrs: "b"},
+		Inactive:  Style{FG: "#8a8a8a"},
+		Message:   Style{FG: "#af5f00", Attrs: "b"},
+		Error:     Style{FG: "#d70000", Attrs: "b"},
+		Warn:      Style{FG: "#af5f00"},
+		Info:      Style{FG: "#005f00"},
+		Debug:     Style{FG: "#8a8a8a"},
+		Text:      Style{FG: "#1c1c1c", BG: "#ffffff"},
+		Bar:       Style{FG: "#303030", BG: "#e4e4e4"},
+		Title:     Style{FG: "#000000", Attrs: "b"},
+		Counter:   Style{FG: "#005f87"},
+		Separator: Style{FG: "#bcbcbc"},
+		Focus:     Style{FG: "#005fd7"},
+		Input:     Style{FG: "#000000", BG: "#eeeeee"},
+		Selection: Style{FG: "#000000", BG: "#afd7ff"},
+	}
+	// PaletteDeuteranopia avoids red/green contrasts, using blue and orange,
+	// for colour-blind users.
+	PaletteDeuteranopia = Palette{
+		Name:     "deuteranopia",
+		Key:      Style{FG: "#56b4e9", Attrs: "b"},
+		Active:   Style{FG: "#0072b2", Attrs: "b"},
+		Inactive: Style{FG: "#e69f00"},
+		Message:  Style{FG: "#f0e442", Attrs: "b"},
+		Error:    Style{FG: "#d55e00", Attrs: "bu"},
+		Warn:     Style{FG: "#e69f00"},
+		Info:     Style{FG: "#56b4e9"},
+		Debug:    Style{FG: "gray"},
+		Counter:  Style{FG: "#56b4e9"},
+		Focus:    Style{FG: "#0072b2"},
+	}
+	// PaletteHighContrast uses bright, bold colours and reverse video.
+	PaletteHighContrast = Palette{
+		Name:     "high-contrast",
+		Key:      Style{FG: "white", Attrs: "bu"},
+		Active:   Style{FG: "black", BG: "white", Attrs: "b"},
+		Inactive: Style{FG: "white"},
+		Message:  Style{FG: "black", BG: "yellow", Attrs: "b"},
+		Error:    Style{FG: "white", BG: "red", Attrs: "b"},
+		Warn:     Style{FG: "black", BG: "yellow"},
+		Info:     Style{FG: "white", Attrs: "b"},
+		Debug:    Style{FG: "silver"},
+	}
+	// PaletteMonochromeBold uses attributes only, for monochrome terminals.
+	PaletteMonochromeBold = Palette{
+		Name:     "monochrome-bold",
+		Key:      Style{Attrs: "b"},
+		Active:   Style{Attrs: "br"},
+		Inactive: Style{Attrs: "d"},
+		Message:  Style{Attrs: "bu"},
+		Error:    Style{Attrs: "br"},
+		Warn:     Style{Attrs: "bu"},
+		Info:     Style{Attrs: "b"},
+		Debug:    Style{Attrs: "d"},
+	}
+)
+
+// Palettes lists the built-in palettes.
+var Palettes = []Palette{PaletteDefault, PaletteDark, PaletteLight, PaletteDeuteranopia, PaletteHighContrast, PaletteMonochromeBold}
+
+// PaletteByName returns the built-in palette with the given name, ignoring
+// case. The empty name selects the default palette and "colour-blind" the
+// deuteranopia one.
+func PaletteByName(name string) (Palette, bool) {
+	switch strings.ToLower(name) {
+	case "":
+		return PaletteDefault, true
+	case "colour-blind", "color-blind":
+		return PaletteDeuteranopia, true
+	}
+	for _, p := range Palettes {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return Palette{}, false
+}
+
+// LevelStyle returns the palette's style for a level as reported by LevelOf.
+func (p Palette) LevelStyle(level string) Style {
+	switch strings.ToLower(level) {
+	case "error", "fatal", "panic":
+		return p.Error
+	case "warn", "warning":
+		return p.Warn
+	case "debug", "trace":
+		return p.Debug
+	default:
+		return p.Info
+	}
+}
+
+// ExampleHighlights returns starter highlight rules for common level words,
+// styled with the palette.
+func (p Palette) ExampleHighlights() []HighlightSpec {
+	spec := func(match string, st Style) HighlightSpec {
+		return HighlightSpec{Match: match, CaseSensitive: true, Style: &st}
+	}
+	return []HighlightSpec{
+		spec("ERROR", p.Error),
+		spec("FATAL", p.Error),
+		spec("PANIC", p.Error),
+		spec("WARN", p.Warn),
+		spec("INFO", p.Info),
+		spec("DEBUG", p.Debug),
+	}
+}
+
+// paletteNames lists the built-in palette names separated by "|".
+func paletteNames() string {
+	names := make([]string, len(Palettes))
+	for i, p := range Palettes {
+		names[i] = p.Name
+	}
+	return strings.Join(names, "|")
+}
+
+// currentPalette returns the palette in use.
+func (u *UI) currentPalette() Palette {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.palette
+}
+
+// setPaletteDirect switches palettes and repaints everything it styles.
+func (u *UI) setPaletteDirect(p Palette) {
+	u.mu.Lock()
+	u.palette = p
+	u.mu.Unlock()
+	u.applyChrome()
+	u.refreshDirect()
+}
+
+// applyChrome colours the widgets from the palette's Text, Bar and Input
+// styles. Separators, title and counters are styled as they are drawn.
+func (u *UI) applyChrome() {
+	if u.noColour {
+		return
+	}
+	pal := u.currentPalette()
+	def := tview.Styles
+	u.logView.SetTextColor(styleColour(pal.Text.FG, def.PrimaryTextColor))
+	u.logView.SetBackgroundColor(styleColour(pal.Text.BG, def.PrimitiveBackgroundColor))
+	for _, bar := range []*tview.TextView{u.topBar, u.statusText, u.topSep, u.bottomSep} {
+		bar.SetTextColor(styleColour(pal.Bar.FG, def.PrimaryTextColor))
+		bar.SetBackgroundColor(styleColour(pal.Bar.BG, def.PrimitiveBackgroundColor))
+	}
+	u.inputField.SetBackgroundColor(styleColour(pal.Bar.BG, def.PrimitiveBackgroundColor))
+	u.inputField.SetLabelColor(styleColour(pal.Input.FG, def.SecondaryTextColor))
+	u.inputField.SetFieldTextColor(styleColour(pal.Input.FG, def.PrimaryTextColor))
+	u.inputField.SetFieldBackgroundColor(styleColour(pal.Input.BG, def.ContrastBackgroundColor))
+}
+
+// styleColour parses a Style colour, returning def for an empty or "-" one.
+func styleColour(c string, def tcell.Color) tcell.Color {
+	if c == "" || c == "-" {
+		return def
+	}
+	return tcell.GetColor(c)
+}
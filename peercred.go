@@ -0,0 +1,68 @@
+package console
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+	"slices"
+)
+
+// peerCred identifies the local user on the other end of a UNIX socket.
+type peerCred struct {
+	uid  int
+	gids []int // the primary group, and on BSDs the supplementary ones
+}
+
+// unknownPeer is the peerCred of connections without credentials.
+var unknownPeer = peerCred{uid: -1}
+
+// gid returns the primary group, or -1.
+func (c peerCred) gid() int {
+	if len(c.gids) == 0 {
+		return -1
+	}
+	return c.gids[0]
+}
+
+// restrictsPeers reports whether AllowedUIDs or AllowedGIDs is set.
+func (b *Broker) restrictsPeers() bool {
+	return len(b.allowedUIDs) > 0 || len(b.allowedGIDs) > 0
+}
+
+// checkPeerSupport fails Start when peers are restricted on a platform
+// without peer credentials, rather than letting every user attach.
+func (b *Broker) checkPeerSupport() error {
+	if b.restrictsPeers() && !peerCredSupported {
+		return fmt.Errorf("console broker: AllowedUIDs and AllowedGIDs are not supported on %s", runtime.GOOS)
+	}
+	return nil
+}
+
+// authorizePeer reads the credentials of a UNIX socket client and checks
+// them against AllowedUIDs and AllowedGIDs, returning the notice to reject
+// it with. Other connections are not checked and have unknownPeer.
+func (b *Broker) authorizePeer(conn net.Conn) (peerCred, string, bool) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return unknownPeer, "", true
+	}
+	cred, err := peerCredentials(uc)
+	if !b.restrictsPeers() {
+		if err != nil {
+			cred = unknownPeer
+		}
+		return cred, "", true
+	}
+	if err != nil {
+		return unknownPeer, fmt.Sprintf("[notice] access denied: %v", err), false
+	}
+	if slices.Contains(b.allowedUIDs, cred.uid) {
+		return cred, "", true
+	}
+	for _, g := range cred.gids {
+		if slices.Contains(b.allowedGIDs, g) {
+			return cred, "", true
+		}
+	}
+	return cred, fmt.Sprintf("[notice] access denied: user %d may not attach", cred.uid), false
+}
@@ -0,0 +1,37 @@
+//go:build darwin || freebsd
+
+package console
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+const peerCredSupported = true
+
+// peerCredentials reads LOCAL_PEERCRED, which getpeereid is built on, with
+// the peer's groups.
+func peerCredentials(c *net.UnixConn) (peerCred, error) {
+	raw, err := c.SyscallConn()
+	if err != nil {
+		return peerCred{}, err
+	}
+	var (
+		xucred *unix.Xucred
+		xerr   error
+	)
+	if err := raw.Control(func(fd uintptr) {
+		xucred, xerr = unix.GetsockoptXucred(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+	}); err != nil {
+		return peerCred{}, err
+	}
+	if xerr != nil {
+		return peerCred{}, xerr
+	}
+	cred := peerCred{uid: int(xucred.Uid)}
+	for _, g := range xucred.Groups[:min(int(xucred.Ngroups), len(xucred.Groups))] {
+		cred.gids = append(cred.gids, int(g))
+	}
+	return cred, nil
+}
@@ -0,0 +1,33 @@
+//go:build linux
+
+package console
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+const peerCredSupported = true
+
+// peerCredentials reads SO_PEERCRED, the credentials of the process that
+// connected.
+func peerCredentials(c *net.UnixConn) (peerCred, error) {
+	raw, err := c.SyscallConn()
+	if err != nil {
+		return peerCred{}, err
+	}
+	var (
+		ucred *unix.Ucred
+		uerr  error
+	)
+	if err := raw.Control(func(fd uintptr) {
+		ucred, uerr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	}); err != nil {
+		return peerCred{}, err
+	}
+	if uerr != nil {
+		return peerCred{}, uerr
+	}
+	return peerCred{uid: int(ucred.Uid), gids: []int{int(ucred.Gid)}}, nil
+}
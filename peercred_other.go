@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd
+
+package console
+
+import (
+	"errors"
+	"net"
+)
+
+const peerCredSupported = false
+
+func peerCredentials(*net.UnixConn) (peerCred, error) {
+	return peerCred{}, errors.New("peer credentials not supported")
+}
@@ -0,0 +1,143 @@
+package console
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// persistFlushInterval bounds how many recent lines a crash can lose.
+const persistFlushInterval = time.Second
+
+// ringStore is the append-only backing file of the broker ring. Every line
+// frame is appended, marshalled from the ring's Line; once the file holds
+// twice the ring capacity it is rewritten from the ring. It is guarded by
+// the broker's ringMu.
+type ringStore struct {
+	path     string
+	f        *os.File
+	w        *bufio.Writer
+	lines    int // frames in the file
+	capacity int
+	err      error // first write error; persistence stops after it
+}
+
+// openRingStore loads the frames saved at path, at most capacity of the
+// newest, and opens the file for appending. A missing file is empty.
+func openRingStore(path string, capacity int) (*ringStore, []ringEntry, uint64, error) {
+	entries, lastSeq, err := loadRing(path, capacity)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, nil, 0, err
+	}
+	s := &ringStore{path: path, capacity: capacity}
+	// start from a compact file holding only what was restored
+	if err := s.rewrite(entries); err != nil {
+		return nil, nil, 0, err
+	}
+	return s, entries, lastSeq, nil
+}
+
+// loadRing reads line frames from path, skipping ones that do not parse, such
+// as a final frame cut short by a crash.
+func loadRing(path string, capacity int) ([]ringEntry, uint64, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	var (
+		entries []ringEntry
+		lastSeq uint64
+	)
+	for len(data) > 0 {
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			data = nil
+		}
+		var ev Line
+		if json.Unmarshal(line, &ev) != nil || ev.Type != "line" {
+			continue
+		}
+		entries = append(entries, newRingEntry(ev, false))
+		lastSeq = max(lastSeq, ev.Seq)
+	}
+	if len(entries) > capacity {
+		entries = entries[len(entries)-capacity:]
+	}
+	return entries, lastSeq, nil
+}
+
+// append adds one frame, compacting the file to ring when it has grown to
+// twice the capacity.
+func (s *ringStore) append(buf []byte, ring func() []ringEntry) {
+	if s.err != nil {
+		return
+	}
+	if s.lines >= 2*s.capacity {
+		s.fail(s.rewrite(ring()))
+		return
+	}
+	_, err := s.w.Write(buf)
+	s.fail(err)
+	s.lines++
+}
+
+// rewrite replaces the file with entries, atomically.
+func (s *ringStore) rewrite(entries []ringEntry) error {
+	if s.f != nil {
+		_ = s.f.Close()
+		s.f = nil
+	}
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriterSize(f, 64<<10)
+	for _, e := range entries {
+		if _, err := w.Write(lineFrame(e.ev)); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	f, err = os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	s.f, s.w, s.lines = f, bufio.NewWriterSize(f, 64<<10), len(entries)
+	return nil
+}
+
+// flush writes buffered frames to the file.
+func (s *ringStore) flush() {
+	if s.err == nil && s.w != nil {
+		s.fail(s.w.Flush())
+	}
+}
+
+func (s *ringStore) fail(err error) {
+	if err != nil && s.err == nil {
+		s.err = fmt.Errorf("console broker: persist %s: %w", s.path, err)
+	}
+}
@@ -0,0 +1,416 @@
+package console
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A session file holds the frames a client received, one JSON record per
+// line with the time it arrived:
+//
+//	{"at_us":1718000000000000,"frame":{"type":"line","seq":1,...}}
+//
+// Recorder writes them and Player replays them with the original pacing.
+
+// sessionRecord is one line of a session file.
+type sessionRecord struct {
+	AtUs  int64           `json:"at_us"`
+	Frame json.RawMessage `json:"frame"`
+}
+
+// Recorder writes NDJSON frames to a session file with the time each one is
+// written. It is an io.Writer, so AttachStream(opts, rec, StreamRaw) records
+// a session without a UI; AttachOptions.RecordFile records while attached.
+type Recorder struct {
+	mu      sync.Mutex
+	w       *bufio.Writer
+	c       io.Closer
+	pending []byte
+	err     error
+}
+
+// NewRecorder returns a recorder writing to w.
+func NewRecorder(w io.Writer) *Recorder {
+	r := &Recorder{w: bufio.NewWriterSize(w, 64<<10)}
+	if c, ok := w.(io.Closer); ok {
+		r.c = c
+	}
+	return r
+}
+
+// CreateRecorder creates or truncates the session file at path.
+func CreateRecorder(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("console record: %w", err)
+	}
+	return NewRecorder(f), nil
+}
+
+// Write records every complete frame in p and keeps a partial one until the
+// next write. Fragments that are not JSON are dropped. It returns the first
+// error writing the file.
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := len(p)
+	at := time.Now().UnixMicro()
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			r.pending = append(r.pending, p...)
+			break
+		}
+		r.pending = append(r.pending, p[:i]...)
+		r.recordLocked(at, r.pending)
+		r.pending = r.pending[:0]
+		p = p[i+1:]
+	}
+	if r.err == nil {
+		r.err = r.w.Flush()
+	}
+	return n, r.err
+}
+
+// record writes one frame, as returned by frameReader.next.
+func (r *Recorder) record(frame []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recordLocked(time.Now().UnixMicro(), bytes.TrimSuffix(frame, []byte("\n")))
+	if r.err == nil {
+		r.err = r.w.Flush()
+	}
+}
+
+func (r *Recorder) recordLocked(atUs int64, frame []byte) {
+	frame = bytes.TrimSpace(frame)
+	if r.err != nil || len(frame) == 0 || !json.Valid(frame) {
+		return
+	}
+	buf := make([]byte, 0, len(frame)+40)
+	buf = append(buf, `{"at_us":`...)
+	buf = strconv.AppendInt(buf, atUs, 10)
+	buf = append(buf, `,"frame":`...)
+	buf = append(buf, frame...)
+	buf = append(buf, "}\n"...)
+	_, r.err = r.w.Write(buf)
+}
+
+// Close flushes the file and closes it if the recorder opened it or was
+// given an io.Closer.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err := r.err
+	if err == nil {
+		err = r.w.Flush()
+	}
+	if r.c != nil {
+		err = errors.Join(err, r.c.Close())
+		r.c = nil
+	}
+	return err
+}
+
+// Player replays a session file at its original pace, scaled by a speed
+// factor. Its controls are safe to use while Play runs.
+type Player struct {
+	r *bufio.Reader
+	c io.Closer
+
+	mu     sync.Mutex
+	speed  float64
+	paused bool
+	pos    time.Duration // session time played so far
+	wake   chan struct{}
+}
+
+// NewPlayer returns a player reading a session from r.
+func NewPlayer(r io.Reader) *Player {
+	p := &Player{r: bufio.NewReaderSize(r, 64<<10), speed: 1, wake: make(chan struct{}, 1)}
+	if c, ok := r.(io.Closer); ok {
+		p.c = c
+	}
+	return p
+}
+
+// OpenPlayer opens the session file at path.
+func OpenPlayer(path string) (*Player, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("console playback: %w", err)
+	}
+	return NewPlayer(f), nil
+}
+
+// Close closes the session file.
+func (p *Player) Close() error {
+	if p.c == nil {
+		return nil
+	}
+	return p.c.Close()
+}
+
+// Play calls fn with each frame, newline included, when its time comes. It
+// returns nil at the end of the session, or ctx.Err() once ctx is cancelled.
+// Records that do not parse are skipped.
+func (p *Player) Play(ctx context.Context, fn func(frame []byte)) error {
+	var base int64
+	for {
+		line, err := p.r.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("console playback: %w", err)
+		}
+		var rec sessionRecord
+		if json.Unmarshal(line, &rec) != nil || len(rec.Frame) == 0 {
+			continue
+		}
+		if base == 0 {
+			base = rec.AtUs
+		}
+		if err := p.waitUntil(ctx, time.Duration(rec.AtUs-base)*time.Microsecond); err != nil {
+			return err
+		}
+		fn(append(rec.Frame, '\n'))
+	}
+}
+
+// waitUntil blocks until the session clock reaches at.
+func (p *Player) waitUntil(ctx context.Context, at time.Duration) error {
+	for {
+		p.mu.Lock()
+		left, speed, paused := at-p.pos, p.speed, p.paused
+		p.mu.Unlock()
+		if left <= 0 {
+			return nil
+		}
+		var (
+			t     *time.Timer
+			timer <-chan time.Time
+		)
+		if !paused {
+			t = time.NewTimer(time.Duration(float64(left) / speed))
+			timer = t.C
+		}
+		start := time.Now()
+		select {
+		case <-ctx.Done():
+		case <-timer:
+		case <-p.wake:
+		}
+		if t != nil {
+			t.Stop()
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !paused {
+			p.mu.Lock()
+			p.pos += time.Duration(float64(time.Since(start)) * speed)
+			p.mu.Unlock()
+		}
+	}
+}
+
+// poke wakes a waiting Play so it sees new settings.
+func (p *Player) poke() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// SetSpeed sets the playback speed factor, 1 being real time. Values that
+// are not positive are ignored.
+func (p *Player) SetSpeed(speed float64) {
+	if speed <= 0 {
+		return
+	}
+	p.mu.Lock()
+	p.speed = speed
+	p.mu.Unlock()
+	p.poke()
+}
+
+// Speed returns the playback speed factor.
+func (p *Player) Speed() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.speed
+}
+
+// SetPaused pauses or resumes playback.
+func (p *Player) SetPaused(paused bool) {
+	p.mu.Lock()
+	p.paused = paused
+	p.mu.Unlock()
+	p.poke()
+}
+
+// Paused reports whether playback is paused.
+func (p *Player) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+// Jump skips d of session time ahead; frames in between are delivered at
+// once. A session cannot be played backwards.
+func (p *Player) Jump(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	p.mu.Lock()
+	p.pos += d
+	p.mu.Unlock()
+	p.poke()
+}
+
+// Position returns how much session time has been played.
+func (p *Player) Position() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pos
+}
+
+// playbackSpeeds are the steps < and > move through.
+var playbackSpeeds = []float64{0.25, 0.5, 1, 2, 4, 8, 16, 64}
+
+// stepPlaybackSpeedDirect moves the player one speed step up or down.
+func (u *UI) stepPlaybackSpeedDirect(dir int) {
+	p := u.player
+	cur := p.Speed()
+	next := cur
+	if dir > 0 {
+		for _, s := range playbackSpeeds {
+			if s > cur {
+				next = s
+				break
+			}
+		}
+	} else {
+		for i := len(playbackSpeeds) - 1; i >= 0; i-- {
+			if playbackSpeeds[i] < cur {
+				next = playbackSpeeds[i]
+				break
+			}
+		}
+	}
+	p.SetSpeed(next)
+	u.updateBottomBarDirect()
+}
+
+// playbackCommand runs :speed <factor> and :jump <duration>.
+func (u *UI) playbackCommand(fields []string) {
+	if u.player == nil {
+		u.setStatusMessage(u.msg("playback.unavailable"))
+		return
+	}
+	if len(fields) != 2 {
+		u.setStatusMessage(u.msg(fields[0] + ".usage"))
+		return
+	}
+	switch fields[0] {
+	case "speed":
+		speed, err := strconv.ParseFloat(strings.TrimSuffix(fields[1], "x"), 64)
+		if err != nil || speed <= 0 {
+			u.setStatusMessage(u.msg("speed.usage"))
+			return
+		}
+		u.player.SetSpeed(speed)
+	case "jump":
+		d, err := time.ParseDuration(fields[1])
+		if err != nil || d <= 0 {
+			u.setStatusMessage(u.msg("jump.usage"))
+			return
+		}
+		u.player.Jump(d)
+	}
+	u.updateBottomBarDirect()
+}
+
+// playbackBadge returns the badge text for the player's state.
+func (u *UI) playbackBadge() string {
+	p := u.player
+	pos := p.Position().Truncate(time.Second)
+	if p.Paused() {
+		return u.msg("badge.playback_paused", pos)
+	}
+	return u.msg("badge.playback", pos, strconv.FormatFloat(p.Speed(), 'g', -1, 64))
+}
+
+// playback replays opts.PlaybackFile in the UI instead of attaching.
+func playback(ctx context.Context, opts AttachOptions) error {
+	p, err := OpenPlayer(opts.PlaybackFile)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	uiOpts, local := attachUIOptions(opts.UI, opts.NoColour, opts.OnExit)
+	u := NewUI(uiOpts)
+	u.player = p
+	if opts.Transparent {
+		useTransparentStyles()
+	}
+	if opts.Title != "" {
+		u.SetTitle(opts.Title)
+	} else {
+		u.SetTitle(u.msg("title.playback", opts.PlaybackFile))
+	}
+
+	playCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		// the badge shows the session clock
+		tick := time.NewTicker(time.Second)
+		defer tick.Stop()
+		for {
+			select {
+			case <-playCtx.Done():
+				return
+			case <-tick.C:
+				u.Do(u.updateBottomBarDirect)
+			}
+		}
+	}()
+	go func() {
+		fr := &frameReader{}
+		feed := frameFeeder{u: u, local: local, lineTime: recordedLineTime}
+		err := p.Play(playCtx, func(b []byte) { feed.frame(fr, b) })
+		switch {
+		case playCtx.Err() != nil:
+		case err != nil:
+			u.Append(u.msg("notice.read_error", err))
+		default:
+			u.Append(u.msg("notice.playback_end", feed.lines))
+		}
+	}()
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				u.app.Stop()
+			case <-playCtx.Done():
+			}
+		}()
+	}
+
+	if err := u.Run(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
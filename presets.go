@@ -0,0 +1,97 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// FilterPreset is a named filter selected with Alt+1–9 in the log view, in
+// the order presets are listed.
+type FilterPreset struct {
+	Name string `json:"name"`
+	// Filter is the filter text as typed in the input field; empty leaves
+	// the buffer unfiltered.
+	Filter string `json:"filter"`
+	// Regex treats Filter as a regular expression.
+	Regex bool `json:"regex,omitempty"`
+	// Levels shows only lines of these level classes: "error", "warn",
+	// "info" or "debug". Empty shows every level.
+	Levels []string `json:"levels,omitempty"`
+}
+
+// maxPresets is how many presets the Alt+number keys reach.
+const maxPresets = 9
+
+// presetKey returns the preset bound to ev, an Alt+number key. The plain
+// number keys stay with the level toggles.
+func (u *UI) presetKey(ev *tcell.EventKey) (FilterPreset, bool) {
+	if ev.Key() != tcell.KeyRune || ev.Modifiers()&tcell.ModAlt == 0 {
+		return FilterPreset{}, false
+	}
+	i := int(ev.Rune() - '1')
+	if i < 0 || i >= min(len(u.presets), maxPresets) {
+		return FilterPreset{}, false
+	}
+	return u.presets[i], true
+}
+
+// applyPresetDirect sets the filter, regex mode and shown levels of p, or
+// clears them when p is already the active preset.
+func (u *UI) applyPresetDirect(p FilterPreset) {
+	u.mu.Lock()
+	off := u.preset == p.Name
+	clear(u.hiddenLevels)
+	if off {
+		u.filter, u.filterActive = "", false
+	} else {
+		u.filter, u.filterActive = p.Filter, p.Filter != ""
+		u.filterRegex = p.Regex
+		if len(p.Levels) > 0 {
+			for _, l := range levels {
+				u.hiddenLevels[l] = true
+			}
+			for _, l := range p.Levels {
+				delete(u.hiddenLevels, LevelClass(l))
+			}
+		}
+	}
+	filter := u.filter
+	u.mu.Unlock()
+	u.inputField.SetText(filter)
+	u.mu.Lock()
+	u.preset = ""
+	if !off {
+		u.preset = p.Name
+	}
+	u.mu.Unlock()
+	u.refreshDirect()
+	u.notifyFilterChange()
+}
+
+// clearPreset forgets the active preset once the filter or levels are
+// changed by hand.
+func (u *UI) clearPreset() {
+	u.mu.Lock()
+	u.preset = ""
+	u.mu.Unlock()
+}
+
+// presetsHelp returns the help section listing the presets, or "" if there
+// are none.
+func (u *UI) presetsHelp() string {
+	if len(u.presets) == 0 {
+		return ""
+	}
+	lines := []string{u.msg("help.presets")}
+	for i, p := range u.presets[:min(len(u.presets), maxPresets)] {
+		desc := p.Filter
+		if len(p.Levels) > 0 {
+			desc = strings.TrimSpace("level=" + strings.Join(p.Levels, ",") + " " + desc)
+		}
+		lines = append(lines, fmt.Sprintf("  Alt+%d  %-16s %s", i+1, p.Name, tview.Escape(desc)))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,76 @@
+// Package promconsole reports a console broker's activity to Prometheus.
+package promconsole
+
+import (
+	"strconv"
+
+	console "github.com/network-plane/planeconsole"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	metricClients = prometheus.NewDesc("planeconsole_clients",
+		"Clients attached to the broker.", nil, nil)
+	metricLines = prometheus.NewDesc("planeconsole_lines_total",
+		"Lines appended since the broker started.", nil, nil)
+	metricSentBytes = prometheus.NewDesc("planeconsole_sent_bytes_total",
+		"Bytes of line frames queued to clients.", nil, nil)
+	metricDropped = prometheus.NewDesc("planeconsole_client_dropped_lines_total",
+		"Lines dropped for a client that fell behind.", []string{"client", "remote_addr"}, nil)
+	metricCounter = prometheus.NewDesc("planeconsole_counter",
+		"Matches of a registered counter within its rolling window.", []string{"label"}, nil)
+	metricCounterTop = prometheus.NewDesc("planeconsole_counter_top",
+		"Matches within the rolling window for the busiest captured values of a grouping counter.", []string{"label", "value"}, nil)
+	metricCounterTotal = prometheus.NewDesc("planeconsole_counter_matches_total",
+		"Matches of a registered counter since the broker started.", []string{"label"}, nil)
+)
+
+// collector reports a broker's activity to Prometheus.
+type collector struct {
+	b *console.Broker
+}
+
+// Collector returns a prometheus.Collector reporting attached clients, lines
+// appended, bytes sent, per-client drops and the value of each counter in the
+// broker's Config:
+//
+//	prometheus.MustRegister(promconsole.Collector(b))
+func Collector(b *console.Broker) prometheus.Collector {
+	return collector{b: b}
+}
+
+func (c collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- metricClients
+	ch <- metricLines
+	ch <- metricSentBytes
+	ch <- metricDropped
+	ch <- metricCounter
+	ch <- metricCounterTotal
+	ch <- metricCounterTop
+}
+
+func (c collector) Collect(ch chan<- prometheus.Metric) {
+	st := c.b.Stats()
+	ch <- prometheus.MustNewConstMetric(metricClients, prometheus.GaugeValue, float64(len(st.Clients)))
+	ch <- prometheus.MustNewConstMetric(metricLines, prometheus.CounterValue, float64(st.Lines))
+	ch <- prometheus.MustNewConstMetric(metricSentBytes, prometheus.CounterValue, float64(st.SentBytes))
+	for _, cs := range st.Clients {
+		ch <- prometheus.MustNewConstMetric(metricDropped, prometheus.CounterValue, float64(cs.Dropped),
+			strconv.FormatUint(cs.ID, 10), cs.RemoteAddr)
+	}
+
+	samples := c.b.CounterSamples()
+	seen := make(map[string]bool, len(samples))
+	for _, cs := range samples {
+		// a repeated label would fail the whole scrape
+		if cs.Label == "" || seen[cs.Label] {
+			continue
+		}
+		seen[cs.Label] = true
+		ch <- prometheus.MustNewConstMetric(metricCounter, prometheus.GaugeValue, float64(cs.Count), cs.Label)
+		ch <- prometheus.MustNewConstMetric(metricCounterTotal, prometheus.CounterValue, float64(cs.Total), cs.Label)
+		for _, kc := range cs.Top {
+			ch <- prometheus.MustNewConstMetric(metricCounterTop, prometheus.GaugeValue, float64(kc.Count), cs.Label, kc.Key)
+		}
+	}
+}
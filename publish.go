@@ -0,0 +1,60 @@
+package console
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Publisher appends lines to a remote broker with publish frames. The broker
+// must allow it through BrokerOptions.AuthorizePublish.
+type Publisher struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	source string
+}
+
+// DialPublisher connects to the broker at addr, a UNIX socket path or TCP
+// address as accepted by AttachOptions.Socket. Lines are tagged with source;
+// if empty, the broker names the connection.
+func DialPublisher(ctx context.Context, addr, source string) (*Publisher, error) {
+	conn, err := dialConsole(ctx, addr)
+	if err != nil {
+		return nil, fmt.Errorf("console publish: %w", err)
+	}
+	// the broker streams the console to every connection; drain it so the
+	// broker never waits on us
+	go func() { _, _ = io.Copy(io.Discard, conn) }()
+	return &Publisher{conn: conn, source: source}, nil
+}
+
+// Publish appends a line timestamped now.
+func (p *Publisher) Publish(line string) error {
+	return p.PublishAt(time.Now(), line)
+}
+
+// Publishf appends a formatted line timestamped now.
+func (p *Publisher) Publishf(format string, args ...any) error {
+	return p.Publish(fmt.Sprintf(format, args...))
+}
+
+// PublishAt appends a line with an explicit timestamp.
+func (p *Publisher) PublishAt(when time.Time, line string) error {
+	buf, err := json.Marshal(Publish{Type: "publish", Text: truncateLineText(line), TsUs: when.UnixMicro(), Source: p.source})
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err = p.conn.Write(append(buf, '\n'))
+	return err
+}
+
+// Close closes the connection.
+func (p *Publisher) Close() error {
+	return p.conn.Close()
+}
@@ -0,0 +1,130 @@
+package console
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LineQuery selects lines from the broker's ring for Broker.Lines and the
+// HTTPBridge's /lines endpoint.
+type LineQuery struct {
+	// SinceUs, if set, keeps lines stamped after it.
+	SinceUs int64
+	// Match is a filter as typed in the UI, such as "OFFER && -keepalive",
+	// matched against the line text with its "[source] " prefix.
+	Match         string
+	CaseSensitive bool
+	Regex         bool
+	// Levels keeps lines of these level classes: "error", "warn", "info"
+	// or "debug". Empty keeps every level.
+	Levels []string
+	// Limit is how many of the newest matching lines are returned; default
+	// and at most 2000.
+	Limit int
+}
+
+// Lines returns the newest buffered lines matching q, oldest first, and
+// whether older matching lines were left out.
+func (b *Broker) Lines(q LineQuery) ([]Line, bool) {
+	limit := q.Limit
+	if limit <= 0 || limit > maxHistoryLines {
+		limit = maxHistoryLines
+	}
+	var filter *filterExpr
+	if e, ok := parseFilter(q.Match, q.CaseSensitive, q.Regex); ok {
+		filter = &e
+	}
+	levels := make([]string, len(q.Levels))
+	for i, l := range q.Levels {
+		levels[i] = LevelClass(l)
+	}
+
+	b.ringMu.Lock()
+	defer b.ringMu.Unlock()
+	var out []Line
+	more := false
+	// walk newest to oldest
+	for i := 1; i <= b.count; i++ {
+		e := b.ring[(b.head-i+b.capacity)%b.capacity]
+		if e.ev.TsUs <= q.SinceUs || !filter.pass(e.text, time.UnixMicro(e.ev.TsUs)) {
+			continue
+		}
+		if len(levels) > 0 && !slices.Contains(levels, lineLevel(e.ev)) {
+			continue
+		}
+		if len(out) == limit {
+			more = true
+			break
+		}
+		ev := e.ev
+		ev.Fields, ev.Spans = maps.Clone(ev.Fields), slices.Clone(ev.Spans)
+		out = append(out, ev)
+	}
+	slices.Reverse(out)
+	return out, more
+}
+
+// serveLines answers GET /lines with a History frame of the lines matching
+// the query parameters since, a duration back from now such as "10m", an
+// RFC 3339 time or microseconds since the epoch; match, a filter as typed
+// in the UI; level, a comma-separated list of level classes; and limit.
+//
+//	curl 'http://localhost:8080/lines?since=15m&level=error,warn&match=OFFER'
+func (h *HTTPBridge) serveLines(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.authorize(w, r) {
+		return
+	}
+	params := r.URL.Query()
+	q := LineQuery{Match: params.Get("match")}
+	var err error
+	if q.SinceUs, err = parseSince(params.Get("since"), time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s := params.Get("limit"); s != "" {
+		if q.Limit, err = strconv.Atoi(s); err != nil || q.Limit < 0 {
+			http.Error(w, fmt.Sprintf("invalid limit %q", s), http.StatusBadRequest)
+			return
+		}
+	}
+	for l := range strings.SplitSeq(params.Get("level"), ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			q.Levels = append(q.Levels, l)
+		}
+	}
+
+	lines, more := h.b.Lines(q)
+	if lines == nil {
+		lines = []Line{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(History{Type: "history", More: more, Lines: lines})
+}
+
+// parseSince parses the since parameter of /lines into a timestamp in
+// microseconds; empty means the start of the ring.
+func parseSince(s string, now time.Time) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d).UnixMicro(), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.UnixMicro(), nil
+	}
+	if us, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return us, nil
+	}
+	return 0, fmt.Errorf("invalid since %q: use a duration such as 10m, an RFC 3339 time or microseconds", s)
+}
@@ -0,0 +1,86 @@
+package console
+
+import "strings"
+
+// Rendering is incremental where possible: appended lines that extend the
+// view are written below it, and lines trimmed from the buffer stay in the
+// view as stale rows at the top until enough accumulate to repaint. Filter,
+// highlight, order and mode changes repaint everything.
+
+// minStaleRows is the least number of trimmed rows kept in the view before a
+// full repaint; larger buffers allow an eighth of their size.
+const minStaleRows = 256
+
+// incrementalLocked reports whether new lines can be appended to the view
+// without a repaint. Callers hold u.mu.
+func (u *UI) incrementalLocked() bool {
+	return !u.needFull && !u.paused && !u.newestFirst && !u.diffMode
+}
+
+// dropShownLocked accounts for trimming the group ord: its rows become stale
+// in the view, or are dropped if not yet rendered. Callers hold u.mu.
+func (u *UI) dropShownLocked(ord uint64) {
+	for u.stale < len(u.shownRows) && u.shownRows[u.stale].ord == ord {
+		u.stale++
+	}
+	for len(u.pendingRows) > 0 && u.pendingRows[0].key.ord == ord {
+		u.pendingRows = u.pendingRows[1:]
+	}
+}
+
+// staleRows returns how many rows at the top of the view show trimmed lines.
+// View row r shows displayRows()[r-staleRows()].
+func (u *UI) staleRows() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.stale
+}
+
+// invalidateRows forces the next render to repaint every row.
+func (u *UI) invalidateRows() {
+	u.mu.Lock()
+	u.needFull = true
+	u.mu.Unlock()
+}
+
+// renderPendingDirect writes rows appended since the last render, or repaints
+// the view if that is required or too many stale rows have built up.
+func (u *UI) renderPendingDirect() {
+	u.mu.Lock()
+	// selection marks depend on rows around the new ones
+	full := u.needFull || u.selecting || u.stale > max(minStaleRows, u.maxLines/8)
+	rows := u.pendingRows
+	u.pendingRows = nil
+	u.mu.Unlock()
+	if full {
+		u.repaintLogDirect()
+		return
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	follow := u.following(false)
+	mark := u.filterMarker()
+	u.mu.Lock()
+	prev := u.tsPrev
+	u.mu.Unlock()
+	stamps, last := u.tsPrefixes(rows, prev)
+	var b strings.Builder
+	keys := make([]rowKey, len(rows))
+	for i, r := range rows {
+		keys[i] = r.key
+		if stamps != nil {
+			b.WriteString(stamps[i])
+		}
+		b.WriteString(u.idPrefix(r.seq) + mark(u.styleRow(r.level, r.source, r.text)) + "\n")
+	}
+	_, _ = u.logView.Write([]byte(b.String()))
+	u.mu.Lock()
+	u.shownRows = append(u.shownRows, keys...)
+	u.tsPrev = last
+	u.mu.Unlock()
+	if follow {
+		u.followEndDirect()
+	}
+}
@@ -0,0 +1,126 @@
+package console
+
+import "strings"
+
+// Search marks matches in place without hiding other lines. It is started
+// with / from the log view, typed into the input line, and stepped through
+// with n and N.
+
+// setSearchDirect sets the search pattern, or clears it when pattern is
+// empty, and jumps to the first match from the top of the viewport.
+func (u *UI) setSearchDirect(pattern string) {
+	u.mu.Lock()
+	u.searchText = pattern
+	if pattern == "" {
+		u.search = nil
+	} else {
+		// smart case, as there is no separate case toggle for searching
+		caseOn := strings.ToLower(pattern) != pattern
+		m, err := newTextMatcher(pattern, caseOn, u.filterRegex)
+		if err != nil {
+			m, _ = newTextMatcher(pattern, caseOn, false)
+		}
+		u.search = m
+	}
+	u.mu.Unlock()
+	u.repaintLogDirect()
+	u.app.SetFocus(u.logView)
+	u.setLogSeparators(true)
+	if pattern != "" {
+		u.searchStepDirect(0)
+	}
+}
+
+// searchStepDirect scrolls to the next match below the top of the viewport
+// for dir > 0, the previous one for dir < 0, or the first one at or below it
+// for 0, wrapping around the buffer, and reports the position.
+func (u *UI) searchStepDirect(dir int) {
+	u.mu.Lock()
+	m, pattern := u.search, u.searchText
+	u.mu.Unlock()
+	if m == nil {
+		u.setStatusMessage(u.msg("search.none"))
+		return
+	}
+	rows := u.displayRows()
+	var hits []int
+	for i, r := range rows {
+		if m.match(r.text) {
+			hits = append(hits, i)
+		}
+	}
+	if len(hits) == 0 {
+		u.setStatusMessage(u.msg("search.no_match", pattern))
+		return
+	}
+
+	if u.rowsWrapped() {
+		u.setStatusMessage(u.msg("wrap.rows", "search"))
+		return
+	}
+	stale := u.staleRows()
+	row, col := u.logView.GetScrollOffset()
+	top := max(row-stale, 0)
+	// step from the last match while it is on screen; near the end of the
+	// buffer the view cannot scroll it to the top
+	_, _, _, height := u.logView.GetInnerRect()
+	u.mu.Lock()
+	if last := u.searchRow; dir != 0 && last >= top && last < top+height {
+		top = last
+	}
+	u.mu.Unlock()
+	k := -1
+	switch {
+	case dir < 0:
+		for i := len(hits) - 1; i >= 0; i-- {
+			if hits[i] < top {
+				k = i
+				break
+			}
+		}
+		if k < 0 {
+			k = len(hits) - 1
+		}
+	default:
+		for i, h := range hits {
+			if h > top || (dir == 0 && h == top) {
+				k = i
+				break
+			}
+		}
+		if k < 0 {
+			k = 0
+		}
+	}
+	u.mu.Lock()
+	u.searchRow = hits[k]
+	u.mu.Unlock()
+	u.logView.ScrollTo(hits[k]+stale, col)
+	u.setStatusMessage(u.msg("search.position", k+1, len(hits)))
+}
+
+// endSearchInput puts the filter text back in the input line once a search
+// has been typed.
+func (u *UI) endSearchInput() {
+	u.mu.Lock()
+	filter := u.filter
+	if !u.filterActive {
+		filter = ""
+	}
+	u.mu.Unlock()
+	u.inputField.SetText(filter)
+}
+
+// searchMarker returns a function that marks search matches in an already
+// styled line.
+func (u *UI) searchMarker() func(string) string {
+	u.mu.Lock()
+	m := u.search
+	u.mu.Unlock()
+	if u.noColour || m == nil {
+		return func(s string) string { return s }
+	}
+	return func(s string) string {
+		return markVisibleWith(s, m, "[::ub]", "[::UB]")
+	}
+}
@@ -0,0 +1,192 @@
+package console
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Rows are selected with Shift+Up/Down or by dragging with the mouse, and
+// copied with y through the terminal (OSC 52), which reaches the local
+// clipboard even over SSH.
+
+// wholeText matches all of a row's visible text, to mark a selected row.
+var wholeText = &textMatcher{re: regexp.MustCompile(".+")}
+
+// selectionRangeLocked returns the first and last index in keys of the
+// selected rows, reporting false when nothing is selected or an end of the
+// selection is no longer shown. Callers hold u.mu.
+func (u *UI) selectionRangeLocked(keys []rowKey) (from, to int, ok bool) {
+	if !u.selecting {
+		return 0, 0, false
+	}
+	from, to = slices.Index(keys, u.selAnchor), slices.Index(keys, u.selCursor)
+	if from < 0 || to < 0 {
+		return 0, 0, false
+	}
+	return min(from, to), max(from, to), true
+}
+
+// selectionMarker returns a function marking a selected row in the
+// palette's Selection style, reverse video by default.
+func (u *UI) selectionMarker() func(string) string {
+	if u.noColour {
+		return func(s string) string { return "> " + s }
+	}
+	open, close := "[::r]", "[::R]"
+	if st := u.currentPalette().Selection; st != (Style{}) {
+		open, close = "["+st.FG+":"+st.BG+":"+st.Attrs+"]", "[-:-:-]"
+	}
+	return func(s string) string { return markVisibleWith(s, wholeText, open, close) }
+}
+
+// extendSelectionDirect moves the selection's free end by step rows,
+// starting a selection at the current row if there is none.
+func (u *UI) extendSelectionDirect(step int) {
+	if u.rowsWrapped() {
+		u.setStatusMessage(u.msg("wrap.rows", "select"))
+		return
+	}
+	u.mu.Lock()
+	shown, stale, newestFirst := u.shownRows, u.stale, u.newestFirst
+	selecting, cursor := u.selecting, u.selCursor
+	u.mu.Unlock()
+	if len(shown) <= stale {
+		return
+	}
+	top, col := u.logView.GetScrollOffset()
+	idx := slices.Index(shown, cursor)
+	switch {
+	case !selecting || idx < 0:
+		idx = top
+		if u.following(newestFirst) && !newestFirst {
+			idx = len(shown) - 1
+		}
+		idx = min(max(idx, stale), len(shown)-1)
+		u.mu.Lock()
+		u.selecting, u.selAnchor = true, shown[idx]
+		u.mu.Unlock()
+	default:
+		idx = min(max(idx+step, stale), len(shown)-1)
+	}
+	u.mu.Lock()
+	u.selCursor = shown[idx]
+	u.mu.Unlock()
+	u.repaintLogDirect()
+
+	// keep the moving end in view
+	_, _, _, h := u.logView.GetInnerRect()
+	top, _ = u.logView.GetScrollOffset()
+	switch {
+	case idx < top:
+		u.logView.ScrollTo(idx, col)
+	case h > 0 && idx >= top+h:
+		u.logView.ScrollTo(idx-h+1, col)
+	}
+}
+
+// clearSelectionDirect drops the selection, reporting false if there was
+// none.
+func (u *UI) clearSelectionDirect() bool {
+	u.mu.Lock()
+	was := u.selecting
+	u.selecting = false
+	u.mu.Unlock()
+	if was {
+		u.repaintLogDirect()
+	}
+	return was
+}
+
+// copySelectionDirect puts the selected rows' text on the clipboard through
+// the terminal and ends the selection.
+func (u *UI) copySelectionDirect() {
+	rows := u.displayRows()
+	u.mu.Lock()
+	keys := make([]rowKey, len(rows))
+	for i, r := range rows {
+		keys[i] = r.key
+	}
+	from, to, ok := u.selectionRangeLocked(keys)
+	screen, limit := u.screen, u.copyMaxBytes
+	u.mu.Unlock()
+	if !ok {
+		u.setStatusMessage(u.msg("select.none"))
+		return
+	}
+	var b strings.Builder
+	for _, r := range rows[from : to+1] {
+		b.WriteString(stripMarkup(r.text) + "\n")
+	}
+	switch {
+	case screen == nil:
+		return // not drawn yet
+	case b.Len() > limit:
+		u.setStatusMessage(u.msg("select.too_large", limit))
+		return
+	}
+	screen.SetClipboard([]byte(b.String()))
+	u.clearSelectionDirect()
+	u.setStatusMessage(u.msg("select.copied", to-from+1))
+}
+
+// selectMouse selects rows by dragging with the left button; Shift+click
+// moves the end of an existing selection.
+func (u *UI) selectMouse(action tview.MouseAction, ev *tcell.EventMouse) (tview.MouseAction, *tcell.EventMouse) {
+	x, y := ev.Position()
+	if u.rowsWrapped() || !u.logView.InRect(x, y) && action != tview.MouseMove {
+		return action, ev
+	}
+	_, ry, _, _ := u.logView.GetInnerRect()
+	top, _ := u.logView.GetScrollOffset()
+	row := top + y - ry
+	u.mu.Lock()
+	var key rowKey
+	onRow := row >= u.stale && row < len(u.shownRows)
+	if onRow {
+		key = u.shownRows[row]
+	}
+	u.mu.Unlock()
+
+	switch {
+	case action == tview.MouseLeftDown && ev.Modifiers()&tcell.ModShift != 0:
+		u.mu.Lock()
+		extend := u.selecting && onRow
+		if extend {
+			u.selCursor = key
+		}
+		u.mu.Unlock()
+		if extend {
+			u.repaintLogDirect()
+			return tview.MouseConsumed, nil
+		}
+	case action == tview.MouseLeftDown:
+		u.clearSelectionDirect()
+		u.mu.Lock()
+		u.dragFrom, u.dragging = key, onRow
+		u.mu.Unlock()
+	case action == tview.MouseMove && ev.Buttons()&tcell.Button1 != 0:
+		u.mu.Lock()
+		changed := u.dragging && onRow && (u.selecting || key != u.dragFrom) && key != u.selCursor
+		if changed {
+			u.selecting, u.selAnchor, u.selCursor = true, u.dragFrom, key
+		}
+		u.mu.Unlock()
+		if changed {
+			u.repaintLogDirect()
+		}
+		return tview.MouseConsumed, nil
+	case action == tview.MouseLeftUp:
+		u.mu.Lock()
+		u.dragging = false
+		u.mu.Unlock()
+	case action == tview.MouseScrollUp:
+		u.scrolledDirect(true, false)
+	case action == tview.MouseScrollDown:
+		u.scrolledDirect(false, false)
+	}
+	return action, ev
+}
@@ -0,0 +1,157 @@
+package console
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultFileSinkMaxBytes and DefaultFileSinkMaxFiles are the FileSinkOptions
+// defaults.
+const (
+	DefaultFileSinkMaxBytes = 100 << 20
+	DefaultFileSinkMaxFiles = 10
+)
+
+// rotatedLayout stamps rotated sink files, sorting oldest first.
+const rotatedLayout = "20060102T150405.000"
+
+// FileSinkOptions configures BrokerOptions.FileSink.
+type FileSinkOptions struct {
+	// Path is the file every appended line is written to as an NDJSON line
+	// frame, as sent to clients. When it reaches MaxBytes it is renamed
+	// with the time inserted before its extension, e.g.
+	// "dhcp-20261016T142200.000.ndjson", and a new file is started.
+	Path string
+	// MaxBytes is the size at which Path is rotated; default
+	// DefaultFileSinkMaxBytes.
+	MaxBytes int64
+	// MaxFiles is how many rotated files are kept; default
+	// DefaultFileSinkMaxFiles. MaxAge, if set, also removes rotated files
+	// older than that.
+	MaxFiles int
+	MaxAge   time.Duration
+}
+
+// fileSink writes line frames to rotated files. It is guarded by the
+// broker's ringMu.
+type fileSink struct {
+	opts FileSinkOptions
+	f    *os.File
+	w    *bufio.Writer
+	size int64
+	err  error // first error; writing stops after it
+}
+
+func openFileSink(opts FileSinkOptions) (*fileSink, error) {
+	if opts.Path == "" {
+		return nil, fmt.Errorf("console broker: file sink: no path")
+	}
+	opts.MaxBytes = cmp.Or(opts.MaxBytes, DefaultFileSinkMaxBytes)
+	opts.MaxFiles = cmp.Or(opts.MaxFiles, DefaultFileSinkMaxFiles)
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("console broker: file sink: %w", err)
+	}
+	s := &fileSink{opts: opts}
+	if err := s.open(); err != nil {
+		return nil, fmt.Errorf("console broker: file sink: %w", err)
+	}
+	return s, nil
+}
+
+// open appends to Path, creating it if needed.
+func (s *fileSink) open() error {
+	f, err := os.OpenFile(s.opts.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	s.f, s.w, s.size = f, bufio.NewWriterSize(f, 64<<10), st.Size()
+	return nil
+}
+
+// write adds one frame, rotating first if it would take the file past
+// MaxBytes.
+func (s *fileSink) write(buf []byte) {
+	if s.err != nil {
+		return
+	}
+	if s.size > 0 && s.size+int64(len(buf)) > s.opts.MaxBytes {
+		if s.fail(s.rotate()) {
+			return
+		}
+	}
+	n, err := s.w.Write(buf)
+	s.size += int64(n)
+	s.fail(err)
+}
+
+// rotate renames the full file aside, starts a new one and removes rotated
+// files past the retention limits.
+func (s *fileSink) rotate() error {
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+	if err := s.f.Close(); err != nil {
+		return err
+	}
+	ext := filepath.Ext(s.opts.Path)
+	stem := strings.TrimSuffix(s.opts.Path, ext)
+	if err := os.Rename(s.opts.Path, stem+"-"+time.Now().Format(rotatedLayout)+ext); err != nil {
+		return err
+	}
+	if err := s.open(); err != nil {
+		return err
+	}
+	s.prune(stem, ext)
+	return nil
+}
+
+// prune removes the oldest rotated files beyond MaxFiles and those older
+// than MaxAge. Failures are left for the next rotation.
+func (s *fileSink) prune(stem, ext string) {
+	names, _ := filepath.Glob(stem + "-*" + ext)
+	var rotated []string
+	for _, name := range names {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, stem+"-"), ext)
+		if _, err := time.Parse(rotatedLayout, stamp); err == nil {
+			rotated = append(rotated, name)
+		}
+	}
+	slices.Sort(rotated)
+	for i, name := range rotated {
+		old := i < len(rotated)-s.opts.MaxFiles
+		if !old && s.opts.MaxAge > 0 {
+			if st, err := os.Stat(name); err == nil && time.Since(st.ModTime()) > s.opts.MaxAge {
+				old = true
+			}
+		}
+		if old {
+			_ = os.Remove(name)
+		}
+	}
+}
+
+// flush writes buffered frames to the file.
+func (s *fileSink) flush() {
+	if s.err == nil {
+		s.fail(s.w.Flush())
+	}
+}
+
+// fail records err, if any, reporting whether there was one.
+func (s *fileSink) fail(err error) bool {
+	if err != nil && s.err == nil {
+		s.err = fmt.Errorf("console broker: file sink %s: %w", s.opts.Path, err)
+	}
+	return err != nil
+}
@@ -0,0 +1,74 @@
+package console
+
+import (
+	"sync"
+	"time"
+)
+
+// skewTolerance is the clock difference below which server timestamps are used
+// as-is; smaller offsets are indistinguishable from transport latency.
+const skewTolerance = time.Second
+
+// clockSkew estimates how far the viewer clock is ahead of the server clock.
+//
+// Each observation is clientNow - serverTs, which is the true offset plus the
+// (non-negative) delivery latency, so the minimum observation is the best
+// estimate. Estimation starts from the meta frame's server time; line
+// timestamps only refine it, because replayed lines are arbitrarily old.
+type clockSkew struct {
+	mu     sync.Mutex
+	known  bool
+	offset time.Duration
+}
+
+// reset starts a new estimate from a meta frame sent at serverUs.
+func (cs *clockSkew) reset(serverUs int64, now time.Time) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if serverUs <= 0 {
+		cs.known = false
+		cs.offset = 0
+		return
+	}
+	cs.known = true
+	cs.offset = now.Sub(time.UnixMicro(serverUs))
+}
+
+// observe refines the estimate with a line stamped at serverUs.
+func (cs *clockSkew) observe(serverUs int64, now time.Time) {
+	if serverUs <= 0 {
+		return
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if !cs.known {
+		return
+	}
+	if off := now.Sub(time.UnixMicro(serverUs)); off < cs.offset {
+		cs.offset = off
+	}
+}
+
+// Offset returns the current estimate and whether it exceeds skewTolerance.
+func (cs *clockSkew) Offset() (time.Duration, bool) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if !cs.known {
+		return 0, false
+	}
+	return cs.offset, cs.offset >= skewTolerance || cs.offset <= -skewTolerance
+}
+
+// lineTime converts a server timestamp to viewer time, or returns the zero
+// time when the line carries none so the UI can derive one from its text.
+func (cs *clockSkew) lineTime(ev Line, now time.Time) time.Time {
+	if ev.TsUs <= 0 {
+		return time.Time{}
+	}
+	cs.observe(ev.TsUs, now)
+	ts := time.UnixMicro(ev.TsUs)
+	if off, significant := cs.Offset(); significant {
+		return ts.Add(off)
+	}
+	return ts
+}
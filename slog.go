@@ -0,0 +1,177 @@
+package console
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SlogOptions configure a handler created by NewSlogHandler.
+type SlogOptions struct {
+	// Level is the minimum level logged; nil means slog.LevelInfo.
+	Level slog.Leveler
+	// AddSource appends the caller's file:line to each line.
+	AddSource bool
+	// Fields sends attributes as Line.Fields instead of key=value text, so
+	// viewers can show them as columns and filter on them.
+	Fields bool
+	// Channel is the channel lines are appended to.
+	Channel string
+}
+
+// SlogHandler is a slog.Handler that appends records to a Broker. Records
+// carry their level as Line.Level and read like "ERROR: message
+// key=value ...", so counters matching the prefix apply too.
+type SlogHandler struct {
+	b      *Broker
+	opts   SlogOptions
+	attrs  []slog.Attr // from WithAttrs, keys already qualified
+	prefix string      // group prefix for later attributes, "a.b."
+}
+
+// NewSlogHandler returns a handler appending to b. opts may be nil.
+func NewSlogHandler(b *Broker, opts *SlogOptions) *SlogHandler {
+	h := &SlogHandler{b: b}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+// Enabled reports whether level is at or above the configured minimum.
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+// Handle formats r and appends it to the broker.
+func (h *SlogHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(slogLevelPrefix(r.Level))
+	b.WriteString(r.Message)
+
+	fields := make(map[string]string)
+	add := func(key, value string) {
+		if h.opts.Fields {
+			fields[key] = value
+			return
+		}
+		b.WriteString(" " + key + "=" + quoteValue(value))
+	}
+	for _, a := range h.attrs {
+		appendAttr(add, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		appendAttr(add, h.prefix, a)
+		return true
+	})
+	if h.opts.AddSource && r.PC != 0 {
+		f, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		add(slog.SourceKey, f.File+":"+strconv.Itoa(f.Line))
+	}
+
+	when := r.Time
+	if when.IsZero() {
+		when = time.Now()
+	}
+	ev := Line{Type: "line", TsUs: when.UnixMicro(), Text: b.String(), Level: slogLevel(r.Level), Channel: h.opts.Channel}
+	if len(fields) > 0 {
+		ev.Fields = fields
+	}
+	h.b.submit(ev)
+	return nil
+}
+
+// WithAttrs returns a handler that adds attrs to every record.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		a.Key = h.prefix + a.Key
+		h2.attrs = append(h2.attrs, a)
+	}
+	return &h2
+}
+
+// WithGroup returns a handler that qualifies later attribute keys with name.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// slogLevel returns the level class of level.
+func slogLevel(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "error"
+	case level >= slog.LevelWarn:
+		return "warn"
+	case level >= slog.LevelInfo:
+		return "info"
+	}
+	return "debug"
+}
+
+// slogLevelPrefix returns the line prefix for level; errors use the
+// "ERROR: " prefix LevelOf recognises.
+func slogLevelPrefix(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "ERROR: "
+	case level >= slog.LevelWarn:
+		return "WARN: "
+	case level >= slog.LevelInfo:
+		return "INFO: "
+	}
+	return "DEBUG: "
+}
+
+// appendAttr flattens a, resolving LogValuers and expanding groups into
+// dotted keys.
+func appendAttr(add func(key, value string), prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		group := a.Value.Group()
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range group {
+			appendAttr(add, prefix, ga)
+		}
+		return
+	}
+	var v string
+	switch a.Value.Kind() {
+	case slog.KindTime:
+		v = a.Value.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		v = fmt.Sprint(a.Value.Any())
+	default:
+		v = a.Value.String()
+	}
+	add(prefix+a.Key, v)
+}
+
+// quoteValue quotes values that would not read back as one token.
+func quoteValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+var _ slog.Handler = (*SlogHandler)(nil)
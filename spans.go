@@ -0,0 +1,68 @@
+package console
+
+import "sort"
+
+// Span styles Text[Start:End] of a line (byte offsets).
+type Span struct {
+	Start int   `json:"start"`
+	End   int   `json:"end"`
+	Style Style `json:"style"`
+}
+
+// HighlightSpans evaluates highlight rules against text and returns the
+// resulting non-overlapping spans sorted by Start. Rules are applied in order
+// and earlier rules win where matches overlap. Rules without a style are
+// skipped.
+func HighlightSpans(text string, rules []HighlightSpec) []Span {
+	return spansOf(text, compileSpanRules(rules))
+}
+
+// spanRule is a highlight rule compiled once for spansOf.
+type spanRule struct {
+	matcher *textMatcher
+	style   Style
+}
+
+// compileSpanRules compiles the rules HighlightSpans applies, skipping
+// those without a match or style and those that do not compile.
+func compileSpanRules(rules []HighlightSpec) []spanRule {
+	var out []spanRule
+	for _, r := range rules {
+		if r.Match == "" || r.Style == nil {
+			continue
+		}
+		m, err := newTextMatcher(r.Match, r.CaseSensitive, r.Regex)
+		if err != nil {
+			continue
+		}
+		out = append(out, spanRule{m, *r.Style})
+	}
+	return out
+}
+
+// spansOf is HighlightSpans with the rules already compiled.
+func spansOf(text string, rules []spanRule) []Span {
+	if text == "" || len(rules) == 0 {
+		return nil
+	}
+	var spans []Span
+	for _, r := range rules {
+		for _, loc := range r.matcher.findAll(text) {
+			sp := Span{Start: loc[0], End: loc[1], Style: r.style}
+			if !overlapsAny(spans, sp) {
+				spans = append(spans, sp)
+			}
+		}
+	}
+	sort.Slice(spans, func(a, b int) bool { return spans[a].Start < spans[b].Start })
+	return spans
+}
+
+func overlapsAny(spans []Span, sp Span) bool {
+	for _, s := range spans {
+		if sp.Start < s.End && s.Start < sp.End {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,507 @@
+package console
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// sameRule reports whether c and old count the same lines, so c can take
+// over old's history when a config is re-applied.
+func (c *counterRule) sameRule(old *counterRule) bool {
+	return old.label == c.label && old.match == c.match && old.caseSensitive == c.caseSensitive
+}
+
+// inherit takes over old's samples and running total. Extraction samples
+// are kept only if both extract the same values, as values must stay
+// parallel to times; otherwise the window starts empty.
+func (c *counterRule) inherit(old *counterRule) {
+	c.total, c.alerting = old.total, old.alerting
+	if c.spec.Extract != old.spec.Extract {
+		return
+	}
+	c.times = old.times
+	if c.extract != nil {
+		c.values = old.values
+	}
+	if c.byKey != nil && old.byKey != nil {
+		c.byKey = old.byKey
+	}
+}
+
+// observe records text if it matches the counter. Extraction counters need a
+// parseable capture as well.
+func (c *counterRule) observe(text string, when time.Time) {
+	if c.byKey != nil && !c.invalid {
+		c.observeGroups(text, when)
+		return
+	}
+	if c.invalid || (c.matcher != nil && !c.matcher.match(text)) {
+		return
+	}
+	if c.extract == nil {
+		if c.matcher != nil {
+			c.hit(when)
+		}
+		return
+	}
+	m := c.extract.FindStringSubmatch(text)
+	if len(m) < 2 {
+		return
+	}
+	v, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return
+	}
+	c.hit(when)
+	c.values = append(c.values, v)
+}
+
+// matches reports whether observe would count text.
+func (c *counterRule) matches(text string) bool {
+	if c.invalid || (c.matcher != nil && !c.matcher.match(text)) {
+		return false
+	}
+	if c.extract == nil {
+		return c.matcher != nil
+	}
+	m := c.extract.FindStringSubmatch(text)
+	if len(m) < 2 {
+		return false
+	}
+	_, err := strconv.ParseFloat(m[1], 64)
+	return err == nil
+}
+
+// observeGroups counts a match under the values of its named captures,
+// joined with "/".
+func (c *counterRule) observeGroups(text string, when time.Time) {
+	m := c.matcher.re.FindStringSubmatch(text)
+	if m == nil {
+		return
+	}
+	c.hit(when)
+	parts := make([]string, 0, len(c.groups))
+	for _, i := range c.groups {
+		parts = append(parts, m[i])
+	}
+	key := strings.Join(parts, "/")
+	c.byKey[key] = append(c.byKey[key], when)
+}
+
+// topKeys returns up to n captured values with the most matches after cut,
+// busiest first, and how many other values there are.
+func (c *counterRule) topKeys(cut time.Time, n int) (keys []string, counts []int, rest int) {
+	type kc struct {
+		key string
+		n   int
+	}
+	var all []kc
+	for k, ts := range c.byKey {
+		cnt := 0
+		for i := len(ts) - 1; i >= 0 && ts[i].After(cut); i-- {
+			cnt++
+		}
+		if cnt > 0 {
+			all = append(all, kc{k, cnt})
+		}
+	}
+	slices.SortFunc(all, func(a, b kc) int {
+		if a.n != b.n {
+			return b.n - a.n
+		}
+		return strings.Compare(a.key, b.key)
+	})
+	for i, e := range all {
+		if i == n {
+			return keys, counts, len(all) - n
+		}
+		keys = append(keys, e.key)
+		counts = append(counts, e.n)
+	}
+	return keys, counts, 0
+}
+
+// prune drops samples at or before cut.
+func (c *counterRule) prune(cut time.Time) {
+	for k, ts := range c.byKey {
+		i := 0
+		for i < len(ts) && !ts[i].After(cut) {
+			i++
+		}
+		if i == len(ts) {
+			delete(c.byKey, k)
+		} else if i > 0 {
+			c.byKey[k] = ts[i:]
+		}
+	}
+	if len(c.times) == 0 {
+		return
+	}
+	keep := c.times[:0]
+	vals := c.values[:0]
+	for i, t := range c.times {
+		if t.After(cut) {
+			keep = append(keep, t)
+			if c.extract != nil {
+				vals = append(vals, c.values[i])
+			}
+		}
+	}
+	c.times = keep
+	if c.extract != nil {
+		c.values = vals
+	}
+}
+
+// display renders the counter's value for the bars: the rolling count, or
+// average and p95 for extraction counters, plus the total if requested.
+// invalid is shown instead for a pattern that does not compile.
+func (c *counterRule) display(now time.Time, invalid string) string {
+	if c.remote != nil {
+		return c.remote.Display
+	}
+	if c.invalid {
+		return invalid
+	}
+	cut := now.Add(-c.window)
+	var out string
+	if c.extract == nil {
+		out = strconv.Itoa(c.windowCount(now))
+	} else {
+		var window []float64
+		for i, t := range c.times {
+			if t.After(cut) {
+				window = append(window, c.values[i])
+			}
+		}
+		out = "-"
+		if len(window) > 0 {
+			avg, p95 := summarize(window)
+			out = fmt.Sprintf("avg %s%s p95 %s%s", formatStat(avg), c.unit, formatStat(p95), c.unit)
+		}
+	}
+	if c.showTotal {
+		out += fmt.Sprintf(" (%d)", c.total)
+	}
+	if c.sparkline > 0 {
+		out += " " + c.sparklineAt(now, c.sparkline)
+	}
+	if c.byKey != nil {
+		keys, counts, rest := c.topKeys(cut, c.topN)
+		if len(keys) > 0 {
+			parts := make([]string, len(keys))
+			for i, k := range keys {
+				parts[i] = tview.Escape(k) + " " + strconv.Itoa(counts[i])
+			}
+			top := strings.Join(parts, ", ")
+			if rest > 0 {
+				top += fmt.Sprintf(" +%d", rest)
+			}
+			out += " {" + top + "}"
+		}
+	}
+	return out
+}
+
+// retention is how long the counter keeps samples: its window, or the
+// threshold window if longer.
+func (c *counterRule) retention() time.Duration {
+	return max(c.window, c.thresholdWindow)
+}
+
+// overThreshold returns the matches within the threshold window before now
+// and whether they exceed the threshold.
+func (c *counterRule) overThreshold(now time.Time) (int, bool) {
+	if c.remote != nil {
+		return c.remote.Count, c.remote.Alert
+	}
+	if c.threshold <= 0 || c.invalid {
+		return 0, false
+	}
+	cut := now.Add(-c.thresholdWindow)
+	cnt := 0
+	for i := len(c.times) - 1; i >= 0 && c.times[i].After(cut); i-- {
+		cnt++
+	}
+	return cnt, cnt > c.threshold
+}
+
+// thresholdAlert is a counter that just went over its threshold.
+type thresholdAlert struct {
+	spec  CounterSpec
+	count int
+}
+
+// thresholdAlerts updates the alert state of counters and returns those
+// that went over their threshold since the last check. Callers hold the
+// counters' lock.
+func thresholdAlerts(counters []*counterRule, now time.Time) []thresholdAlert {
+	var out []thresholdAlert
+	for _, c := range counters {
+		n, over := c.overThreshold(now)
+		if over && !c.alerting {
+			out = append(out, thresholdAlert{c.spec, n})
+		}
+		c.alerting = over
+	}
+	return out
+}
+
+// fireThresholds passes alerts to UIOptions.OnThreshold.
+func (u *UI) fireThresholds(alerts []thresholdAlert) {
+	if u.onThreshold == nil {
+		return
+	}
+	for _, a := range alerts {
+		u.onThreshold(a.spec, a.count)
+	}
+}
+
+// sparkBlocks are the bar heights of a sparkline, lowest first.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparklineAt renders the match counts of n intervals of the counter's
+// window up to now, scaled to the busiest one. Any match raises a bar above
+// the lowest.
+func (c *counterRule) sparklineAt(now time.Time, n int) string {
+	counts := make([]int, n)
+	step := c.window / time.Duration(n)
+	cut := now.Add(-c.window)
+	for _, t := range c.times {
+		if !t.After(cut) {
+			continue
+		}
+		i := min(int(t.Sub(cut)/max(step, 1)), len(counts)-1)
+		counts[i]++
+	}
+	peak := slices.Max(counts)
+	out := make([]rune, len(counts))
+	for i, n := range counts {
+		level := 0
+		if peak > 0 {
+			level = (n*(len(sparkBlocks)-1) + peak - 1) / peak
+		}
+		out[i] = sparkBlocks[level]
+	}
+	return string(out)
+}
+
+// windowCount returns the number of matches within the window before now.
+func (c *counterRule) windowCount(now time.Time) int {
+	cut := now.Add(-c.window)
+	cnt := 0
+	for i := len(c.times) - 1; i >= 0; i-- {
+		if !c.times[i].After(cut) {
+			break
+		}
+		cnt++
+	}
+	return cnt
+}
+
+// summarize returns the mean and 95th percentile (nearest rank) of vals,
+// which it sorts.
+func summarize(vals []float64) (avg, p95 float64) {
+	sum := 0.0
+	for _, v := range vals {
+		sum += v
+	}
+	slices.Sort(vals)
+	rank := int(math.Ceil(0.95*float64(len(vals)))) - 1
+	return sum / float64(len(vals)), vals[max(rank, 0)]
+}
+
+// formatStat prints v with about three significant digits.
+func formatStat(v float64) string {
+	switch a := math.Abs(v); {
+	case a >= 100:
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	case a >= 10:
+		return strconv.FormatFloat(v, 'f', 1, 64)
+	default:
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	}
+}
+
+// checkErrorBurstLocked records error-level lines of batch and pauses when
+// more than burstLimit fell within burstWindow. Lines stamped before the
+// window, such as a replayed ring, do not count. Callers hold u.mu.
+func (u *UI) checkErrorBurstLocked(batch []timedLine, now time.Time) {
+	if u.burstLimit <= 0 {
+		return
+	}
+	cut := now.Add(-u.burstWindow)
+	for _, tl := range batch {
+		if tl.when.After(cut) && tl.level == "error" {
+			u.errorTimes = append(u.errorTimes, tl.when)
+		}
+	}
+	keep := u.errorTimes[:0]
+	for _, t := range u.errorTimes {
+		if t.After(cut) {
+			keep = append(keep, t)
+		}
+	}
+	u.errorTimes = keep
+	if len(u.errorTimes) > u.burstLimit && !u.paused {
+		u.paused = true
+		u.burstPaused = true
+		u.errorTimes = u.errorTimes[:0]
+	}
+}
+
+// rateWindow is how many seconds the lines-per-second rate averages over.
+const rateWindow = 10
+
+// streamStats accumulates what the statistics panel shows about the lines
+// received. It is guarded by UI.mu.
+type streamStats struct {
+	since  time.Time
+	lines  uint64
+	bytes  uint64
+	levels map[string]uint64
+	secs   []secCount // arrivals in the last rateWindow seconds
+	peak   int        // most lines received within one second
+}
+
+type secCount struct {
+	sec int64
+	n   int
+}
+
+// add counts a line of level received at now.
+func (s *streamStats) add(text, level string, now time.Time) {
+	if s.levels == nil {
+		s.since, s.levels = now, make(map[string]uint64)
+	}
+	s.lines++
+	s.bytes += uint64(len(text)) + 1
+	s.levels[level]++
+	sec := now.Unix()
+	if n := len(s.secs); n > 0 && s.secs[n-1].sec == sec {
+		s.secs[n-1].n++
+	} else {
+		s.secs = append(s.secs, secCount{sec, 1})
+		for len(s.secs) > 0 && s.secs[0].sec <= sec-rateWindow {
+			s.secs = s.secs[1:]
+		}
+	}
+	s.peak = max(s.peak, s.secs[len(s.secs)-1].n)
+}
+
+// rate returns the lines per second received over the last rateWindow
+// seconds, or since the first line if that is more recent.
+func (s *streamStats) rate(now time.Time) float64 {
+	sec := now.Unix()
+	n := 0
+	for _, c := range s.secs {
+		if c.sec > sec-rateWindow {
+			n += c.n
+		}
+	}
+	span := min(rateWindow, sec-s.since.Unix()+1)
+	return float64(n) / float64(max(span, 1))
+}
+
+// formatBytes prints n with a binary unit.
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	v, i := float64(n)/unit, 0
+	for v >= unit && i < 4 {
+		v /= unit
+		i++
+	}
+	return formatStat(v) + " " + "KMGTP"[i:i+1] + "iB"
+}
+
+// statsSparkline is how many intervals the statistics panel draws for each
+// counter's window.
+const statsSparkline = 20
+
+// streamStatsText renders the statistics panel.
+func (u *UI) streamStatsText() string {
+	now := time.Now()
+	u.mu.Lock()
+	st := u.stats
+	rate := st.rate(now)
+	counts := maps.Clone(st.levels)
+	buffered := len(u.lines)
+	u.mu.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-16s %d", u.msg("stats.received"), st.lines)
+	if !st.since.IsZero() {
+		b.WriteString(" " + u.msg("stats.since", st.since.Format(time.TimeOnly), now.Sub(st.since).Round(time.Second)))
+	}
+	fmt.Fprintf(&b, "\n%-16s %s\n", u.msg("stats.rate"), u.msg("stats.rate_now", formatStat(rate), st.peak))
+	fmt.Fprintf(&b, "%-16s %s\n", u.msg("stats.bytes"), formatBytes(st.bytes))
+	fmt.Fprintf(&b, "%-16s %s\n", u.msg("stats.buffered"), u.msg("stats.lines", buffered))
+	parts := make([]string, len(levels))
+	for i, lv := range levels {
+		parts[i] = fmt.Sprintf("%s %d", lv, counts[lv])
+	}
+	fmt.Fprintf(&b, "%-16s %s\n", u.msg("stats.levels"), strings.Join(parts, "  "))
+
+	u.counterMu.Lock()
+	defer u.counterMu.Unlock()
+	if len(u.counters) == 0 {
+		return tview.Escape(b.String())
+	}
+	b.WriteString("\n" + u.msg("stats.counters") + "\n")
+	for _, c := range u.counters {
+		count, total := c.windowCount(now), c.total
+		if c.remote != nil {
+			count, total = c.remote.Count, c.remote.Total
+		}
+		fmt.Fprintf(&b, "  %-12s %s  %s\n",
+			c.label, u.msg("stats.counter", count, c.window, total), c.sparklineAt(now, statsSparkline))
+	}
+	return tview.Escape(b.String())
+}
+
+// toggleStatsModalDirect opens the statistics panel, or closes it if open.
+func (u *UI) toggleStatsModalDirect() {
+	if u.statsView != nil && u.modal == u.statsPanel {
+		u.closeModal()
+		return
+	}
+	u.statsView = u.showTextModal(u.msg("modal.stream"), u.streamStatsText())
+	u.statsPanel, u.statsShown = u.modal, time.Now()
+}
+
+// refreshStatsModalDirect updates the statistics panel if it is open, at
+// most once a second.
+func (u *UI) refreshStatsModalDirect() {
+	if u.statsView == nil || u.modal != u.statsPanel || time.Since(u.statsShown) < time.Second {
+		return
+	}
+	u.statsShown = time.Now()
+	u.statsView.SetText(u.streamStatsText())
+}
+
+// formatStats renders a broker Stats frame for the stats modal.
+func (u *UI) formatStats(st Stats) string {
+	var b strings.Builder
+	b.WriteString(u.msg("stats.appended", st.Lines) + "\n" + u.msg("stats.viewers", len(st.Clients)) + "\n")
+	if st.SlowDisconnects > 0 {
+		b.WriteString(u.msg("stats.slow", st.SlowDisconnects) + "\n")
+	}
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "%-5s %-24s %-10s %9s %11s\n", u.msg("stats.id"), u.msg("stats.remote"), u.msg("stats.connected"), u.msg("stats.dropped"), u.msg("stats.queue"))
+	for _, c := range st.Clients {
+		since := time.Since(time.UnixMicro(c.ConnectedUs)).Round(time.Second)
+		fmt.Fprintf(&b, "%-5d %-24s %-10s %9d %5d/%-5d\n",
+			c.ID, c.RemoteAddr, since, c.Dropped, c.QueueLen, c.QueueHighWater)
+	}
+	return tview.Escape(b.String())
+}
@@ -0,0 +1,133 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// StreamFormat selects what AttachStream writes.
+type StreamFormat int
+
+const (
+	// StreamRendered writes one line per log line with the server's
+	// highlights as ANSI colours, or plain text when NoColour is set.
+	StreamRendered StreamFormat = iota
+	// StreamRaw copies the NDJSON frames as received, for tools like jq.
+	StreamRaw
+)
+
+// AttachStream connects like Attach but writes the stream to w instead of
+// running the UI, so it can be piped into other tools. Only the broker, TLS,
+// Since and NoColour fields of opts apply; highlights in opts.UI.Rules are added to the
+// server's. It returns nil when the broker closes the connection.
+func AttachStream(opts AttachOptions, w io.Writer, format StreamFormat) error {
+	return AttachStreamContext(context.Background(), opts, w, format)
+}
+
+// AttachStreamContext is like AttachStream but returns ctx.Err() once ctx is
+// cancelled.
+func AttachStreamContext(ctx context.Context, opts AttachOptions, w io.Writer, format StreamFormat) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(opts.Upstreams) > 0 {
+		return attachUpstreams(ctx, opts, func(ctx context.Context, opts AttachOptions) error {
+			return AttachStreamContext(ctx, opts, w, format)
+		})
+	}
+	path, err := opts.resolvePath()
+	if err != nil {
+		return err
+	}
+	conn, err := opts.dial(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := writeHello(conn, opts.sinceUs(), opts.token()); err != nil {
+		return fmt.Errorf("console attach: %w", err)
+	}
+	if ctx.Done() != nil {
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			select {
+			case <-ctx.Done():
+				_ = conn.Close()
+			case <-stop:
+			}
+		}()
+	}
+
+	var local Config
+	if opts.UI != nil {
+		local = opts.UI.Rules
+	}
+	out := NewANSIWriter(w, local, opts.NoColour)
+	fr := newConnFrameReader(conn)
+	for {
+		b, err := fr.next()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("console attach: %w", err)
+		}
+		if format == StreamRaw {
+			if !bytes.HasSuffix(b, []byte("\n")) {
+				b = append(b, '\n')
+			}
+			if _, err := w.Write(b); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := streamFrame(out, fr, b, local); err != nil {
+			return err
+		}
+	}
+}
+
+// streamFrame writes the lines and notices in frame b to out.
+func streamFrame(out *ANSIWriter, fr *frameReader, b []byte, local Config) error {
+	typ, b := fr.peekFrameType(b)
+	_ = fr.takeMalformed()
+	var lines []string
+	switch typ {
+	case "meta":
+		var m Meta
+		if json.Unmarshal(b, &m) == nil {
+			out.SetConfig(Config{Highlights: append(append([]HighlightSpec(nil), m.Highlights...), local.Highlights...)})
+		}
+	case "line":
+		var ev Line
+		if json.Unmarshal(b, &ev) == nil {
+			lines = append(lines, lineText(ev)+formatFields(ev.Fields, nil))
+		}
+	case "lines":
+		var evs Lines
+		if json.Unmarshal(b, &evs) == nil {
+			for _, ev := range evs.Lines {
+				lines = append(lines, lineText(ev)+formatFields(ev.Fields, nil))
+			}
+		}
+	case "notice":
+		var n Notice
+		if json.Unmarshal(b, &n) == nil {
+			lines = append(lines, n.Text)
+		}
+	}
+	for _, l := range lines {
+		if err := out.WriteLine(l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,418 @@
+package console
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyslogOptions configures BrokerOptions.Syslog, which forwards every line
+// appended to the broker to a syslog daemon.
+type SyslogOptions struct {
+	// Network and Addr locate the daemon: "udp" or "tcp" with a host:port,
+	// or "unixgram" or "unix" with a socket path. An empty Network uses
+	// the local daemon's socket, such as /dev/log.
+	Network string
+	Addr    string
+	// Tag is the program name messages carry; default the executable's.
+	Tag string
+	// Facility is the syslog facility code, 0 to 23; default 1, user.
+	Facility int
+	// RFC5424 sends RFC 5424 messages; default the older RFC 3164 format,
+	// which every daemon reads.
+	RFC5424 bool
+}
+
+// syslogQueue is how many lines the forwarder holds while the daemon is
+// slow or unreachable; further lines are not forwarded.
+const syslogQueue = 1024
+
+// localSyslogSockets are tried in order when SyslogOptions.Network is empty.
+var localSyslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// syslogForwarder sends lines to a syslog daemon from its own goroutine, so
+// a slow daemon never holds up Append.
+type syslogForwarder struct {
+	opts     SyslogOptions
+	host     string
+	lines    chan Line
+	conn     net.Conn
+	lastFail time.Time
+}
+
+func newSyslogForwarder(opts SyslogOptions) *syslogForwarder {
+	if opts.Tag == "" {
+		opts.Tag = filepath.Base(os.Args[0])
+	}
+	opts.Facility = cmp.Or(opts.Facility, 1)
+	host, _ := os.Hostname()
+	return &syslogForwarder{opts: opts, host: cmp.Or(host, "-"), lines: make(chan Line, syslogQueue)}
+}
+
+// send queues ev, dropping it if the queue is full.
+func (f *syslogForwarder) send(ev Line) {
+	select {
+	case f.lines <- ev:
+	default:
+	}
+}
+
+// run forwards queued lines until stopCh closes.
+func (f *syslogForwarder) run(stopCh chan struct{}) {
+	defer f.close()
+	for {
+		select {
+		case ev := <-f.lines:
+			f.write(ev)
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// dial connects to the daemon.
+func (f *syslogForwarder) dial() (net.Conn, error) {
+	if f.opts.Network != "" {
+		return net.DialTimeout(f.opts.Network, f.opts.Addr, 5*time.Second)
+	}
+	for _, path := range localSyslogSockets {
+		for _, network := range []string{"unixgram", "unix"} {
+			if c, err := net.Dial(network, path); err == nil {
+				return c, nil
+			}
+		}
+	}
+	return nil, errors.New("no local syslog daemon")
+}
+
+// write sends one message, redialling after a failure. While the daemon is
+// unreachable lines are dropped, with a dial attempt at most every second.
+func (f *syslogForwarder) write(ev Line) {
+	if f.conn == nil {
+		if time.Since(f.lastFail) < time.Second {
+			return
+		}
+		c, err := f.dial()
+		if err != nil {
+			f.lastFail = time.Now()
+			return
+		}
+		f.conn = c
+	}
+	msg := f.format(ev)
+	if _, packet := f.conn.(net.PacketConn); !packet {
+		msg = append(msg, '\n') // non-transparent framing on streams
+	}
+	_ = f.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if _, err := f.conn.Write(msg); err != nil {
+		f.close()
+		f.lastFail = time.Now()
+	}
+}
+
+func (f *syslogForwarder) close() {
+	if f.conn != nil {
+		_ = f.conn.Close()
+		f.conn = nil
+	}
+}
+
+// format renders ev as an RFC 3164 or RFC 5424 message.
+func (f *syslogForwarder) format(ev Line) []byte {
+	pri := f.opts.Facility*8 + severityOf(lineLevel(ev))
+	ts := time.UnixMicro(ev.TsUs)
+	text := strings.ReplaceAll(filterText(ev), "\n", " ")
+	var b bytes.Buffer
+	if f.opts.RFC5424 {
+		fmt.Fprintf(&b, "<%d>1 %s %s %s %d - - %s", pri, ts.Format("2006-01-02T15:04:05.000000Z07:00"), f.host, f.opts.Tag, os.Getpid(), text)
+	} else {
+		fmt.Fprintf(&b, "<%d>%s %s %s[%d]: %s", pri, ts.Format(time.Stamp), f.host, f.opts.Tag, os.Getpid(), text)
+	}
+	return b.Bytes()
+}
+
+// severityOf maps a level class to a syslog severity.
+func severityOf(class string) int {
+	switch class {
+	case "error":
+		return 3
+	case "warn":
+		return 4
+	case "debug":
+		return 7
+	}
+	return 6
+}
+
+// levelOfSeverity maps a syslog severity to a Line.Level.
+func levelOfSeverity(sev int) string {
+	switch {
+	case sev <= 3:
+		return "error"
+	case sev == 4:
+		return "warn"
+	case sev == 7:
+		return "debug"
+	}
+	return "info"
+}
+
+// ListenSyslog accepts syslog messages on network ("udp", "tcp", "unixgram"
+// or "unix") and addr and appends them to b until stop is called, so the
+// console can show the host's other daemons next to the program's own
+// lines. RFC 5424 and RFC 3164 messages are understood: the line keeps the
+// message's timestamp and severity, its Source is the app name or tag, and
+// the host, pid, msgid and structured data become Fields. TCP messages are
+// newline-delimited or octet-counted.
+func ListenSyslog(network, addr string, b *Broker) (stop func(), err error) {
+	var closers []func() error
+	var wg sync.WaitGroup
+	switch network {
+	case "udp", "udp4", "udp6", "unixgram":
+		pc, err := net.ListenPacket(network, addr)
+		if err != nil {
+			return nil, fmt.Errorf("console syslog: %w", err)
+		}
+		closers = append(closers, pc.Close)
+		wg.Go(func() {
+			buf := make([]byte, 64<<10)
+			for {
+				n, _, err := pc.ReadFrom(buf)
+				if err != nil {
+					return
+				}
+				b.submit(parseSyslog(buf[:n], time.Now()))
+			}
+		})
+	case "tcp", "tcp4", "tcp6", "unix":
+		ln, err := net.Listen(network, addr)
+		if err != nil {
+			return nil, fmt.Errorf("console syslog: %w", err)
+		}
+		var (
+			mu    sync.Mutex
+			conns = map[net.Conn]struct{}{}
+		)
+		closers = append(closers, ln.Close, func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			for c := range conns {
+				_ = c.Close()
+			}
+			return nil
+		})
+		wg.Go(func() {
+			for {
+				c, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				mu.Lock()
+				conns[c] = struct{}{}
+				mu.Unlock()
+				wg.Go(func() {
+					readSyslogStream(c, b)
+					mu.Lock()
+					delete(conns, c)
+					mu.Unlock()
+					_ = c.Close()
+				})
+			}
+		})
+	default:
+		return nil, fmt.Errorf("console syslog: unsupported network %q", network)
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			for _, c := range closers {
+				_ = c()
+			}
+			wg.Wait()
+		})
+	}, nil
+}
+
+// readSyslogStream appends the messages of one stream connection, framed
+// by octet counting ("12 <34>...") or by newlines.
+func readSyslogStream(c net.Conn, b *Broker) {
+	r := bufio.NewReaderSize(c, 64<<10)
+	for {
+		first, err := r.Peek(1)
+		if err != nil {
+			return
+		}
+		var msg []byte
+		if first[0] >= '1' && first[0] <= '9' {
+			lenText, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(lenText))
+			if err != nil || n > MaxFrameBytes {
+				return
+			}
+			msg = make([]byte, n)
+			if _, err := io.ReadFull(r, msg); err != nil {
+				return
+			}
+		} else {
+			line, err := r.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				msg = line
+			}
+			if err != nil && msg == nil {
+				return
+			}
+		}
+		if msg != nil {
+			b.submit(parseSyslog(msg, time.Now()))
+		}
+	}
+}
+
+// parseSyslog parses an RFC 5424 or RFC 3164 message into a line. Parts
+// that do not parse are kept in the text, so nothing received is lost.
+func parseSyslog(msg []byte, now time.Time) Line {
+	s := strings.TrimRight(string(msg), "\r\n\x00")
+	ev := Line{Type: "line", TsUs: now.UnixMicro(), Level: "info"}
+	fields := map[string]string{}
+	if rest, pri, ok := cutPriority(s); ok {
+		s = rest
+		ev.Level = levelOfSeverity(pri % 8)
+		fields["facility"] = strconv.Itoa(pri / 8)
+	}
+	if rest, ok := strings.CutPrefix(s, "1 "); ok {
+		s = parse5424(rest, &ev, fields)
+	} else {
+		s = parse3164(s, now, &ev, fields)
+	}
+	ev.Text = s
+	ev.Fields = fields
+	return ev
+}
+
+// cutPriority removes a "<PRI>" prefix.
+func cutPriority(s string) (string, int, bool) {
+	if !strings.HasPrefix(s, "<") {
+		return s, 0, false
+	}
+	end := strings.IndexByte(s, '>')
+	if end < 2 || end > 4 {
+		return s, 0, false
+	}
+	pri, err := strconv.Atoi(s[1:end])
+	if err != nil || pri > 191 {
+		return s, 0, false
+	}
+	return s[end+1:], pri, true
+}
+
+// parse5424 reads "TIMESTAMP HOST APP PROCID MSGID SD MSG", returning MSG.
+func parse5424(s string, ev *Line, fields map[string]string) string {
+	var head [5]string
+	for i := range head {
+		var ok bool
+		if head[i], s, ok = strings.Cut(s, " "); !ok && i < 4 {
+			return s
+		}
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, head[0]); err == nil {
+		ev.TsUs = ts.UnixMicro()
+	}
+	for i, key := range []string{"", "host", "app", "pid", "msgid"} {
+		if i > 0 && head[i] != "-" {
+			fields[key] = head[i]
+		}
+	}
+	ev.Source = fields["app"]
+	delete(fields, "app")
+	s = parseStructuredData(s, fields)
+	return strings.TrimPrefix(s, "\ufeff") // the UTF-8 BOM
+}
+
+// parseStructuredData reads "-" or "[id k="v" ...]..." into fields keyed
+// "id.k", returning the rest of the message.
+func parseStructuredData(s string, fields map[string]string) string {
+	if rest, ok := strings.CutPrefix(s, "-"); ok {
+		return strings.TrimPrefix(rest, " ")
+	}
+	for strings.HasPrefix(s, "[") {
+		end := -1
+		inQuote := false
+		for i := 1; i < len(s); i++ {
+			switch {
+			case s[i] == '\\' && inQuote:
+				i++
+			case s[i] == '"':
+				inQuote = !inQuote
+			case s[i] == ']' && !inQuote:
+				end = i
+			}
+			if end >= 0 {
+				break
+			}
+		}
+		if end < 0 {
+			return s
+		}
+		id, params, _ := strings.Cut(s[1:end], " ")
+		for params != "" {
+			key, rest, ok := strings.Cut(params, `="`)
+			if !ok {
+				break
+			}
+			var val strings.Builder
+			i := 0
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				val.WriteByte(rest[i])
+			}
+			fields[id+"."+strings.TrimSpace(key)] = val.String()
+			params = strings.TrimPrefix(rest[min(i+1, len(rest)):], " ")
+		}
+		s = s[end+1:]
+	}
+	return strings.TrimPrefix(s, " ")
+}
+
+// parse3164 reads "Mmm dd hh:mm:ss HOST TAG[PID]: MSG", returning MSG. The
+// timestamp has no year; the one making it closest to now is used.
+func parse3164(s string, now time.Time, ev *Line, fields map[string]string) string {
+	if len(s) >= len(time.Stamp) {
+		if ts, err := time.ParseInLocation(time.Stamp, s[:len(time.Stamp)], now.Location()); err == nil {
+			ts = ts.AddDate(now.Year(), 0, 0)
+			if ts.Sub(now) > 24*time.Hour {
+				ts = ts.AddDate(-1, 0, 0) // December lines read in January
+			}
+			ev.TsUs = ts.UnixMicro()
+			s = strings.TrimPrefix(s[len(time.Stamp):], " ")
+			if host, rest, ok := strings.Cut(s, " "); ok && !strings.HasSuffix(host, ":") {
+				fields["host"], s = host, rest
+			}
+		}
+	}
+	// the tag ends at the first ":" or "[" of the first word
+	if word, _, _ := strings.Cut(s, " "); strings.HasSuffix(word, ":") {
+		tag := strings.TrimSuffix(word, ":")
+		if name, pid, ok := strings.Cut(tag, "["); ok {
+			tag = name
+			fields["pid"] = strings.TrimSuffix(pid, "]")
+		}
+		ev.Source = tag
+		s = strings.TrimPrefix(s[len(word):], " ")
+	}
+	return s
+}
@@ -0,0 +1,151 @@
+package console
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// tailPollInterval is how often TailFile checks for new data and rotation.
+const tailPollInterval = 250 * time.Millisecond
+
+// TailFile follows the file at path like tail -F, appending each new line to
+// b from a Source named after the file, until stop is called. It starts at
+// the end of the file. When the file is renamed or removed and recreated, as
+// log rotation does, the rest of the old file is read and the new one is
+// followed from its start; when it is truncated it is read again from the
+// start. A file that does not exist yet is waited for.
+func TailFile(path string, b *Broker) (stop func(), err error) {
+	t := &tailer{path: path, src: b.Source(filepath.Base(path)), done: make(chan struct{})}
+	if err := t.open(true); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	var wg sync.WaitGroup
+	wg.Go(t.run)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(t.done)
+			wg.Wait()
+		})
+	}, nil
+}
+
+// tailer is the state of one TailFile.
+type tailer struct {
+	path    string
+	src     *Source
+	done    chan struct{}
+	f       *os.File // nil while the file is missing
+	r       *bufio.Reader
+	offset  int64
+	partial []byte // a line still missing its newline
+	skip    bool   // the rest of a line cut at maxLineTextBytes is discarded
+}
+
+// open opens path, at its end if atEnd.
+func (t *tailer) open(atEnd bool) error {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	t.offset = 0
+	if atEnd {
+		if t.offset, err = f.Seek(0, io.SeekEnd); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	t.f, t.r, t.partial, t.skip = f, bufio.NewReaderSize(f, 64<<10), nil, false
+	return nil
+}
+
+func (t *tailer) run() {
+	defer func() {
+		if t.f != nil {
+			_ = t.f.Close()
+		}
+	}()
+	tick := time.NewTicker(tailPollInterval)
+	defer tick.Stop()
+	for {
+		t.poll()
+		select {
+		case <-tick.C:
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// poll reads what was appended and follows rotation and truncation.
+func (t *tailer) poll() {
+	if t.f == nil {
+		if t.open(false) != nil {
+			return
+		}
+	}
+	t.read()
+	cur, err := os.Stat(t.path)
+	old, oldErr := t.f.Stat()
+	switch {
+	case oldErr != nil:
+	case err != nil || !os.SameFile(old, cur):
+		// rotated: the old file was drained above; follow the new one
+		t.flushPartial()
+		_ = t.f.Close()
+		t.f = nil
+		if err == nil && t.open(false) == nil {
+			t.read()
+		}
+	case cur.Size() < t.offset:
+		// truncated in place
+		t.partial, t.skip = nil, false
+		if _, err := t.f.Seek(0, io.SeekStart); err == nil {
+			t.offset = 0
+			t.r.Reset(t.f)
+			t.read()
+		}
+	}
+}
+
+// read appends the complete lines available. A line growing past
+// maxLineTextBytes is appended then, truncated, and the rest of it skipped,
+// so a file without newlines does not fill memory.
+func (t *tailer) read() {
+	for {
+		chunk, err := t.r.ReadSlice('\n')
+		t.offset += int64(len(chunk))
+		ended := err == nil
+		switch {
+		case t.skip:
+			t.skip = !ended
+		case ended:
+			t.src.Append(string(bytes.TrimRight(append(t.partial, chunk...), "\r\n")))
+			t.partial = nil
+		default:
+			t.partial = append(t.partial, chunk...)
+			if len(t.partial) > maxLineTextBytes {
+				t.src.Append(truncateLineText(string(t.partial)))
+				t.partial, t.skip = nil, true
+			}
+		}
+		if !ended && !errors.Is(err, bufio.ErrBufferFull) {
+			return
+		}
+	}
+}
+
+// flushPartial appends a last line left without a newline.
+func (t *tailer) flushPartial() {
+	if len(t.partial) > 0 {
+		t.src.Append(string(bytes.TrimRight(t.partial, "\r\n")))
+	}
+	t.partial, t.skip = nil, false
+}
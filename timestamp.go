@@ -0,0 +1,62 @@
+package console
+
+import "time"
+
+// DefaultTimestampLayouts are common log timestamp prefixes: RFC 3339, the
+// usual "date time" forms, the standard library log package, and syslog.
+var DefaultTimestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.000000",
+	"2006-01-02 15:04:05.000",
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04:05.000000",
+	"2006/01/02 15:04:05",
+	time.StampMicro,
+	time.Stamp,
+}
+
+// maxTimestampWords bounds how many space-separated words a prefix may span.
+const maxTimestampWords = 4
+
+// ParseTimestampPrefix extracts a leading timestamp from s using the first
+// matching layout. Layouts are parsed in the local time zone unless they
+// carry their own; layouts without a year (syslog) get the current year.
+func ParseTimestampPrefix(s string, layouts []string) (time.Time, bool) {
+	if len(layouts) == 0 || s == "" || s[0] == ' ' {
+		return time.Time{}, false
+	}
+	// candidate prefix ends: each word boundary, plus the whole string
+	ends := make([]int, 0, maxTimestampWords+1)
+	for i := 0; i < len(s) && len(ends) < maxTimestampWords; i++ {
+		if s[i] == ' ' && i > 0 && s[i-1] != ' ' {
+			ends = append(ends, i)
+		}
+	}
+	ends = append(ends, len(s))
+
+	now := time.Now()
+	for _, layout := range layouts {
+		for _, end := range ends {
+			if t, ok := parseTimestamp(layout, s[:end], now); ok {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseTimestamp parses s with layout in the local time zone, giving a time
+// without a year the year that puts it no later than a day after now.
+func parseTimestamp(layout, s string, now time.Time) (time.Time, bool) {
+	t, err := time.ParseInLocation(layout, s, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if t.Year() == 0 {
+		t = t.AddDate(now.Year(), 0, 0)
+		if t.After(now.Add(24 * time.Hour)) {
+			t = t.AddDate(-1, 0, 0) // December lines read in January
+		}
+	}
+	return t, true
+}
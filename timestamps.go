@@ -0,0 +1,96 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// Timestamp column modes, for UIOptions.Timestamps and the t key.
+const (
+	TimestampsOff      = ""
+	TimestampsAbsolute = "absolute" // time of day, formatted with TimestampFormat
+	TimestampsRelative = "relative" // time since the first line received
+	TimestampsDelta    = "delta"    // time since the line above
+)
+
+// timestampModes is the order the t key cycles through.
+var timestampModes = []string{TimestampsOff, TimestampsAbsolute, TimestampsRelative, TimestampsDelta}
+
+// DefaultTimestampFormat is the layout of absolute timestamps.
+const DefaultTimestampFormat = "15:04:05.000"
+
+// cycleTimestampsDirect switches to the next timestamp column mode.
+func (u *UI) cycleTimestampsDirect() {
+	u.mu.Lock()
+	i := 0
+	for j, m := range timestampModes {
+		if m == u.tsMode {
+			i = j
+		}
+	}
+	u.tsMode = timestampModes[(i+1)%len(timestampModes)]
+	mode := u.tsMode
+	u.mu.Unlock()
+	u.refreshDirect()
+	if mode == TimestampsOff {
+		mode = u.msg("timestamps.off")
+	}
+	u.setStatusMessage(u.msg("timestamps.mode", mode))
+}
+
+// tsPrefixes renders the timestamp column of rows, in time order, following
+// a row stamped prev. It returns nil if the column is off, and the time of
+// the last row for the next call.
+func (u *UI) tsPrefixes(rows []displayRow, prev time.Time) ([]string, time.Time) {
+	u.mu.Lock()
+	mode, layout, utc, base := u.tsMode, u.tsLayout, u.tsUTC, u.tsBase
+	u.mu.Unlock()
+	if mode == TimestampsOff || len(rows) == 0 {
+		return nil, prev
+	}
+	out := make([]string, len(rows))
+	for i, r := range rows {
+		var ts string
+		switch mode {
+		case TimestampsAbsolute:
+			when := r.when.Local()
+			if utc {
+				when = r.when.UTC()
+			}
+			ts = when.Format(layout)
+		case TimestampsRelative:
+			ts = formatOffset(r.when.Sub(base))
+		case TimestampsDelta:
+			d := time.Duration(0)
+			if !prev.IsZero() {
+				d = r.when.Sub(prev)
+			}
+			ts = fmt.Sprintf("%+9.3fs", d.Seconds())
+		}
+		if r.key.sub >= 0 {
+			// continuation lines share their parent's time
+			out[i] = strings.Repeat(" ", len(ts)+1)
+			continue
+		}
+		prev = r.when
+		if u.noColour {
+			out[i] = ts + " "
+		} else {
+			out[i] = "[gray]" + tview.Escape(ts) + "[-] "
+		}
+	}
+	return out, prev
+}
+
+// formatOffset formats d as a signed [h]h:mm:ss.mmm.
+func formatOffset(d time.Duration) string {
+	sign := "+"
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%s%02d:%02d:%02d.%03d", sign, ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,127 @@
+package console
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// tlsHandshakeTimeout bounds how long the broker waits for a TCP client to
+// complete the TLS handshake.
+const tlsHandshakeTimeout = 10 * time.Second
+
+// TLSOptions configures TLS for TCP connections between broker and clients.
+// Files are PEM encoded.
+type TLSOptions struct {
+	// CertFile and KeyFile hold the certificate presented to the peer. The
+	// broker requires them; a client sets them for mutual authentication.
+	CertFile string
+	KeyFile  string
+	// CAFile holds the certificates used to verify the peer. When empty a
+	// client uses the system roots and the broker does not verify clients.
+	CAFile string
+	// RequireClientCert makes the broker reject clients without a
+	// certificate signed by CAFile.
+	RequireClientCert bool
+	// ServerName overrides the name a client verifies in the broker's
+	// certificate; by default it is the host part of the address.
+	ServerName string
+	// InsecureSkipVerify disables verification of the broker's certificate.
+	// Intended for testing only.
+	InsecureSkipVerify bool
+}
+
+// serverConfig builds the broker side configuration.
+func (o *TLSOptions) serverConfig() (*tls.Config, error) {
+	if o.CertFile == "" || o.KeyFile == "" {
+		return nil, errors.New("console tls: broker requires CertFile and KeyFile")
+	}
+	cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("console tls: %w", err)
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if o.CAFile != "" {
+		pool, err := loadCertPool(o.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	if o.RequireClientCert {
+		if cfg.ClientCAs == nil {
+			return nil, errors.New("console tls: RequireClientCert needs CAFile")
+		}
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+// ClientConfig builds the client side configuration for a broker at addr,
+// also for transports dialled outside this package, such as grpcconsole's.
+func (o *TLSOptions) ClientConfig(addr string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		cfg.ServerName = host
+	}
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("console tls: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if o.CAFile != "" {
+		pool, err := loadCertPool(o.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("console tls: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("console tls: no certificates in %s", path)
+	}
+	return pool, nil
+}
+
+// clientTLS wraps conn, dialled to addr, in TLS and completes the handshake
+// so certificate problems are reported at connect time.
+func clientTLS(ctx context.Context, conn net.Conn, addr string, o *TLSOptions) (net.Conn, error) {
+	addr = strings.TrimPrefix(strings.TrimPrefix(addr, "tcp://"), "unix://")
+	cfg, err := o.ClientConfig(addr)
+	if err != nil {
+		return nil, err
+	}
+	tc := tls.Client(conn, cfg)
+	if err := tc.HandshakeContext(ctx); err != nil {
+		return nil, fmt.Errorf("TLS handshake with %s: %w", addr, err)
+	}
+	return tc, nil
+}
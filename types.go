@@ -1,6 +1,9 @@
 package console
 
-import "strings"
+import (
+	"slices"
+	"strings"
+)
 
 const DefaultMaxLines = 10000
 
@@ -19,6 +22,29 @@ type CounterSpec struct {
 	CaseSensitive bool   `json:"case_sensitive"`
 	Label         string `json:"label"`
 	WindowSeconds int    `json:"window_s"`
+	// ShowTotal also displays the count since start, as "LABEL:3 (127)".
+	ShowTotal bool `json:"show_total,omitempty"`
+	// Extract, when set, is a regular expression whose first capture group
+	// is parsed as a number, e.g. `took (\d+)ms`. The counter then shows the
+	// rolling average and p95 of the values, followed by Unit.
+	Extract string `json:"extract,omitempty"`
+	Unit    string `json:"unit,omitempty"`
+	// Regex treats Match as a regular expression. If it has named capture
+	// groups, such as `DISCOVER from (?P<mac>\S+)`, matches are also
+	// counted per captured value and the TopN busiest values are shown.
+	Regex bool `json:"regex,omitempty"`
+	// TopN is how many captured values a grouping counter shows; default 3.
+	TopN int `json:"top_n,omitempty"`
+	// Threshold, when positive, raises an alert once more than this many
+	// matches fall within ThresholdWindow seconds (default WindowSeconds):
+	// the counter blinks in the error style and UIOptions.OnThreshold or
+	// BrokerOptions.OnThreshold is called.
+	Threshold       int `json:"threshold,omitempty"`
+	ThresholdWindow int `json:"threshold_window_s,omitempty"`
+	// Sparkline, when positive, splits the window into this many intervals
+	// and shows their match counts as a bar chart after the value, oldest
+	// first, e.g. "DISC:42 ▁▂▂▄▇".
+	Sparkline int `json:"sparkline,omitempty"`
 }
 
 // HighlightSpec describes a substring highlight with an optional style.
@@ -26,6 +52,8 @@ type HighlightSpec struct {
 	Match         string `json:"match"`
 	CaseSensitive bool   `json:"case_sensitive"`
 	Style         *Style `json:"style,omitempty"`
+	// Regex treats Match as a regular expression.
+	Regex bool `json:"regex,omitempty"`
 }
 
 // Config captures shared presentation rules exchanged between broker and UI.
@@ -33,6 +61,13 @@ type Config struct {
 	MaxLines   int
 	Counters   []CounterSpec
 	Highlights []HighlightSpec
+	// Palette names a built-in palette; see PaletteByName. It applies only
+	// locally and is not sent to clients.
+	Palette string
+	// MaxBytes, if positive, also caps the broker's ring by the size of the
+	// lines it holds, dropping the oldest once they exceed it. It is not
+	// sent to clients.
+	MaxBytes int
 }
 
 // EffectiveMaxLines returns a sane positive value for ring buffer sizing.
@@ -49,6 +84,64 @@ type Meta struct {
 	MaxLines   int             `json:"max_lines"`
 	Counters   []CounterSpec   `json:"counters"`
 	Highlights []HighlightSpec `json:"highlights"`
+	// ServerTimeUs is the broker clock when the meta was sent; clients use it
+	// to estimate clock skew.
+	ServerTimeUs int64 `json:"server_time_us,omitempty"`
+	// StartedUs identifies the broker instance by its start time, so a
+	// reconnecting client can tell a replay of lines it has seen from a
+	// restarted broker.
+	StartedUs int64 `json:"started_us,omitempty"`
+	// Version is the broker's ProtocolVersion; brokers predating it send
+	// none.
+	Version int `json:"version,omitempty"`
+	// Capabilities lists the optional features the broker serves, such as
+	// "command" or "publish".
+	Capabilities []string `json:"capabilities,omitempty"`
+	// Framing is "length_prefix" in the meta opening a connection whose
+	// hello listed that capability: every later frame the broker sends is
+	// a 4-byte big-endian length followed by the JSON document, with no
+	// newline. Empty means NDJSON. Clients keep sending NDJSON.
+	Framing string `json:"framing,omitempty"`
+	// Compression is "gzip" in the meta opening a connection whose hello
+	// listed that capability, when the broker compresses: the bytes after
+	// this meta are a gzip stream, flushed after every write, holding the
+	// frames. Empty means uncompressed.
+	Compression string `json:"compression,omitempty"`
+}
+
+// ProtocolVersion is the version of the frame protocol spoken by this
+// package. It grows when frame types or fields are added.
+const ProtocolVersion = 2
+
+// legacyCapabilities are what a broker sending no Version serves.
+var legacyCapabilities = []string{"lines", "history", "stats"}
+
+// Supports reports whether the broker that sent m serves capability c.
+func (m Meta) Supports(c string) bool {
+	if m.Version == 0 {
+		return slices.Contains(legacyCapabilities, c)
+	}
+	return slices.Contains(m.Capabilities, c)
+}
+
+// Hello is the first frame a client sends: its protocol version and the
+// optional frame types and fields it understands. The broker only sends a
+// client that said hello what it declared; clients that send none get
+// what brokers sent before version 2. Version 2 clients detect lines the
+// broker dropped for them from gaps in Line.Seq, so they get no notice
+// unless they sent a Filter.
+type Hello struct {
+	Type         string   `json:"type"`
+	Version      int      `json:"version"`
+	Capabilities []string `json:"capabilities,omitempty"`
+	// Client names the client software, for logs and stats.
+	Client string `json:"client,omitempty"`
+	// SinceUs, if set, limits the replayed ring to lines stamped after it,
+	// such as the newest line a reconnecting client already has.
+	SinceUs int64 `json:"since_us,omitempty"`
+	// Token authenticates the client to a broker with
+	// BrokerOptions.Authenticate.
+	Token string `json:"token,omitempty"`
 }
 
 // Line carries a single console line with its original timestamp and a coarse level.
@@ -57,6 +150,58 @@ type Line struct {
 	TsUs  int64  `json:"ts_us"`
 	Text  string `json:"text"`
 	Level string `json:"level"`
+	// Seq is a stable, broker-assigned line ID, increasing by one per line.
+	Seq uint64 `json:"seq,omitempty"`
+	// Spans are highlight ranges computed by the broker when
+	// BrokerOptions.ComputeSpans is set.
+	Spans []Span `json:"spans,omitempty"`
+	// Source names the producer of the line when it came from a Source.
+	Source string `json:"source,omitempty"`
+	// Channel names the stream the line belongs to, e.g. "leases" or
+	// "errors"; clients can show one channel at a time. Empty is the
+	// default channel.
+	Channel string `json:"channel,omitempty"`
+	// Fields are structured key/value data attached to the line. Clients
+	// can show them as columns and filter on them.
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// Lines carries several consecutive line events in a single frame. The broker
+// uses it to flush bursts to a client with one write.
+type Lines struct {
+	Type  string `json:"type"`
+	Lines []Line `json:"lines"`
+}
+
+// HistoryRequest is sent by a client to ask for lines older than BeforeUs,
+// typically when the user scrolls to the top of the local buffer. BeforeSeq,
+// when set, asks for the lines before that ID instead, so lines sharing the
+// oldest one's microsecond are not skipped; brokers predating it use
+// BeforeUs.
+type HistoryRequest struct {
+	Type      string `json:"type"`
+	BeforeUs  int64  `json:"before_us"`
+	BeforeSeq uint64 `json:"before_seq,omitempty"`
+	Limit     int    `json:"limit"`
+}
+
+// Publish is sent by a client to append a line through the broker. TsUs of
+// zero means the time the broker received it; an empty Source defaults to
+// the client's ID.
+type Publish struct {
+	Type    string `json:"type"`
+	Text    string `json:"text"`
+	TsUs    int64  `json:"ts_us,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Channel string `json:"channel,omitempty"`
+}
+
+// History answers a HistoryRequest with older lines, oldest first. More is
+// set when the broker holds further lines before the first one returned.
+type History struct {
+	Type  string `json:"type"`
+	More  bool   `json:"more"`
+	Lines []Line `json:"lines"`
 }
 
 // Notice informs a slow client that some lines were dropped locally.
@@ -92,3 +237,84 @@ func LevelOf(s string) string {
 	}
 	return "info"
 }
+
+// Command carries operator input typed into an attached client. The broker
+// passes Text to BrokerOptions.OnCommand.
+type Command struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Filter asks the broker to send the client only the lines passing Text, a
+// filter as typed in the UI, such as "OFFER && 192.168.10" or "-keepalive".
+// It is matched against the line text with its "[source] " prefix, and time
+// terms such as "since:10m" against the line's timestamp. An empty Text
+// restores the full stream.
+type Filter struct {
+	Type          string `json:"type"`
+	Text          string `json:"text,omitempty"`
+	CaseSensitive bool   `json:"case_sensitive,omitempty"`
+	Regex         bool   `json:"regex,omitempty"`
+}
+
+// CounterValues carries the broker's evaluation of its Config.Counters. It is
+// sent after the replay and then every BrokerOptions.CounterInterval to
+// clients whose hello lists "counters", so every viewer shows the same
+// numbers, including those counted before it attached.
+type CounterValues struct {
+	Type     string         `json:"type"`
+	Counters []CounterValue `json:"counters"`
+}
+
+// CounterValue is the state of the counter with Label and Match.
+type CounterValue struct {
+	Label string `json:"label"`
+	Match string `json:"match"`
+	Count int    `json:"count"` // matches within the window
+	Total uint64 `json:"total"`
+	// Display is the value as shown in the bars, e.g. "42 ▁▂▄▇" or
+	// "avg 12ms p95 40ms".
+	Display string `json:"display"`
+	// Alert is set while the counter is over its threshold.
+	Alert bool `json:"alert,omitempty"`
+}
+
+// Viewers tells clients whose hello lists "viewers" how many clients are
+// attached, themselves included. It is sent after the replay and whenever
+// the number changes.
+type Viewers struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+// StatsRequest asks the broker for a Stats frame.
+type StatsRequest struct {
+	Type string `json:"type"`
+}
+
+// ClientStats describes one attached client's delivery health.
+type ClientStats struct {
+	ID          uint64 `json:"id"`
+	RemoteAddr  string `json:"remote_addr"`
+	ConnectedUs int64  `json:"connected_us"`
+	// Dropped counts line frames discarded because the client fell behind.
+	Dropped uint64 `json:"dropped"`
+	// QueueLen and QueueHighWater are the current and deepest send queue
+	// length, in frames, out of QueueCap.
+	QueueLen       int `json:"queue_len"`
+	QueueHighWater int `json:"queue_high_water"`
+	QueueCap       int `json:"queue_cap"`
+}
+
+// Stats reports broker and per-client delivery statistics. It is returned by
+// Broker.Stats and sent as a frame in answer to a StatsRequest.
+type Stats struct {
+	Type    string        `json:"type"`
+	Lines   uint64        `json:"lines"` // lines appended since start
+	Clients []ClientStats `json:"clients"`
+	// SlowDisconnects counts clients disconnected by the DisconnectSlow
+	// policy.
+	SlowDisconnects uint64 `json:"slow_disconnects,omitempty"`
+	// SentBytes counts the bytes of line frames queued to clients.
+	SentBytes uint64 `json:"sent_bytes,omitempty"`
+}
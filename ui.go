@@ -2,15 +2,21 @@
 package console
 
 import (
-	"bufio"
+	"cmp"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"net"
 	"os"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
@@ -25,6 +31,114 @@ type UIOptions struct {
 	NoColour      bool
 	MouseEnabled  bool
 	DisableTopBar bool // false = show top bar (Title | Counters); true = legacy: no top bar
+
+	// OnFilterChange is called whenever the filter text, its active state, or
+	// case sensitivity changes. It runs on the UI goroutine.
+	OnFilterChange func(filter string, active, caseSensitive bool)
+
+	// Application, when set, embeds the console into an existing tview
+	// application owned by the caller. The UI then neither sets the app root
+	// nor stops the app on exit; place Primitive() in your own layout instead.
+	Application *tview.Application
+
+	// SmartCase starts in smart-case filtering: an all-lowercase filter is
+	// case-insensitive, any uppercase letter makes it case-sensitive.
+	SmartCase bool
+
+	// NewestFirst renders the buffer newest-at-top with autoscroll pinned to
+	// the top of the view.
+	NewestFirst bool
+
+	// FoldGroups starts with continuation lines (indented lines, Go panic
+	// traces) collapsed under their parent line.
+	FoldGroups bool
+
+	// TimestampLayouts, when set, are tried against the start of lines that
+	// carry no timestamp of their own (see ParseTimestampPrefix). A match
+	// replaces the arrival time for counters and display.
+	TimestampLayouts []string
+
+	// Palette names the built-in palette styling the bars, badges, input and
+	// levels, such as "dark" or "light" (see PaletteByName). When set it
+	// overrides Config.Palette.
+	Palette string
+
+	// AutoPauseErrors, when positive, pauses autoscroll as soon as more
+	// than this many error-level lines arrive within AutoPauseWindow
+	// (default 10s), so the context of a burst stays on screen.
+	AutoPauseErrors int
+	AutoPauseWindow time.Duration
+
+	// CopyMaxBytes caps the text :copy puts on the clipboard; larger sets
+	// are written to a temp file. Default DefaultCopyMaxBytes.
+	CopyMaxBytes int
+
+	// Messages overrides entries of DefaultMessages by ID, to translate or
+	// re-word the UI.
+	Messages map[string]string
+
+	// FilterRegex starts with the filter text treated as a regular
+	// expression; the e key toggles it.
+	FilterRegex bool
+
+	// Columns names structured fields (Line.Fields) shown as aligned
+	// columns before each message; see also the :columns command.
+	Columns []string
+
+	// CustomPalette, if set, replaces the palette selected by Palette.
+	CustomPalette *Palette
+
+	// Keys rebinds log view actions, named as in KeyActions; a zero rune
+	// disables the action. See also LoadConfig.
+	Keys map[string]rune
+
+	// NoLevelColours leaves error, warning and debug lines in the default
+	// colours instead of their palette styles.
+	NoLevelColours bool
+
+	// KeepScrollOnResume leaves the view where it was when autoscroll
+	// resumes, instead of jumping to the lines appended while paused.
+	KeepScrollOnResume bool
+
+	// OnThreshold is called when a counter goes over its
+	// CounterSpec.Threshold, with the count in its threshold window. It is
+	// called again only after the count has dropped back. It runs on the
+	// goroutine appending the line.
+	OnThreshold func(spec CounterSpec, count int)
+
+	// Completer, if set, offers completions for the last word of a filter
+	// or command argument when Tab is pressed in the input field, such as
+	// the MAC addresses or hostnames an application knows about. Words not
+	// starting with prefix are ignored. It runs on the UI goroutine.
+	Completer func(prefix string) []string
+
+	// HistoryPath, if set, names a state file where the filters, searches
+	// and commands entered in the input field are kept between sessions,
+	// one per line. Up and Down browse them either way.
+	HistoryPath string
+
+	// Presets are named filters selected with Alt+1–9 in the log view;
+	// the plain number keys keep toggling levels. Pressing the key of the
+	// active preset clears it. See also FileConfig.Presets.
+	Presets []FilterPreset
+
+	// Timestamps starts with a timestamp column before each line: one of
+	// TimestampsAbsolute, TimestampsRelative or TimestampsDelta. The t key
+	// cycles through them and off. Lines are stamped with their broker
+	// timestamp, a TimestampLayouts prefix or their arrival time.
+	Timestamps string
+	// TimestampFormat is the time layout of absolute timestamps; default
+	// DefaultTimestampFormat. They are shown in local time, or in UTC with
+	// TimestampUTC.
+	TimestampFormat string
+	TimestampUTC    bool
+
+	// Wrap starts with long lines wrapped at the edge of the log view
+	// instead of cut off there; the W key toggles it. Unwrapped lines
+	// scroll sideways with Left and Right. Selection, search stepping,
+	// :goto, the inspector and correlation need lines cut off and are off
+	// while wrapping.
+	Wrap bool
 }
 
 type counterRule struct {
@@ -32,13 +146,84 @@ type counterRule struct {
 	caseSensitive bool
 	label         string
 	window        time.Duration
+	showTotal     bool
 	// rolling timestamps (most recent kept)
-	times []time.Time
+	times   []time.Time
+	total   uint64       // matches since the rule was registered
+	matcher *textMatcher // nil when match is empty
+	invalid bool         // match or extract failed to compile
+	// numeric extraction (CounterSpec.Extract); values parallel times
+	extract *regexp.Regexp
+	unit    string
+	values  []float64
+	// grouping by named captures of a regex match; byKey holds the
+	// rolling timestamps per captured value
+	groups []int
+	byKey  map[string][]time.Time
+	topN   int
+	// threshold alerts (CounterSpec.Threshold)
+	spec            CounterSpec
+	threshold       int
+	thresholdWindow time.Duration
+	alerting        bool          // over the threshold when last checked
+	remote          *CounterValue // the broker's evaluation, shown instead
+	sparkline       int           // intervals drawn by sparkline, 0 for none
+}
+
+func newCounterRule(spec CounterSpec) *counterRule {
+	window := spec.WindowSeconds
+	if window <= 0 {
+		window = 60
+	}
+	c := &counterRule{
+		match:         spec.Match,
+		caseSensitive: spec.CaseSensitive,
+		label:         spec.Label,
+		window:        time.Duration(window) * time.Second,
+		showTotal:     spec.ShowTotal,
+		unit:          spec.Unit,
+		spec:          spec,
+		threshold:     spec.Threshold,
+		sparkline:     max(spec.Sparkline, 0),
+	}
+	c.thresholdWindow = c.window
+	if spec.ThresholdWindow > 0 {
+		c.thresholdWindow = time.Duration(spec.ThresholdWindow) * time.Second
+	}
+	if spec.Match != "" {
+		m, err := newTextMatcher(spec.Match, spec.CaseSensitive, spec.Regex)
+		c.matcher = m
+		c.invalid = err != nil
+	}
+	if spec.Extract != "" {
+		re, err := regexp.Compile(spec.Extract)
+		c.extract = re
+		c.invalid = c.invalid || err != nil
+	}
+	if c.matcher != nil && c.matcher.re != nil && c.extract == nil {
+		for i, name := range c.matcher.re.SubexpNames() {
+			if name != "" {
+				c.groups = append(c.groups, i)
+			}
+		}
+		if c.groups != nil {
+			c.byKey = make(map[string][]time.Time)
+			c.topN = cmp.Or(max(spec.TopN, 0), 3)
+		}
+	}
+	return c
+}
+
+// hit records one match at when.
+func (c *counterRule) hit(when time.Time) {
+	c.times = append(c.times, when)
+	c.total++
 }
 
 type highlightRule struct {
 	match         string
 	caseSensitive bool
+	matcher       *textMatcher // nil if match is empty or invalid
 	// Either style or styler is used. If both are set, styler wins.
 	style  *Style
 	styler func(s string, noColour bool) string
@@ -53,28 +238,111 @@ type UI struct {
 	topSep     *tview.TextView
 	bottomSep  *tview.TextView
 	topBar     *tview.TextView // top bar with Title (left) | Counters (right)
-	root       tview.Primitive
+	root       *tview.Flex
+	footer     *tview.Flex  // input field above status bar
+	pages      *tview.Pages // root page plus modal overlays
+	ownsApp    bool
 	modal      tview.Primitive
 	prevFocus  tview.Primitive
 
 	// state
-	mu          sync.Mutex
-	counterMu   sync.Mutex
-	hlMu        sync.Mutex
-	lines       []string
-	helpExtra   []string
-	counters    []*counterRule
-	highlights  []*highlightRule
-	filter      string
-	title       string
-	maxLines    int
-	onExit      func(int)
+	mu                  sync.Mutex
+	counterMu           sync.Mutex
+	hlMu                sync.Mutex
+	lines               []logLine
+	helpExtra           []string
+	counters            []*counterRule
+	highlights          []*highlightRule
+	filter              string
+	title               string
+	maxLines            int
+	onExit              func(int)
+	onFilterChange      func(filter string, active, caseSensitive bool)
+	onThreshold         func(CounterSpec, int)
+	completer           func(prefix string) []string
+	history             []string // filters and commands entered, oldest first
+	historyPos          int      // entry shown while browsing with Up/Down, or -1
+	historyDraft        string   // input text before browsing began
+	historyPath         string
+	presets             []FilterPreset
+	preset              string          // name of the preset last applied, until the filter is edited
+	tsMode              string          // timestamp column, one of timestampModes
+	tsLayout            string          // layout of absolute timestamps
+	tsUTC               bool            // absolute timestamps in UTC rather than local time
+	tsBase              time.Time       // the first line's time, for relative timestamps
+	tsPrev              time.Time       // the last row rendered, for delta timestamps
+	wrap                bool            // long lines wrap instead of scrolling sideways
+	stats               streamStats     // lines received, for the statistics panel
+	statsView           *tview.TextView // the statistics panel's text, once opened
+	statsPanel          tview.Primitive // the statistics modal, while it may be open
+	statsShown          time.Time       // when statsView was last refreshed
 	filterActive        bool
 	filterCaseSensitive bool
-	paused              bool
-	mouseOn             bool
-	noColour            bool
-	topBarEnabled       bool // derived from !opts.DisableTopBar
+	smartCase           bool
+	filterRegex         bool // filter text is a regular expression
+	newestFirst         bool
+	folded              bool
+	showIDs             bool
+	diffMode            bool
+	statusMsg           string
+	statusSeq           int // invalidates pending status message clears
+	timestampLayouts    []string
+
+	// history backfill (set by Attach)
+	onNeedHistory    func(beforeUs int64, beforeSeq uint64)
+	onStatsRequest   func()           // asks the broker for a stats frame
+	onCommand        func(cmd string) // sends a command to the broker
+	onServerFilter   func(f Filter)   // sends the filter to the broker
+	serverFilter     bool             // F: have the broker apply the filter
+	sentFilter       Filter           // filter the broker applies, without Type
+	server           Meta             // last meta from the broker, for its capabilities
+	viewers          int              // clients attached to the broker; 0 if not told
+	historyPending   bool
+	historyExhausted bool
+	historyFloor     uint64 // newest line ID cleared; backfill stops above it
+	extraHistory     int    // lines kept beyond maxLines because they were backfilled
+	paused           bool
+	pendingCount     int  // rows appended while paused, for the status bar
+	scrolledAway     bool // the user scrolled off the newest lines; End follows again
+	keepScroll       bool // UIOptions.KeepScrollOnResume
+	mouseOn          bool
+	noColour         bool
+	topBarEnabled    bool // derived from !opts.DisableTopBar
+	width            int  // root width at the last draw, 0 before the first
+	palette          Palette
+	palettePinned    bool // set by UIOptions.Palette; Config.Palette is ignored
+	burstLimit       int
+	burstWindow      time.Duration
+	errorTimes       []time.Time // recent error lines, for auto-pause
+	burstPaused      bool        // paused by an error burst, until resumed
+	reconnecting     bool        // attach lost the broker and is redialling
+	player           *Player     // set when replaying a session file
+	channels         []string    // named channels seen, in order of arrival
+	channel          string      // channel shown, "" for all
+	columns          []string    // fields shown as columns
+	colWidths        []int       // widest value seen per column
+	whereKey         string      // field filter, "" for none
+	whereValue       string
+	hiddenLevels     map[string]bool // level classes filtered out
+	levelColours     bool
+	keyRemap         map[rune]rune // pressed key to default key; see Keys
+	search           *textMatcher  // in-place search, nil when off
+	searchText       string
+	searchRow        int // display row of the last match jumped to
+	copyMaxBytes     int
+	messages         map[string]string
+	nextOrd          uint64   // last logLine.ord assigned
+	shownRows        []rowKey // rows in the log view, in display order
+	stale            int      // leading shownRows whose lines were trimmed
+	pendingRows      []displayRow
+	needFull         bool // pendingRows is incomplete; repaint everything
+	selecting        bool // rows from selAnchor to selCursor are selected
+	selAnchor        rowKey
+	selCursor        rowKey
+	dragging         bool // the left button went down on dragFrom
+	dragFrom         rowKey
+	screen           tcell.Screen // drawn to last, for OSC 52 copies
+	sizeClass        sizeClass
 }
 
 // New creates a new console UI with the given options.
@@ -87,29 +355,71 @@ func NewUI(opts UIOptions) *UI {
 		effectiveMax = DefaultMaxLines
 	}
 	u := &UI{
-		lines:         make([]string, 0, effectiveMax),
-		maxLines:      effectiveMax,
-		mouseOn:       opts.MouseEnabled,
-		noColour:      opts.NoColour,
-		helpExtra:     append([]string(nil), opts.HelpExtra...),
-		topBarEnabled: !opts.DisableTopBar,
+		lines:            make([]logLine, 0, effectiveMax),
+		maxLines:         effectiveMax,
+		mouseOn:          opts.MouseEnabled,
+		noColour:         opts.NoColour,
+		helpExtra:        append([]string(nil), opts.HelpExtra...),
+		topBarEnabled:    !opts.DisableTopBar,
+		onFilterChange:   opts.OnFilterChange,
+		onThreshold:      opts.OnThreshold,
+		completer:        opts.Completer,
+		history:          loadHistory(opts.HistoryPath),
+		historyPos:       -1,
+		historyPath:      opts.HistoryPath,
+		presets:          append([]FilterPreset(nil), opts.Presets...),
+		tsMode:           opts.Timestamps,
+		tsLayout:         cmp.Or(opts.TimestampFormat, DefaultTimestampFormat),
+		tsUTC:            opts.TimestampUTC,
+		wrap:             opts.Wrap,
+		smartCase:        opts.SmartCase,
+		newestFirst:      opts.NewestFirst,
+		folded:           opts.FoldGroups,
+		timestampLayouts: append([]string(nil), opts.TimestampLayouts...),
+		palette:          PaletteDefault,
+		burstLimit:       opts.AutoPauseErrors,
+		burstWindow:      opts.AutoPauseWindow,
+		copyMaxBytes:     opts.CopyMaxBytes,
+		messages:         mergeMessages(opts.Messages),
+		filterRegex:      opts.FilterRegex,
+		keyRemap:         keyRemap(opts.Keys),
+		hiddenLevels:     make(map[string]bool),
+		levelColours:     !opts.NoLevelColours,
+		keepScroll:       opts.KeepScrollOnResume,
+	}
+	if u.copyMaxBytes <= 0 {
+		u.copyMaxBytes = DefaultCopyMaxBytes
+	}
+	if u.burstWindow <= 0 {
+		u.burstWindow = 10 * time.Second
+	}
+	u.setColumnsLocked(append([]string(nil), opts.Columns...))
+	if p, ok := PaletteByName(opts.Palette); ok && opts.Palette != "" {
+		u.palette = p
+		u.palettePinned = true
+	}
+	if opts.CustomPalette != nil {
+		u.palette = *opts.CustomPalette
+		u.palettePinned = true
 	}
 
-	if opts.OnExit != nil {
-		u.onExit = func(code int) {
+	u.ownsApp = opts.Application == nil
+	u.onExit = func(code int) {
+		if u.ownsApp {
 			u.app.EnableMouse(false)
 			u.app.Stop()
-			opts.OnExit(code)
 		}
-	} else {
-		u.onExit = func(code int) {
-			u.app.EnableMouse(false)
-			u.app.Stop()
+		if opts.OnExit != nil {
+			opts.OnExit(code)
 		}
 	}
 
-	u.app = tview.NewApplication()
-	u.logView = tview.NewTextView().SetScrollable(true).SetWrap(false)
+	if u.ownsApp {
+		u.app = tview.NewApplication()
+	} else {
+		u.app = opts.Application
+	}
+	u.logView = tview.NewTextView().SetScrollable(true).SetWrap(u.wrap)
 	u.inputField = tview.NewInputField().SetLabel("> ").SetFieldWidth(0)
 	u.statusText = tview.NewTextView().SetWrap(false)
 	u.topSep = tview.NewTextView().SetWrap(false)
@@ -120,37 +430,35 @@ func NewUI(opts UIOptions) *UI {
 	u.logView.SetDynamicColors(!u.noColour)
 	u.statusText.SetDynamicColors(!u.noColour)
 	u.topBar.SetDynamicColors(!u.noColour)
+	u.topSep.SetDynamicColors(!u.noColour)
+	u.bottomSep.SetDynamicColors(!u.noColour)
 
 	// layout
-	var root *tview.Flex
+	u.footer = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(u.inputField, 1, 0, true).
+		AddItem(u.statusText, 1, 0, false)
+	root := tview.NewFlex().SetDirection(tview.FlexRow)
 	if u.topBarEnabled {
-		root = tview.NewFlex().SetDirection(tview.FlexRow).
-			AddItem(u.topBar, 1, 0, false).
-			AddItem(u.logView, 0, 1, false).
-			AddItem(u.bottomSep, 1, 0, false).
-			AddItem(
-				tview.NewFlex().SetDirection(tview.FlexRow).
-					AddItem(u.inputField, 1, 0, true).
-					AddItem(u.statusText, 1, 0, false),
-				2, 0, true)
+		root.AddItem(u.topBar, 1, 0, false)
 	} else {
-		root = tview.NewFlex().SetDirection(tview.FlexRow).
-			AddItem(u.topSep, 1, 0, false).
-			AddItem(u.logView, 0, 1, false).
-			AddItem(u.bottomSep, 1, 0, false).
-			AddItem(
-				tview.NewFlex().SetDirection(tview.FlexRow).
-					AddItem(u.inputField, 1, 0, true).
-					AddItem(u.statusText, 1, 0, false),
-				2, 0, true)
+		root.AddItem(u.topSep, 1, 0, false)
 	}
+	root.AddItem(u.logView, 0, 1, false).
+		AddItem(u.bottomSep, 1, 0, false).
+		AddItem(u.footer, 2, 0, true)
+	root.SetDrawFunc(u.layoutForSize)
 	u.root = root
+	u.pages = tview.NewPages().AddPage("main", u.root, true, true)
 
 	// behavior
 	u.bindKeys()
-	u.app.EnableMouse(u.mouseOn)
-	u.app.SetRoot(u.root, true)
-	u.app.SetFocus(u.inputField)
+	u.logView.SetMouseCapture(u.selectMouse)
+	if u.ownsApp {
+		u.app.EnableMouse(u.mouseOn)
+		u.app.SetRoot(u.pages, true)
+		u.app.SetFocus(u.inputField)
+	}
+	u.applyChrome()
 	u.setLogSeparators(false) // input focused
 
 	// Apply initial rules/config if provided.
@@ -171,48 +479,70 @@ func NewUI(opts UIOptions) *UI {
 	return u
 }
 
+// Primitive returns the console layout so it can be placed inside another
+// tview layout. Modals such as help are drawn within this primitive.
+func (u *UI) Primitive() tview.Primitive {
+	return u.pages
+}
+
+// Run runs the UI's own tview application and blocks until it exits.
+// It returns an error when the UI was created with UIOptions.Application.
+func (u *UI) Run() error {
+	if !u.ownsApp {
+		return errors.New("console: UI is embedded; run the owning application instead")
+	}
+	return u.app.Run()
+}
+
 // ApplyConfig replaces current counters, highlights, and max-lines settings with cfg.
 func (u *UI) ApplyConfig(cfg Config) {
 	if cfg.MaxLines > 0 {
 		u.mu.Lock()
-		u.maxLines = cfg.MaxLines
-		if len(u.lines) > u.maxLines {
-			u.lines = append([]string(nil), u.lines[len(u.lines)-u.maxLines:]...)
+		u.setMaxLinesLocked(cfg.MaxLines)
+		u.mu.Unlock()
+	}
+	if p, ok := PaletteByName(cfg.Palette); ok && cfg.Palette != "" {
+		u.mu.Lock()
+		if !u.palettePinned {
+			u.palette = p
 		}
 		u.mu.Unlock()
 	}
 
 	counterRules := make([]*counterRule, 0, len(cfg.Counters))
 	for _, spec := range cfg.Counters {
-		window := spec.WindowSeconds
-		if window <= 0 {
-			window = 60
-		}
-		counterRules = append(counterRules, &counterRule{
-			match:         spec.Match,
-			caseSensitive: spec.CaseSensitive,
-			label:         spec.Label,
-			window:        time.Duration(window) * time.Second,
-		})
+		counterRules = append(counterRules, newCounterRule(spec))
 	}
 	u.counterMu.Lock()
+	// keep history for counters that survive a config update, so running
+	// totals are not reset when a server re-sends its meta
+	for _, cr := range counterRules {
+		for _, old := range u.counters {
+			if cr.sameRule(old) {
+				cr.inherit(old)
+				cr.remote = old.remote
+				break
+			}
+		}
+	}
 	u.counters = counterRules
 	u.counterMu.Unlock()
 
-	highlightRules := make([]*highlightRule, 0, len(cfg.Highlights))
-	for _, spec := range cfg.Highlights {
-		hr := &highlightRule{match: spec.Match, caseSensitive: spec.CaseSensitive}
-		if spec.Style != nil {
-			st := *spec.Style
-			hr.style = &st
-		}
-		highlightRules = append(highlightRules, hr)
-	}
+	highlightRules := highlightRulesOf(cfg)
 	u.hlMu.Lock()
 	u.highlights = highlightRules
 	u.hlMu.Unlock()
 
+	// highlights and the palette may have changed under rendered rows
 	u.Do(func() {
+		u.applyChrome()
+		u.setLogSeparators(u.app.GetFocus() == u.logView)
+		u.mu.Lock()
+		paused := u.paused
+		u.mu.Unlock()
+		if !paused {
+			u.repaintLogDirect()
+		}
 		if u.topBarEnabled {
 			u.updateTopBarDirect()
 		}
@@ -230,9 +560,10 @@ func (u *UI) SetTitle(s string) {
 	}
 }
 
-// Append appends a new line to the console UI (client side only).
+// Append appends a new line to the console UI (client side only). Its time
+// is taken from a TimestampLayouts prefix if one matches, else arrival time.
 func (u *UI) Append(line string) {
-	u.appendWithWhen(time.Now(), line)
+	u.appendWithWhen(time.Time{}, line)
 }
 
 // Appendf is like Append but with formatting.
@@ -243,33 +574,88 @@ func (u *UI) Appendf(format string, args ...any) { u.Append(fmt.Sprintf(format,
 // Each time a line is appended that contains the match string, the counter is
 // incremented. The status bar shows the count of matches within the rolling window.
 func (u *UI) RegisterCounter(match string, caseSensitive bool, label string, windowSeconds int) {
-	if windowSeconds <= 0 {
-		windowSeconds = 60
-	}
+	u.RegisterCounterSpec(CounterSpec{Match: match, CaseSensitive: caseSensitive, Label: label, WindowSeconds: windowSeconds})
+}
+
+// RegisterCounterSpec adds a counter described by spec, e.g. one that also
+// shows its running total.
+func (u *UI) RegisterCounterSpec(spec CounterSpec) {
 	u.counterMu.Lock()
 	defer u.counterMu.Unlock()
-	u.counters = append(u.counters, &counterRule{
-		match:         match,
-		caseSensitive: caseSensitive,
-		label:         label,
-		window:        time.Duration(windowSeconds) * time.Second,
-	})
+	u.counters = append(u.counters, newCounterRule(spec))
 }
 
-// Tick increments the counter with the given label by one.
+// Tick increments the counter with the given label by one. Extraction
+// counters, which need a value with each sample, are not ticked.
 func (u *UI) Tick(label string) {
 	now := time.Now()
 	u.counterMu.Lock()
-	defer u.counterMu.Unlock()
 	for _, c := range u.counters {
-		if c.label == label {
-			c.times = append(c.times, now)
+		if c.label == label && c.extract == nil {
+			c.hit(now)
 			break
 		}
 	}
-	if u.topBarEnabled {
-		u.updateTopBarDirect()
+	alerts := thresholdAlerts(u.counters, now)
+	u.counterMu.Unlock()
+	u.fireThresholds(alerts)
+	u.Do(func() {
+		if u.topBarEnabled {
+			u.updateTopBarDirect()
+		} else {
+			u.updateBottomBarDirect()
+		}
+	})
+}
+
+// setCounterValues shows the broker's counter values in place of the
+// locally evaluated ones.
+func (u *UI) setCounterValues(vals []CounterValue) {
+	u.counterMu.Lock()
+	for _, v := range vals {
+		for _, c := range u.counters {
+			if c.label == v.Label && c.match == v.Match {
+				c.remote = &v
+				break
+			}
+		}
+	}
+	alerts := thresholdAlerts(u.counters, time.Now())
+	u.counterMu.Unlock()
+	u.fireThresholds(alerts)
+	u.Do(func() {
+		if u.topBarEnabled {
+			u.updateTopBarDirect()
+		} else {
+			u.updateBottomBarDirect()
+		}
+	})
+}
+
+// setViewers shows how many clients are attached to the broker.
+func (u *UI) setViewers(n int) {
+	u.mu.Lock()
+	u.viewers = n
+	u.mu.Unlock()
+	u.Do(func() {
+		if u.topBarEnabled {
+			u.updateTopBarDirect()
+		} else {
+			u.updateBottomBarDirect()
+		}
+	})
+}
+
+// viewersSnapshot renders the number of attached clients for the counter
+// bar, or "" when the broker did not send it.
+func (u *UI) viewersSnapshot() string {
+	u.mu.Lock()
+	n := u.viewers
+	u.mu.Unlock()
+	if n == 0 {
+		return ""
 	}
+	return " | " + tagStyle(u.msg("badge.viewers", n), u.currentPalette().Counter, u.noColour)
 }
 
 // HighlightMap registers a highlight rule with the given match string (substring),
@@ -279,11 +665,14 @@ func (u *UI) Tick(label string) {
 func (u *UI) HighlightMap(match string, caseSensitive bool, style Style) {
 	u.hlMu.Lock()
 	defer u.hlMu.Unlock()
+	m, _ := newTextMatcher(match, caseSensitive, false)
 	u.highlights = append(u.highlights, &highlightRule{
 		match:         match,
 		caseSensitive: caseSensitive,
+		matcher:       m,
 		style:         &style,
 	})
+	u.invalidateRows()
 }
 
 // HighlightMapFunc registers a rule with a custom styler.
@@ -291,11 +680,14 @@ func (u *UI) HighlightMap(match string, caseSensitive bool, style Style) {
 func (u *UI) HighlightMapFunc(match string, caseSensitive bool, styler func(s string, noColour bool) string) {
 	u.hlMu.Lock()
 	defer u.hlMu.Unlock()
+	m, _ := newTextMatcher(match, caseSensitive, false)
 	u.highlights = append(u.highlights, &highlightRule{
 		match:         match,
 		caseSensitive: caseSensitive,
+		matcher:       m,
 		styler:        styler,
 	})
+	u.invalidateRows()
 }
 
 // MakeTagStyler returns a styler that wraps text with a tview tag [fg:bg:attrs]..[-:-:-].
@@ -313,68 +705,306 @@ func MakeTagStyler(fg, bg, attrs string) func(s string, noColour bool) string {
 // ---- internals ----
 
 // appendWithWhen is the internal implementation for Append with a provided timestamp.
-// Used by the client to preserve server-side timestamps for counters.
+// Used by the client to preserve server-side timestamps for counters. A zero
+// when means the line carries no timestamp.
 func (u *UI) appendWithWhen(when time.Time, line string) {
+	u.appendTimed([]timedLine{{when: when, text: line, level: LevelClass(LevelOf(line))}})
+}
+
+// timedLine is a line waiting to be appended together with its timestamp.
+type timedLine struct {
+	when time.Time
+	text string
+	tsUs int64  // server timestamp, 0 for local lines
+	seq  uint64 // broker line ID, 0 for local lines
+	// channel is the broker channel, "" for the default one
+	channel string
+	fields  map[string]string
+	level   string // level class; detected from text when empty
+	source  string // Line.Source, whose prefix is tinted
+}
+
+// appendTimed appends a batch of lines and repaints once for the whole batch.
+func (u *UI) appendTimed(batch []timedLine) {
+	if len(batch) == 0 {
+		return
+	}
+	now := time.Now()
+	for i := range batch {
+		if batch[i].when.IsZero() {
+			batch[i].when = u.textTime(batch[i].text, now)
+		}
+		if batch[i].level == "" {
+			batch[i].level = LevelClass(LevelOf(batch[i].text))
+		}
+	}
 	u.mu.Lock()
-	u.lines = append(u.lines, line)
-	if len(u.lines) > u.maxLines {
-		u.lines = u.lines[len(u.lines)-u.maxLines:]
+	for _, tl := range batch {
+		u.stats.add(tl.text, tl.level, now)
+	}
+	// rows that can be written below the current view without a repaint
+	inc := u.incrementalLocked()
+	match := u.lineMatcherLocked()
+	for _, tl := range batch {
+		var last *logLine
+		if n := len(u.lines); n > 0 {
+			last = &u.lines[n-1]
+		}
+		if tl.fields == nil && isContinuation(last, tl.channel, tl.text) {
+			last.cont = append(last.cont, subLine{text: tl.text, seq: tl.seq})
+			if u.folded {
+				inc = false // the group's folded row changes
+			} else if (inc || u.paused) && u.groupShownLocked(last) && (match == nil || match(tl.text, last.when)) {
+				if u.paused {
+					u.pendingCount++
+				} else {
+					u.pendingRows = append(u.pendingRows, displayRow{text: u.contTextLocked(tl.text), seq: tl.seq, key: rowKey{last.ord, len(last.cont) - 1}, level: last.level, when: last.when})
+				}
+			}
+			continue
+		}
+		if u.tsBase.IsZero() {
+			u.tsBase = tl.when
+		}
+		u.nextOrd++
+		u.lines = append(u.lines, logLine{text: tl.text, when: tl.when, tsUs: tl.tsUs, seq: tl.seq, ord: u.nextOrd, channel: tl.channel, fields: tl.fields, level: tl.level, source: tl.source})
+		u.addChannelLocked(tl.channel)
+		if u.widenColumnsLocked(tl.fields) {
+			inc = false // earlier rows need the new column width
+		}
+		l := &u.lines[len(u.lines)-1]
+		if text := u.rowTextLocked(l); (inc || u.paused) && u.groupShownLocked(l) && (match == nil || match(text, tl.when)) {
+			if u.paused {
+				u.pendingCount++
+			} else {
+				u.pendingRows = append(u.pendingRows, displayRow{text: text, seq: tl.seq, key: rowKey{u.nextOrd, -1}, level: tl.level, source: tl.source, when: tl.when})
+			}
+		}
+	}
+	if trim := len(u.lines) - (u.maxLines + u.extraHistory); trim > 0 {
+		for i := range u.lines[:trim] {
+			u.dropShownLocked(u.lines[i].ord)
+		}
+		u.lines = u.lines[trim:]
+		// the oldest lines are the backfilled ones, so new lines use up
+		// their allowance
+		u.extraHistory = max(0, u.extraHistory-trim)
+	}
+	if !inc {
+		u.needFull = true
+		u.pendingRows = nil
 	}
+	// render this batch even if it trips the burst pause, so the lines
+	// that caused it are on screen
 	paused := u.paused
+	u.checkErrorBurstLocked(batch, now)
 	u.mu.Unlock()
 
 	// counters: scan matchers quickly
 	u.counterMu.Lock()
-	if len(u.counters) > 0 {
+	for _, tl := range batch {
 		for _, cr := range u.counters {
-			if cr.match == "" {
-				continue
-			}
-			if cr.caseSensitive {
-				if strings.Contains(line, cr.match) {
-					cr.times = append(cr.times, when)
-				}
-			} else {
-				if strings.Contains(strings.ToLower(line), strings.ToLower(cr.match)) {
-					cr.times = append(cr.times, when)
-				}
-			}
+			cr.observe(tl.text, tl.when)
 		}
 	}
 	// prune old samples per counter
 	for _, cr := range u.counters {
-		if len(cr.times) == 0 {
-			continue
-		}
-		cut := time.Now().Add(-cr.window)
-		keep := cr.times[:0]
-		for _, t := range cr.times {
-			if t.After(cut) {
-				keep = append(keep, t)
-			}
-		}
-		cr.times = keep
+		cr.prune(now.Add(-cr.retention()))
 	}
+	alerts := thresholdAlerts(u.counters, now)
 	u.counterMu.Unlock()
+	u.fireThresholds(alerts)
 
 	u.Do(func() {
 		if !paused {
-			atBottom := u.atBottom()
-			u.logView.Clear()
-			for _, l := range u.filteredLines() {
-				fmt.Fprintln(u.logView, u.styleLine(l))
-			}
-			if atBottom {
-				u.logView.ScrollToEnd()
-			}
+			u.renderPendingDirect()
 		}
 		if u.topBarEnabled {
 			u.updateTopBarDirect()
 		}
+		u.refreshStatsModalDirect()
 		u.updateBottomBarDirect() // toggles and keys
 	})
 }
 
+// SetPaused pauses or resumes autoscroll and rendering of new lines.
+// Lines appended while paused are kept, counted in the status bar and shown
+// on resume; see UIOptions.KeepScrollOnResume.
+func (u *UI) SetPaused(paused bool) {
+	u.Do(func() { u.setPausedDirect(paused) })
+}
+
+// ScrollToEnd scrolls the log view to the newest line.
+func (u *UI) ScrollToEnd() {
+	u.Do(func() { u.logView.ScrollToEnd() })
+}
+
+// ScrollToLine scrolls the log view so that the n-th displayed line (0-based,
+// counted after filtering) is at the top of the viewport. It does nothing
+// while lines wrap, as rows no longer map to view lines.
+func (u *UI) ScrollToLine(n int) {
+	u.Do(func() {
+		if u.rowsWrapped() {
+			return
+		}
+		if n < 0 {
+			n = 0
+		}
+		_, col := u.logView.GetScrollOffset()
+		u.logView.ScrollTo(n+u.staleRows(), col)
+	})
+}
+
+// sendCommand forwards operator input to the broker.
+func (u *UI) sendCommand(cmd string) {
+	u.mu.Lock()
+	send, supported := u.onCommand, u.server.Supports("command")
+	u.mu.Unlock()
+	switch {
+	case send == nil:
+		u.setStatusMessage(u.msg("command.unavailable"))
+	case !supported:
+		u.setStatusMessage(u.msg("server.unsupported", "commands"))
+	case cmd != "":
+		send(cmd)
+		u.setStatusMessage(u.msg("command.sent", cmd))
+	}
+}
+
+// setReconnecting shows or hides the reconnecting badge.
+func (u *UI) setReconnecting(on bool) {
+	u.mu.Lock()
+	u.reconnecting = on
+	u.mu.Unlock()
+	u.Do(u.updateBottomBarDirect)
+}
+
+func (u *UI) setPausedDirect(paused bool) {
+	u.mu.Lock()
+	changed := u.paused != paused
+	u.paused = paused
+	if !paused {
+		u.burstPaused = false
+	}
+	pending, keep := u.pendingCount, u.keepScroll
+	u.pendingCount = 0
+	u.mu.Unlock()
+	if changed && !paused {
+		u.refreshDirect()
+		if !keep {
+			u.scrollToNewDirect(pending)
+		}
+	}
+	u.updateBottomBarDirect()
+}
+
+// scrollToNewDirect shows the n rows appended while paused: the newest end
+// of the view if they fit on one page, else the first of them at the top.
+func (u *UI) scrollToNewDirect(n int) {
+	u.mu.Lock()
+	newestFirst, total, wrap := u.newestFirst, len(u.shownRows), u.wrap
+	u.mu.Unlock()
+	_, _, _, h := u.logView.GetInnerRect()
+	switch {
+	case newestFirst:
+		u.logView.ScrollToBeginning()
+	case n <= 0 || n <= h || n > total || wrap:
+		u.logView.ScrollToEnd()
+	default:
+		u.logView.ScrollTo(total-n, 0)
+		u.mu.Lock()
+		u.scrolledAway = true
+		u.mu.Unlock()
+	}
+}
+
+// prependTimed inserts older lines received from history backfill before the
+// buffered ones, keeping the viewport on the same content. more reports
+// whether the source holds even older lines.
+func (u *UI) prependTimed(batch []timedLine, more bool) {
+	now := time.Now()
+	var older []logLine
+	for _, tl := range batch {
+		if tl.when.IsZero() {
+			tl.when = u.textTime(tl.text, now)
+		}
+		if tl.level == "" {
+			tl.level = LevelClass(LevelOf(tl.text))
+		}
+		var last *logLine
+		if n := len(older); n > 0 {
+			last = &older[n-1]
+		}
+		if tl.fields == nil && isContinuation(last, tl.channel, tl.text) {
+			last.cont = append(last.cont, subLine{text: tl.text, seq: tl.seq})
+			continue
+		}
+		older = append(older, logLine{text: tl.text, when: tl.when, tsUs: tl.tsUs, seq: tl.seq, channel: tl.channel, fields: tl.fields, level: tl.level, source: tl.source})
+	}
+
+	u.Do(func() {
+		u.mu.Lock()
+		if u.historyFloor > 0 {
+			// a reply to a request made before :clear
+			older = slices.DeleteFunc(older, func(l logLine) bool { return l.seq <= u.historyFloor })
+		}
+		for i := range older {
+			u.nextOrd++
+			older[i].ord = u.nextOrd
+			u.addChannelLocked(older[i].channel)
+			u.widenColumnsLocked(older[i].fields)
+		}
+		u.lines = append(older, u.lines...)
+		u.extraHistory = min(u.extraHistory+len(older), maxBackfill*u.maxLines)
+		u.historyPending = false
+		u.historyExhausted = !more || u.historyFloor > 0
+		paused := u.paused
+		u.mu.Unlock()
+		if paused {
+			return
+		}
+		// the viewport stays on the same line; see repaintLogDirect
+		u.repaintLogDirect()
+	})
+}
+
+// maybeRequestHistory asks for older lines once the viewport reaches the
+// oldest end of the buffer.
+func (u *UI) maybeRequestHistory() {
+	u.mu.Lock()
+	if u.onNeedHistory == nil || u.historyPending || u.historyExhausted || len(u.lines) == 0 || !u.server.Supports("history") ||
+		u.extraHistory >= maxBackfill*u.maxLines {
+		u.mu.Unlock()
+		return
+	}
+	newestFirst := u.newestFirst
+	beforeUs, beforeSeq := u.lines[0].tsUs, u.lines[0].seq
+	u.mu.Unlock()
+	if beforeUs <= 0 {
+		return
+	}
+	if newestFirst {
+		if !u.atBottom() {
+			return
+		}
+	} else if row, _ := u.logView.GetScrollOffset(); row > u.staleRows() {
+		return
+	}
+	u.mu.Lock()
+	u.historyPending = true
+	need := u.onNeedHistory
+	u.mu.Unlock()
+	need(beforeUs, beforeSeq)
+}
+
+// textTime extracts a timestamp prefix from text, falling back to now.
+func (u *UI) textTime(text string, now time.Time) time.Time {
+	if t, ok := ParseTimestampPrefix(text, u.timestampLayouts); ok {
+		return t
+	}
+	return now
+}
+
 // Do queues the given function to be executed in the UI event loop.
 func (u *UI) Do(fn func()) {
 	u.app.QueueUpdateDraw(fn)
@@ -382,43 +1012,118 @@ func (u *UI) Do(fn func()) {
 
 func (u *UI) bindKeys() {
 	u.inputField.SetChangedFunc(func(text string) {
+		if strings.HasPrefix(text, ":") || strings.HasPrefix(text, "/") || strings.HasPrefix(text, "!") {
+			return // command or search being typed, not a filter
+		}
 		u.mu.Lock()
 		if u.filterActive {
 			u.filter = text
 		}
+		u.preset = ""
 		u.mu.Unlock()
 		if u.filterActive {
 			u.refreshDirect()
+			u.notifyFilterChange()
 		}
 	})
 
 	u.inputField.SetDoneFunc(func(key tcell.Key) {
 		switch key {
 		case tcell.KeyEnter:
+			if text := u.inputField.GetText(); strings.HasPrefix(text, ":") {
+				u.addHistoryDirect(text)
+				u.inputField.SetText("")
+				u.runCommand(strings.TrimPrefix(text, ":"))
+				return
+			}
+			if text := u.inputField.GetText(); strings.HasPrefix(text, "!") {
+				u.addHistoryDirect(text)
+				u.endSearchInput()
+				u.sendCommand(strings.TrimSpace(strings.TrimPrefix(text, "!")))
+				return
+			}
+			if text := u.inputField.GetText(); strings.HasPrefix(text, "/") {
+				u.addHistoryDirect(text)
+				u.endSearchInput()
+				u.setSearchDirect(strings.TrimPrefix(text, "/"))
+				return
+			}
 			u.mu.Lock()
+			u.preset = ""
 			if u.filterActive {
 				u.filterActive = false
 			} else {
 				u.filterActive = true
 				u.filter = u.inputField.GetText()
 			}
+			active, filter := u.filterActive, u.filter
 			u.mu.Unlock()
+			if active {
+				u.addHistoryDirect(filter)
+			}
 			u.refreshDirect()
 			u.updateBottomBarDirect()
+			u.notifyFilterChange()
 		case tcell.KeyEsc:
+			u.historyPos, u.historyDraft = -1, ""
+			if strings.HasPrefix(u.inputField.GetText(), "/") {
+				u.endSearchInput()
+				u.setSearchDirect("")
+				return
+			}
+			if strings.HasPrefix(u.inputField.GetText(), "!") {
+				u.endSearchInput()
+				return
+			}
 			u.mu.Lock()
 			u.filterActive = false
 			u.filter = ""
-			u.inputField.SetText("")
+			u.preset = ""
 			u.mu.Unlock()
+			u.inputField.SetText("")
 			u.refreshDirect()
 			u.updateBottomBarDirect()
+			u.notifyFilterChange()
 		}
 	})
 
-	u.app.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+	capture := func(ev *tcell.EventKey) *tcell.EventKey {
+		if u.modal != nil {
+			// modals get all keys except quit and close
+			switch {
+			case ev.Key() == tcell.KeyCtrlC:
+				u.onExit(130)
+				return nil
+			case ev.Key() == tcell.KeyEsc, ev.Key() == tcell.KeyRune && ev.Rune() == 'q':
+				u.closeModal()
+				return nil
+			case ev.Key() == tcell.KeyRune && u.modal == u.statsPanel:
+				if r, ok := u.boundKey('s'); ok && ev.Rune() == r {
+					u.closeModal()
+					return nil
+				}
+			}
+			return ev
+		}
+		if u.app.GetFocus() == u.logView {
+			if p, ok := u.presetKey(ev); ok {
+				u.applyPresetDirect(p)
+				return nil
+			}
+		}
+		if ev.Key() == tcell.KeyRune && u.keyRemap != nil && u.app.GetFocus() != u.inputField {
+			if r, ok := u.keyRemap[ev.Rune()]; ok {
+				if r == 0 {
+					return ev // unbound
+				}
+				ev = tcell.NewEventKey(tcell.KeyRune, r, ev.Modifiers())
+			}
+		}
 		switch ev.Key() {
 		case tcell.KeyTab:
+			if u.app.GetFocus() == u.inputField && u.completeInputDirect() {
+				return nil
+			}
 			if u.app.GetFocus() == u.logView {
 				u.app.SetFocus(u.inputField)
 				u.setLogSeparators(false)
@@ -462,36 +1167,216 @@ func (u *UI) bindKeys() {
 					u.showHelpModal()
 					return nil
 				}
+			case '1', '2', '3', '4':
+				if u.app.GetFocus() == u.logView {
+					u.toggleLevelDirect(levels[ev.Rune()-'1'])
+					return nil
+				}
+			case '<', '>':
+				if u.player != nil && u.app.GetFocus() == u.logView {
+					dir := 1
+					if ev.Rune() == '<' {
+						dir = -1
+					}
+					u.stepPlaybackSpeedDirect(dir)
+					return nil
+				}
+			case '!':
+				if u.app.GetFocus() == u.logView {
+					u.inputField.SetText("!")
+					u.app.SetFocus(u.inputField)
+					u.setLogSeparators(false)
+					return nil
+				}
+			case '/':
+				if u.app.GetFocus() == u.logView {
+					u.inputField.SetText("/")
+					u.app.SetFocus(u.inputField)
+					u.setLogSeparators(false)
+					return nil
+				}
+			case 'n', 'N':
+				if u.app.GetFocus() == u.logView {
+					step := 1
+					if ev.Rune() == 'N' {
+						step = -1
+					}
+					u.searchStepDirect(step)
+					return nil
+				}
+			case '[', ']':
+				if u.app.GetFocus() == u.logView {
+					step := 1
+					if ev.Rune() == '[' {
+						step = -1
+					}
+					u.cycleChannelDirect(step)
+					return nil
+				}
 			case ' ':
 				if u.app.GetFocus() != u.inputField {
 					u.mu.Lock()
-					u.paused = !u.paused
+					paused := !u.paused
 					u.mu.Unlock()
-					u.updateBottomBarDirect() // <- reflect running/pause
+					if u.player != nil {
+						u.player.SetPaused(paused)
+					}
+					u.setPausedDirect(paused) // <- reflect running/pause
 					return nil
 				}
 			case 'c':
 				if u.app.GetFocus() != u.inputField {
 					u.mu.Lock()
-					u.filterCaseSensitive = !u.filterCaseSensitive
+					// leaving smart case flips whatever it currently resolves to
+					u.filterCaseSensitive = !u.caseSensitiveLocked()
+					u.smartCase = false
 					u.mu.Unlock()
 					u.refreshDirect()
 					u.updateBottomBarDirect() // <- reflect case toggle
+					u.notifyFilterChange()
 					return nil
 				}
-			}
-		case tcell.KeyUp:
-			if u.app.GetFocus() == u.logView {
-				row, col := u.logView.GetScrollOffset()
-				if row > 0 {
+			case 'r':
+				if u.app.GetFocus() != u.inputField {
+					u.mu.Lock()
+					u.newestFirst = !u.newestFirst
+					u.scrolledAway = false
+					newestFirst := u.newestFirst
+					u.mu.Unlock()
+					u.repaintLogDirect()
+					if newestFirst {
+						u.logView.ScrollToBeginning()
+					} else {
+						u.logView.ScrollToEnd()
+					}
+					u.updateBottomBarDirect() // <- reflect order toggle
+					return nil
+				}
+			case 'z':
+				if u.app.GetFocus() != u.inputField {
+					u.mu.Lock()
+					u.folded = !u.folded
+					u.mu.Unlock()
+					u.refreshDirect()
+					return nil
+				}
+			case 'i':
+				if u.app.GetFocus() == u.logView {
+					u.showInspectModal()
+					return nil
+				}
+			case 'x':
+				if u.app.GetFocus() == u.logView {
+					u.showCorrelationModal()
+					return nil
+				}
+			case 'p':
+				if u.app.GetFocus() == u.logView {
+					u.showPatternsModal()
+					return nil
+				}
+			case 's':
+				if u.app.GetFocus() == u.logView {
+					u.toggleStatsModalDirect()
+					return nil
+				}
+			case 'w':
+				if u.app.GetFocus() == u.logView {
+					u.showExportModal()
+					return nil
+				}
+			case 'y':
+				if u.app.GetFocus() == u.logView {
+					u.copySelectionDirect()
+					return nil
+				}
+			case 'd':
+				if u.app.GetFocus() != u.inputField {
+					u.mu.Lock()
+					u.diffMode = !u.diffMode
+					u.mu.Unlock()
+					u.refreshDirect()
+					return nil
+				}
+			case 'W':
+				if u.app.GetFocus() != u.inputField {
+					u.toggleWrapDirect()
+					return nil
+				}
+			case 't':
+				if u.app.GetFocus() != u.inputField {
+					u.cycleTimestampsDirect()
+					return nil
+				}
+			case '#':
+				if u.app.GetFocus() != u.inputField {
+					u.mu.Lock()
+					u.showIDs = !u.showIDs
+					u.mu.Unlock()
+					u.refreshDirect()
+					return nil
+				}
+			case 'C':
+				if u.app.GetFocus() != u.inputField {
+					u.mu.Lock()
+					u.smartCase = !u.smartCase
+					u.mu.Unlock()
+					u.refreshDirect()
+					u.updateBottomBarDirect() // <- reflect smart-case toggle
+					u.notifyFilterChange()
+					return nil
+				}
+			case 'F':
+				if u.app.GetFocus() != u.inputField {
+					u.toggleServerFilterDirect()
+					return nil
+				}
+			case 'e':
+				if u.app.GetFocus() != u.inputField {
+					u.mu.Lock()
+					u.filterRegex = !u.filterRegex
+					u.mu.Unlock()
+					u.refreshDirect()
+					u.notifyFilterChange()
+					return nil
+				}
+			}
+		case tcell.KeyEsc:
+			if u.app.GetFocus() == u.logView && u.clearSelectionDirect() {
+				return nil
+			}
+		case tcell.KeyUp:
+			if u.app.GetFocus() == u.inputField {
+				u.historyStepDirect(-1)
+				return nil
+			}
+			if u.app.GetFocus() == u.logView && ev.Modifiers()&tcell.ModShift != 0 {
+				u.extendSelectionDirect(-1)
+				return nil
+			}
+			if u.app.GetFocus() == u.logView {
+				row, col := u.logView.GetScrollOffset()
+				if row > 0 {
 					u.logView.ScrollTo(row-1, col)
 				}
+				u.scrolledDirect(true, false)
+				u.maybeRequestHistory()
 				return nil
 			}
 		case tcell.KeyDown:
+			if u.app.GetFocus() == u.inputField {
+				u.historyStepDirect(1)
+				return nil
+			}
+			if u.app.GetFocus() == u.logView && ev.Modifiers()&tcell.ModShift != 0 {
+				u.extendSelectionDirect(1)
+				return nil
+			}
 			if u.app.GetFocus() == u.logView {
 				row, col := u.logView.GetScrollOffset()
 				u.logView.ScrollTo(row+1, col)
+				u.scrolledDirect(false, false)
+				u.maybeRequestHistory()
 				return nil
 			}
 		case tcell.KeyPgUp:
@@ -506,6 +1391,8 @@ func (u *UI) bindKeys() {
 					nr = 0
 				}
 				u.logView.ScrollTo(nr, col)
+				u.scrolledDirect(true, false)
+				u.maybeRequestHistory()
 				return nil
 			}
 		case tcell.KeyPgDn:
@@ -516,28 +1403,236 @@ func (u *UI) bindKeys() {
 				}
 				row, col := u.logView.GetScrollOffset()
 				u.logView.ScrollTo(row+(h-1), col)
+				u.scrolledDirect(false, false)
+				u.maybeRequestHistory()
+				return nil
+			}
+		case tcell.KeyLeft, tcell.KeyRight:
+			if u.app.GetFocus() == u.logView {
+				step := hscrollStep
+				if ev.Modifiers()&tcell.ModShift != 0 {
+					step = u.hscrollPage()
+				}
+				if ev.Key() == tcell.KeyLeft {
+					step = -step
+				}
+				u.hscrollDirect(step)
 				return nil
 			}
 		case tcell.KeyHome:
 			if u.app.GetFocus() == u.logView {
 				u.logView.ScrollToBeginning()
+				u.scrolledDirect(true, true)
+				u.maybeRequestHistory()
 				return nil
 			}
 		case tcell.KeyEnd:
 			if u.app.GetFocus() == u.logView {
 				u.logView.ScrollToEnd()
+				u.scrolledDirect(false, true)
+				u.maybeRequestHistory()
 				return nil
 			}
 		}
 		return ev
-	})
+	}
+	// An owned app captures globally so Ctrl+C reaches onExit; an embedded
+	// console only sees keys while it has focus.
+	if u.ownsApp {
+		u.app.SetInputCapture(capture)
+	} else {
+		u.pages.SetInputCapture(capture)
+	}
 }
 
-func (u *UI) refreshDirect() {
+// notifyFilterChange reports the current filter state to OnFilterChange.
+func (u *UI) notifyFilterChange() {
+	u.syncServerFilter()
+	if u.onFilterChange == nil {
+		return
+	}
+	u.mu.Lock()
+	filter := u.filter
+	active := u.filterActive
+	caseOn := u.caseSensitiveLocked()
+	u.mu.Unlock()
+	u.onFilterChange(filter, active, caseOn)
+}
+
+// displayRows returns the filtered rows in display order.
+func (u *UI) displayRows() []displayRow {
+	rows := u.filteredLines()
+	u.mu.Lock()
+	newestFirst := u.newestFirst
+	u.mu.Unlock()
+	if newestFirst {
+		slices.Reverse(rows)
+	}
+	return rows
+}
+
+// currentRow returns the line the user is looking at: the newest line while
+// following, otherwise the row at the top of the viewport.
+func (u *UI) currentRow() (displayRow, bool) {
+	rows := u.displayRows()
+	if len(rows) == 0 {
+		return displayRow{}, false
+	}
+	u.mu.Lock()
+	newestFirst := u.newestFirst
+	u.mu.Unlock()
+	if u.following(newestFirst) {
+		if newestFirst {
+			return rows[0], true
+		}
+		return rows[len(rows)-1], true
+	}
+	row, _ := u.logView.GetScrollOffset()
+	row = max(row-u.staleRows(), 0)
+	if row >= len(rows) {
+		row = len(rows) - 1
+	}
+	return rows[row], true
+}
+
+// idPrefix renders the line ID column when IDs are shown.
+func (u *UI) idPrefix(seq uint64) string {
+	u.mu.Lock()
+	show := u.showIDs
+	u.mu.Unlock()
+	if !show {
+		return ""
+	}
+	id := "#-"
+	if seq > 0 {
+		id = fmt.Sprintf("#%d", seq)
+	}
+	if u.noColour {
+		return id + " "
+	}
+	return "[gray]" + id + "[-] "
+}
+
+// ScrollToID scrolls the log view to the line with the given broker-assigned
+// ID. It reports false if no displayed line has that ID (trimmed, filtered
+// out, or never received) or if lines wrap. It waits for the UI event loop, so call it while
+// the UI is running and not from within a Do callback.
+func (u *UI) ScrollToID(id uint64) bool {
+	found := make(chan bool, 1)
+	u.Do(func() { found <- u.scrollToIDDirect(id) })
+	return <-found
+}
+
+func (u *UI) scrollToIDDirect(id uint64) bool {
+	if u.rowsWrapped() {
+		return false
+	}
+	rows := u.displayRows()
+	row := u.rowOfSeq(rows, id)
+	if row < 0 {
+		return false
+	}
+	_, col := u.logView.GetScrollOffset()
+	u.logView.ScrollTo(row+u.staleRows(), col)
+	return true
+}
+
+// repaintLogDirect rewrites the log view from the buffer, keeping the view
+// pinned to the newest line if it was following before.
+func (u *UI) repaintLogDirect() {
+	u.mu.Lock()
+	newestFirst := u.newestFirst
+	diffMode := u.diffMode
+	shown := u.shownRows
+	wrap := u.wrap
+	u.mu.Unlock()
+
+	follow := u.following(newestFirst)
+	topRow, col := u.logView.GetScrollOffset()
+	// snapshot the rows and drop pending incremental ones in one step, so
+	// every line is rendered exactly once
+	u.mu.Lock()
+	rows := u.filteredLinesLocked()
+	u.pendingRows = nil
+	u.needFull = false
+	u.stale = 0
+	u.mu.Unlock()
+	stamps, last := u.tsPrefixes(rows, time.Time{})
+	var changes [][][]int
+	if diffMode && !u.noColour {
+		changes = diffChanges(rows)
+	}
+	if newestFirst {
+		slices.Reverse(rows)
+		slices.Reverse(changes)
+		slices.Reverse(stamps)
+	}
 	u.logView.Clear()
-	for _, l := range u.filteredLines() {
-		fmt.Fprintln(u.logView, u.styleLine(l))
+	mark, selected := u.filterMarker(), u.selectionMarker()
+	keys := make([]rowKey, len(rows))
+	for i, r := range rows {
+		keys[i] = r.key
+	}
+	u.mu.Lock()
+	from, to, sel := u.selectionRangeLocked(keys)
+	u.mu.Unlock()
+	for i, r := range rows {
+		line := u.styleRow(r.level, r.source, r.text)
+		if changes != nil {
+			// marked after styling, so highlight spans keep their offsets
+			line = markVisibleRanges(line, changes[i], diffOpen, diffClose)
+		}
+		line = u.idPrefix(r.seq) + mark(line)
+		if stamps != nil {
+			line = stamps[i] + line
+		}
+		if sel && i >= from && i <= to {
+			line = selected(line)
+		}
+		fmt.Fprintln(u.logView, line)
+	}
+	u.mu.Lock()
+	u.shownRows = keys
+	u.tsPrev = last
+	u.mu.Unlock()
+
+	switch {
+	case follow && newestFirst:
+		u.followTopDirect()
+	case follow:
+		u.followEndDirect()
+	case wrap:
+		// the offset counts wrapped lines, not rows; keep it as it was
+		u.logView.ScrollTo(topRow, col)
+	default:
+		if row, ok := anchorRow(shown, keys, topRow); ok {
+			u.logView.ScrollTo(row, col)
+		}
+	}
+}
+
+// anchorRow finds where the line at the top of the viewport, old[top], went
+// in the repainted rows, so trimming, backfill or new lines do not shift
+// content under a reader. If that line is gone, the first later row that
+// survived is used.
+func anchorRow(old, rows []rowKey, top int) (int, bool) {
+	if top < 0 || top >= len(old) {
+		return 0, false
+	}
+	index := make(map[rowKey]int, len(rows))
+	for i, k := range rows {
+		index[k] = i
+	}
+	for _, k := range old[top:] {
+		if i, ok := index[k]; ok {
+			return i, true
+		}
 	}
+	return 0, false
+}
+
+func (u *UI) refreshDirect() {
+	u.repaintLogDirect()
 	u.setLogSeparators(u.app.GetFocus() == u.logView)
 	if u.topBarEnabled {
 		u.updateTopBarDirect()
@@ -545,61 +1640,325 @@ func (u *UI) refreshDirect() {
 	u.updateBottomBarDirect()
 }
 
-func (u *UI) bottomLeftStatus() string {
-	key := func(s string) string {
-		if u.noColour {
-			return s
+// runCommand executes a ':'-prefixed command typed into the input field.
+func (u *UI) runCommand(cmd string) {
+	fields := strings.Fields(cmd)
+	if len(fields) == 0 {
+		return
+	}
+	switch fields[0] {
+	case "goto":
+		if len(fields) != 2 {
+			u.setStatusMessage(u.msg("goto.usage"))
+			return
+		}
+		id, err := strconv.ParseUint(strings.TrimPrefix(fields[1], "#"), 10, 64)
+		if err != nil || id == 0 {
+			u.setStatusMessage(u.msg("goto.invalid", fields[1]))
+			return
+		}
+		if u.rowsWrapped() {
+			u.setStatusMessage(u.msg("wrap.rows", "goto"))
+			return
+		}
+		if !u.scrollToIDDirect(id) {
+			u.setStatusMessage(u.msg("goto.missing", id))
+			return
+		}
+		u.app.SetFocus(u.logView)
+		u.setLogSeparators(true)
+	case "channel":
+		name := ""
+		if len(fields) > 1 {
+			name = fields[1]
+		}
+		if !u.setChannelDirect(name) {
+			u.setStatusMessage(u.msg("channel.unknown", name, strings.Join(u.channelNames(), ", ")))
+		}
+	case "columns":
+		var cols []string
+		if len(fields) > 1 {
+			cols = strings.Split(strings.Join(fields[1:], ","), ",")
+			cols = slices.DeleteFunc(cols, func(c string) bool { return c == "" })
 		}
-		return "[blue::b]" + s + "[-:-:-]"
+		u.setColumnsDirect(cols)
+	case "where":
+		if !u.setWhereDirect(strings.Join(fields[1:], " ")) {
+			u.setStatusMessage(u.msg("where.usage"))
+		}
+	case "speed", "jump":
+		u.playbackCommand(fields)
+	case "stats":
+		u.mu.Lock()
+		req, supported := u.onStatsRequest, u.server.Supports("stats")
+		u.mu.Unlock()
+		switch {
+		case req == nil:
+			u.setStatusMessage(u.msg("stats.unavailable"))
+		case !supported:
+			u.setStatusMessage(u.msg("server.unsupported", "stats"))
+		default:
+			req()
+		}
+	case "copy":
+		switch {
+		case len(fields) == 1:
+			u.copyFiltered(false)
+		case len(fields) == 2 && fields[1] == "file":
+			u.copyFiltered(true)
+		default:
+			u.setStatusMessage(u.msg("copy.usage"))
+		}
+	case "palette", "theme":
+		if len(fields) != 2 {
+			u.setStatusMessage(u.msg("palette.usage", paletteNames()))
+			return
+		}
+		p, ok := PaletteByName(fields[1])
+		if !ok {
+			u.setStatusMessage(u.msg("palette.unknown", paletteNames()))
+			return
+		}
+		u.mu.Lock()
+		u.palettePinned = true
+		u.mu.Unlock()
+		u.setPaletteDirect(p)
+		u.setStatusMessage(u.msg("palette.set", p.Name))
+	case "export":
+		u.exportCommand(fields[1:])
+	case "save":
+		u.exportCommand(append([]string{"text"}, fields[1:]...))
+	case "clear":
+		u.clearDirect()
+	case "maxlines":
+		u.maxLinesCommand(fields[1:])
+	case "filter":
+		u.filterCommand(fields[1:])
+	default:
+		u.setStatusMessage(u.msg("cmd.unknown", fields[0]))
+	}
+}
+
+// statusMessageTTL is how long a status message replaces the key hints.
+const statusMessageTTL = 5 * time.Second
+
+// setStatusMessage shows msg on the left of the status bar for a while.
+// Must be called on the UI goroutine.
+func (u *UI) setStatusMessage(msg string) {
+	u.mu.Lock()
+	u.statusMsg = msg
+	u.statusSeq++
+	seq := u.statusSeq
+	u.mu.Unlock()
+	u.updateBottomBarDirect()
+	time.AfterFunc(statusMessageTTL, func() {
+		u.Do(func() {
+			u.mu.Lock()
+			if u.statusSeq == seq {
+				u.statusMsg = ""
+			}
+			u.mu.Unlock()
+			u.updateBottomBarDirect()
+		})
+	})
+}
+
+// statusMessageText returns the pending status message formatted for the
+// status bar, or "" when there is none.
+func (u *UI) statusMessageText() string {
+	u.mu.Lock()
+	msg := u.statusMsg
+	u.mu.Unlock()
+	if msg == "" || u.noColour {
+		return msg
+	}
+	return tagStyle(tview.Escape(msg), u.currentPalette().Message, false)
+}
+
+func (u *UI) bottomLeftStatus() string {
+	if msg := u.statusMessageText(); msg != "" {
+		return msg
 	}
 	// Keys/help only. (Counters are shown in the top bar when enabled.)
-	return fmt.Sprintf("%s help | %s switch | %s quit",
-		key("?"), key("Tab"), key("Ctrl+C"),
-	)
+	return u.keyHints()
+}
+
+// keyHints renders the help, switch and quit key hints.
+func (u *UI) keyHints() string {
+	pal := u.currentPalette()
+	key := func(s string) string { return tagStyle(s, pal.Key, u.noColour) }
+	hints := fmt.Sprintf("%s %s | %s %s", key("Tab"), u.msg("key.switch"), key("Ctrl+C"), u.msg("key.quit"))
+	if r, ok := u.boundKey('?'); ok {
+		hints = fmt.Sprintf("%s %s | ", key(tview.Escape(keyName(r))), u.msg("key.help")) + hints
+	}
+	return hints
 }
 
 func (u *UI) legacyLeftStatus() string {
-	key := func(s string) string {
-		if u.noColour {
-			return s
-		}
-		return "[blue::b]" + s + "[-:-:-]"
+	if msg := u.statusMessageText(); msg != "" {
+		return msg + u.viewersSnapshot() + u.counterSnapshot()
 	}
-	return fmt.Sprintf("%s help | %s switch | %s quit%s",
-		key("?"), key("Tab"), key("Ctrl+C"), u.counterSnapshot(),
-	)
+	return u.keyHints() + u.viewersSnapshot() + u.counterSnapshot()
 }
 
-func (u *UI) rightStatus(filterOn, caseOn, mouseOn, running bool) string {
-	// Here, "active" (green) should mean: user can select with mouse.
+// statusState is a snapshot of the toggles shown in the bottom status bar.
+type statusState struct {
+	filterOn     bool
+	filterExpr   string
+	serverFilter bool
+	exclude      bool
+	caseOn       bool
+	smartCase    bool
+	regex        bool
+	mouseOn      bool
+	running      bool
+	pending      int
+	scrolled     bool
+	newestFirst  bool
+	folded       bool
+	diffMode     bool
+	burstPaused  bool
+	reconnecting bool
+	playback     string
+	hiddenLevels map[string]bool
+	channel      string
+	where        string
+	preset       string
+	wrap         bool
+	column       int // horizontal scroll offset of unwrapped lines
+}
+
+// rightStatus renders the toggle badges; short selects abbreviated labels
+// for narrow terminals.
+func (u *UI) rightStatus(st statusState, short bool) string {
+	// Here, "active" (green by default) should mean: user can select with mouse.
 	// That happens when tview mouse is DISABLED (mouseOn == false).
-	selectionEnabled := !mouseOn
+	selectionEnabled := !st.mouseOn
 
+	pal := u.currentPalette()
 	col := func(active bool, label string) string {
-		if u.noColour {
-			return label
-		}
 		if active {
-			return "[green::b]" + label + "[-:-:-]"
+			return tagStyle(label, pal.Active, u.noColour)
 		}
-		return "[yellow]" + label + "[-:-:-]"
+		return tagStyle(label, pal.Inactive, u.noColour)
 	}
 
-	return fmt.Sprintf("%s | %s | %s | %s",
-		col(filterOn, "Filter"),
-		col(caseOn, "Case Sensitive"),
-		col(selectionEnabled, "Mouse"), // green = terminal selection enabled
-		col(running, "Running"),
-	)
+	label := func(id string) string {
+		if short {
+			return u.msg(id + ".s")
+		}
+		return u.msg(id)
+	}
+	sep := " | "
+	if short {
+		sep = " "
+	}
+
+	caseLabel := label("badge.case")
+	if st.smartCase {
+		caseLabel = label("badge.smart")
+	}
+
+	filterLabel := label("badge.filter")
+	if st.exclude {
+		filterLabel = label("badge.exclude")
+	}
+	if expr := st.filterExpr; expr != "" && !short {
+		if utf8.RuneCountInString(expr) > maxFilterBadgeWidth {
+			expr = string([]rune(expr)[:maxFilterBadgeWidth-1]) + "…"
+		}
+		filterLabel += ": " + tview.Escape(expr)
+	}
+	out := col(st.filterOn, filterLabel) + sep +
+		col(st.caseOn, caseLabel) + sep +
+		col(selectionEnabled, label("badge.mouse")) + sep + // green = terminal selection enabled
+		col(st.running, label("badge.running"))
+	if !st.running && st.pending > 0 {
+		id := "badge.pending"
+		if short {
+			id += ".s"
+		}
+		out += sep + tagStyle(u.msg(id, st.pending), pal.Warn, u.noColour)
+	}
+	if st.regex {
+		out = col(true, label("badge.regex")) + sep + out
+	}
+	if st.wrap {
+		out = col(true, label("badge.wrap")) + sep + out
+	} else if st.column > 0 {
+		out = col(true, u.msg("badge.column", st.column)) + sep + out
+	}
+	if st.newestFirst {
+		out = col(true, label("badge.newest")) + sep + out
+	}
+	if st.folded {
+		out = col(true, label("badge.folded")) + sep + out
+	}
+	if st.diffMode {
+		out = col(true, label("badge.diff")) + sep + out
+	}
+	if st.burstPaused {
+		out = tagStyle(label("badge.burst"), pal.Error, u.noColour) + sep + out
+	}
+	if st.scrolled {
+		out = tagStyle(label("badge.scrolled"), pal.Warn, u.noColour) + sep + out
+	}
+	if st.where != "" {
+		out = col(true, label("badge.where")) + sep + out
+	}
+	if st.serverFilter {
+		out = col(true, label("badge.server")) + sep + out
+	}
+	if len(st.hiddenLevels) > 0 {
+		out = u.levelsBadge(st.hiddenLevels, pal, short) + sep + out
+	}
+	if st.preset != "" {
+		out = col(true, u.msg("badge.preset", tview.Escape(st.preset))) + sep + out
+	}
+	if st.channel != "" && !u.topBarEnabled {
+		// the top bar shows channel tabs instead
+		out = col(true, u.msg("badge.channel", st.channel)) + sep + out
+	}
+	if st.reconnecting {
+		out = tagStyle(label("badge.reconnecting"), pal.Warn, u.noColour) + sep + out
+	}
+	if st.playback != "" {
+		out = col(true, st.playback) + sep + out
+	}
+	return out
 }
 
 func (u *UI) updateBottomBarDirect() {
 	u.mu.Lock()
-	filterOn := u.filterActive
-	caseOn := u.filterCaseSensitive
-	mouseOn := u.mouseOn
-	paused := u.paused
+	st := statusState{
+		filterOn:     u.filterActive,
+		caseOn:       u.caseSensitiveLocked(),
+		smartCase:    u.smartCase,
+		regex:        u.filterRegex,
+		mouseOn:      u.mouseOn,
+		running:      !u.paused,
+		pending:      u.pendingCount,
+		scrolled:     u.scrolledAway,
+		newestFirst:  u.newestFirst,
+		folded:       u.folded,
+		diffMode:     u.diffMode,
+		burstPaused:  u.burstPaused,
+		reconnecting: u.reconnecting,
+		channel:      u.channel,
+		where:        u.whereKey,
+		serverFilter: u.serverFilter,
+		hiddenLevels: maps.Clone(u.hiddenLevels),
+		preset:       u.preset,
+		wrap:         u.wrap,
+	}
+	if e, ok := u.filterExprLocked(); ok {
+		st.filterExpr, st.exclude = e.String(), e.excludeOnly()
+	}
 	u.mu.Unlock()
+	_, st.column = u.logView.GetScrollOffset()
+	if u.player != nil {
+		st.playback = u.playbackBadge()
+	}
 
 	var left string
 	if u.topBarEnabled {
@@ -607,13 +1966,20 @@ func (u *UI) updateBottomBarDirect() {
 	} else {
 		left = u.legacyLeftStatus() // legacy: counters remain on bottom
 	}
-	right := u.rightStatus(filterOn, caseOn, mouseOn, !paused)
+	right := u.rightStatus(st, false)
 
-	_, _, w, _ := u.statusText.GetInnerRect()
+	w := u.barWidth()
 	if w <= 0 {
 		u.statusText.SetText(left + "  " + right)
 		return
 	}
+	// on narrow terminals shorten the badges, then drop the key hints
+	if visualLen(left)+visualLen(right)+1 > w {
+		right = u.rightStatus(st, true)
+	}
+	if visualLen(left)+visualLen(right)+1 > w {
+		left = ""
+	}
 	pad := w - visualLen(left) - visualLen(right)
 	if pad < 1 {
 		pad = 1
@@ -629,14 +1995,21 @@ func (u *UI) updateTopBarDirect() {
 	title := u.title
 	u.mu.Unlock()
 
-	left := title
-	right := u.counterSnapshot()
+	left := tagStyle(title, u.currentPalette().Title, u.noColour)
+	if tabs := u.channelTabs(); tabs != "" {
+		left += "  " + tabs
+	}
+	right := u.viewersSnapshot() + u.counterSnapshot()
 
-	_, _, w, _ := u.topBar.GetInnerRect()
+	w := u.barWidth()
 	if w <= 0 {
 		u.topBar.SetText(left + "  " + right)
 		return
 	}
+	// counters matter more than the title when space runs out
+	if visualLen(left)+visualLen(right)+1 > w {
+		left = ""
+	}
 	pad := w - visualLen(left) - visualLen(right)
 	if pad < 1 {
 		pad = 1
@@ -653,7 +2026,12 @@ func (u *UI) setLogSeparators(focused bool) {
 	if focused {
 		ch = '═'
 	}
-	line := strings.Repeat(string(ch), w)
+	pal := u.currentPalette()
+	st := pal.Separator
+	if focused && pal.Focus != (Style{}) {
+		st = pal.Focus
+	}
+	line := tagStyle(strings.Repeat(string(ch), w), st, u.noColour)
 
 	// Top line only when top bar is disabled (legacy mode)
 	if !u.topBarEnabled {
@@ -666,101 +2044,233 @@ func (u *UI) styleLine(line string) string {
 	if u.noColour || len(u.highlights) == 0 || line == "" {
 		return line
 	}
-	out := line
 	u.hlMu.Lock()
 	defer u.hlMu.Unlock()
+	return applyHighlights(line, u.highlights, u.noColour)
+}
+
+// highlightRulesOf builds highlight rules from cfg.
+func highlightRulesOf(cfg Config) []*highlightRule {
+	rules := make([]*highlightRule, 0, len(cfg.Highlights))
+	for _, spec := range cfg.Highlights {
+		hr := &highlightRule{match: spec.Match, caseSensitive: spec.CaseSensitive}
+		if spec.Match != "" {
+			hr.matcher, _ = newTextMatcher(spec.Match, spec.CaseSensitive, spec.Regex)
+		}
+		if spec.Style != nil {
+			st := *spec.Style
+			hr.style = &st
+		}
+		rules = append(rules, hr)
+	}
+	return rules
+}
 
-	for _, h := range u.highlights {
-		if h.match == "" {
+// applyHighlights wraps every match of rules in line with its style tags.
+func applyHighlights(line string, rules []*highlightRule, noColour bool) string {
+	out := line
+	for _, h := range rules {
+		if h.matcher == nil {
 			continue
 		}
 		if h.styler != nil {
-			// Case-sensitive or insensitive replace with custom styler
-			if h.caseSensitive {
-				out = strings.ReplaceAll(out, h.match, h.styler(h.match, u.noColour))
-			} else {
-				out = replaceAllInsensitive(out, h.match, func(s string) string { return h.styler(s, u.noColour) })
-			}
+			out = h.matcher.replaceAll(out, func(s string) string { return h.styler(s, noColour) })
 			continue
 		}
 		if h.style != nil {
-			if h.caseSensitive {
-				out = strings.ReplaceAll(out, h.match, u.applyStyle(h.match, *h.style))
-			} else {
-				out = replaceAllInsensitive(out, h.match, func(s string) string { return u.applyStyle(s, *h.style) })
-			}
+			out = h.matcher.replaceAll(out, func(s string) string { return tagStyle(s, *h.style, noColour) })
 		}
 	}
 	return out
 }
 
-func (u *UI) applyStyle(s string, st Style) string {
-	if u.noColour || s == "" {
+// filterMarker returns a function that highlights the active filter pattern,
+// and any search matches, in an already styled line, distinct from configured
+// highlight rules.
+func (u *UI) filterMarker() func(string) string {
+	u.mu.Lock()
+	e, _ := u.filterExprLocked()
+	u.mu.Unlock()
+	search := u.searchMarker()
+	ms := e.markers()
+	if u.noColour || len(ms) == 0 {
+		return search
+	}
+	return func(s string) string {
+		for _, m := range ms {
+			s = markVisibleWith(s, m, "[::r]", "[::R]")
+		}
+		return search(s)
+	}
+}
+func tagStyle(s string, st Style, noColour bool) string {
+	if noColour || s == "" {
 		return s
 	}
 	open := "[" + st.FG + ":" + st.BG + ":" + st.Attrs + "]"
 	return open + s + "[-:-:-]"
 }
 
-func replaceAllInsensitive(s, sub string, rep func(string) string) string {
-	if sub == "" {
-		return s
+// caseSensitiveLocked reports whether the filter currently matches
+// case-sensitively, taking smart-case into account. Callers hold u.mu.
+func (u *UI) caseSensitiveLocked() bool {
+	if u.smartCase {
+		return strings.ToLower(u.filter) != u.filter
 	}
-	ls := strings.ToLower(s)
-	ln := strings.ToLower(sub)
+	return u.filterCaseSensitive
+}
 
-	var b strings.Builder
-	i := 0
-	for {
-		j := strings.Index(ls[i:], ln)
-		if j < 0 {
-			b.WriteString(s[i:])
-			break
-		}
-		j += i
-		b.WriteString(s[i:j])
-		b.WriteString(rep(s[j : j+len(sub)]))
-		i = j + len(sub)
+// lineMatcherLocked returns the active filter predicate, or nil when no
+// filter applies. Callers hold u.mu.
+func (u *UI) lineMatcherLocked() func(text string, when time.Time) bool {
+	e, ok := u.filterExprLocked()
+	if !ok {
+		return nil
+	}
+	return e.match
+}
+
+// filterExprLocked returns the active filter, reporting false if the filter
+// is off or has no pattern. Callers hold u.mu.
+func (u *UI) filterExprLocked() (filterExpr, bool) {
+	if !u.filterActive {
+		return nil, false
 	}
-	return b.String()
+	return parseFilter(u.filter, u.caseSensitiveLocked(), u.filterRegex)
+}
+
+// displayRow is one row of the log view with the ID of the line it shows.
+type displayRow struct {
+	text   string
+	seq    uint64
+	key    rowKey
+	level  string
+	source string    // set on parent rows only
+	when   time.Time // the group's time, for the timestamp column
 }
 
-func (u *UI) filteredLines() []string {
+// rowKey identifies a display row across repaints: the group's ord and the
+// continuation index, or -1 for the parent or a folded group.
+type rowKey struct {
+	ord uint64
+	sub int
+}
+
+// filteredLines returns the display rows after filtering. With folding on,
+// each group is one row and is kept if any of its lines matches.
+func (u *UI) filteredLines() []displayRow {
 	u.mu.Lock()
 	defer u.mu.Unlock()
+	return u.filteredLinesLocked()
+}
 
-	if !u.filterActive || strings.TrimSpace(u.filter) == "" {
-		out := make([]string, len(u.lines))
-		copy(out, u.lines)
-		return out
-	}
-	out := make([]string, 0, len(u.lines))
-	if u.filterCaseSensitive {
-		for _, l := range u.lines {
-			if strings.Contains(l, u.filter) {
-				out = append(out, l)
+// filteredLinesLocked is filteredLines for callers holding u.mu.
+func (u *UI) filteredLinesLocked() []displayRow {
+	match := u.lineMatcherLocked()
+	out := make([]displayRow, 0, len(u.lines))
+	for i := range u.lines {
+		l := &u.lines[i]
+		if !u.groupShownLocked(l) {
+			continue
+		}
+		text := u.rowTextLocked(l)
+		if u.folded {
+			if match == nil || match(text, l.when) || l.matchesAny(match) {
+				out = append(out, displayRow{text: l.foldedText(text), seq: l.seq, key: rowKey{l.ord, -1}, level: l.level, source: l.source, when: l.when})
 			}
+			continue
 		}
-	} else {
-		want := strings.ToLower(u.filter)
-		for _, l := range u.lines {
-			if strings.Contains(strings.ToLower(l), want) {
-				out = append(out, l)
+		if match == nil || match(text, l.when) {
+			out = append(out, displayRow{text: text, seq: l.seq, key: rowKey{l.ord, -1}, level: l.level, source: l.source, when: l.when})
+		}
+		for j, c := range l.cont {
+			if match == nil || match(c.text, l.when) {
+				out = append(out, displayRow{text: u.contTextLocked(c.text), seq: c.seq, key: rowKey{l.ord, j}, level: l.level, when: l.when})
 			}
 		}
 	}
 	return out
 }
 
+// rowOfSeq returns the display row showing line id, or -1. With folding on,
+// an ID inside a group resolves to the group's row.
+func (u *UI) rowOfSeq(rows []displayRow, id uint64) int {
+	u.mu.Lock()
+	folded := u.folded
+	var parent uint64
+	if folded {
+		for i := range u.lines {
+			if u.lines[i].hasSeq(id) {
+				parent = u.lines[i].seq
+				break
+			}
+		}
+	}
+	u.mu.Unlock()
+	if folded {
+		id = parent
+	}
+	if id == 0 {
+		return -1
+	}
+	for i, r := range rows {
+		if r.seq == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// following reports whether the viewport shows the newest lines: the bottom
+// of the view normally, or the top when newest-first ordering is on. It is
+// false while autoscroll is suspended by scrolling away; see scrolledDirect.
+func (u *UI) following(newestFirst bool) bool {
+	u.mu.Lock()
+	away := u.scrolledAway
+	u.mu.Unlock()
+	if away {
+		return false
+	}
+	if newestFirst {
+		row, _ := u.logView.GetScrollOffset()
+		return row == 0
+	}
+	return u.atBottom()
+}
+
+// scrolledDirect records a scroll by the user, up towards the top of the view
+// or down, and whether it jumped to that end. Scrolling towards older lines
+// suspends autoscroll so new lines do not pull the view back; jumping to the
+// newest end resumes it.
+func (u *UI) scrolledDirect(up, toEnd bool) {
+	u.mu.Lock()
+	newestFirst := u.newestFirst
+	u.mu.Unlock()
+	away := up != newestFirst
+	if _, _, _, h := u.logView.GetInnerRect(); away && !toEnd && u.viewLineCount() <= h {
+		return // a view shorter than the screen cannot leave the newest lines
+	}
+	u.mu.Lock()
+	changed := (away || toEnd) && u.scrolledAway != away
+	if changed {
+		u.scrolledAway = away
+	}
+	u.mu.Unlock()
+	if changed {
+		u.updateBottomBarDirect()
+	}
+}
+
 func (u *UI) atBottom() bool {
-	filtered := u.filteredLines()
-	total := len(filtered)
+	// measure what is currently displayed, not the buffer, which may
+	// already hold lines that have not been rendered yet
+	total := u.viewLineCount()
 	row, _ := u.logView.GetScrollOffset()
 	_, _, _, h := u.logView.GetInnerRect()
 	if h <= 0 {
 		h = 1
 	}
-	if total == 0 {
+	if total <= 0 {
 		return true
 	}
 	threshold := total - h
@@ -771,23 +2281,23 @@ func (u *UI) atBottom() bool {
 }
 
 func (u *UI) counterSnapshot() string {
+	pal := u.currentPalette()
+	st, alert := pal.Counter, pal.Error
+	alert.Attrs += "l" // blink
 	u.counterMu.Lock()
 	defer u.counterMu.Unlock()
 
 	parts := make([]string, 0, len(u.counters))
 	now := time.Now()
 	for _, c := range u.counters {
-		// prune for display too (cheap)
-		cut := now.Add(-c.window)
-		cnt := 0
-		for i := len(c.times) - 1; i >= 0; i-- {
-			if c.times[i].After(cut) {
-				cnt++
-			} else {
-				break
+		s, label := st, c.label
+		if _, over := c.overThreshold(now); over {
+			s = alert
+			if u.noColour {
+				label = "!" + label
 			}
 		}
-		parts = append(parts, fmt.Sprintf(" | %s:%d", c.label, cnt))
+		parts = append(parts, " | "+tagStyle(label+":"+c.display(now, u.msg("counter.invalid")), s, u.noColour))
 	}
 
 	// Fit within available width? We can't measure here; we truncate in updateBottomBarDirect by padding.
@@ -804,57 +2314,31 @@ func (u *UI) counterSnapshot() string {
 }
 
 func (u *UI) showHelpModal() {
-	u.prevFocus = u.app.GetFocus()
-	lines := []string{
+	sections := []string{
 		u.title,
-		"",
-		"Focus & Quit",
-		"  Tab / Shift+Tab     Switch focus (Log ↔ Input)",
-		"  Ctrl+C              Quit immediately",
-		"  q (log focus)       Quit",
-		"",
-		"Log View (when focused)",
-		"  Up/Down             Scroll one line",
-		"  PgUp/PgDn           Scroll one page",
-		"  Home/End            Jump to top/bottom",
-		"  Space               Pause/Resume autoscroll",
-		"  c                   Toggle case sensitivity for filter",
-		"  m                   Toggle mouse mode (green = terminal selection enabled)",
-		"  ?                   Toggle this help",
-		"",
-		"Filter (Input line)",
-		"  Type text to set filter pattern",
-		"  Enter               Enable/Disable filter (keeps text)",
-		"  Esc                 Clear & disable filter",
+		u.rebindHelp(u.msg("help.focus")),
+		u.rebindHelp(u.msg("help.log")),
+		u.msg("help.filter", paletteNames()),
+	}
+	if help := u.presetsHelp(); help != "" {
+		sections = append(sections, help)
 	}
 	if u.topBarEnabled {
-		lines = append(lines, "",
-			"Top Bar",
-			"  Shows Title (left) and registered counters (right).")
+		sections = append(sections, u.msg("help.topbar"))
 	} else {
-		lines = append(lines, "",
-			"Bottom Status",
-			"  Shows keys and counters (legacy mode).")
+		sections = append(sections, u.msg("help.legacy"))
 	}
-	lines = append(lines, "",
-		"Status Bar",
-		"  Shows keys on the left and toggles on the right. Mouse badge is green when you can",
-		"  select with the mouse (i.e., tview mouse handling is OFF).",
-	)
-
+	sections = append(sections, u.msg("help.status"))
 	if len(u.helpExtra) > 0 {
-		lines = append(lines, "")
-		lines = append(lines, u.helpExtra...)
+		sections = append(sections, strings.Join(u.helpExtra, "\n"))
 	}
-	help := strings.Join(lines, "\n")
+	help := strings.Join(sections, "\n\n")
 
 	m := tview.NewModal().
 		SetText(help).
-		AddButtons([]string{"Close"}).
+		AddButtons([]string{u.msg("help.close")}).
 		SetDoneFunc(func(_ int, _ string) { u.closeModal() })
-	u.modal = m
-	u.app.SetRoot(m, true)
-	u.app.SetFocus(m)
+	u.showModal(m)
 }
 
 func (u *UI) closeModal() {
@@ -862,7 +2346,7 @@ func (u *UI) closeModal() {
 		return
 	}
 	u.modal = nil
-	u.app.SetRoot(u.root, true)
+	u.pages.RemovePage(modalPage)
 	if u.prevFocus != nil {
 		u.app.SetFocus(u.prevFocus)
 		u.setLogSeparators(u.app.GetFocus() == u.logView)
@@ -907,9 +2391,19 @@ func chooseSocketPathForDial(candidates []string) (string, error) {
 
 // ---- attach client ----
 
+// historyChunk is how many older lines a client asks for per backfill.
+const historyChunk = 500
+
+// maxBackfill caps the lines kept beyond maxLines because they were
+// backfilled, as a multiple of maxLines; scrolling back stops asking for
+// more there.
+const maxBackfill = 4
+
 // AttachOptions control how the client connects and renders.
 type AttachOptions struct {
-	Socket            string // optional override; if empty, auto-detect default path order
+	Socket            string      // optional override; if empty, auto-detect default path order
+	Address           string      // TCP address of a broker ListenAddr; takes precedence over Socket
+	TLS               *TLSOptions // secures the connection to Address
 	SocketCandidates  []string
 	SocketResolver    func() (string, error)
 	NoColour          bool
@@ -917,30 +2411,325 @@ type AttachOptions struct {
 	Title             string // optional title override
 	DisconnectMessage string
 	OnExit            func(int)
+
+	// Dial, if set, connects to the broker in place of Socket, Address and
+	// TLS, over a transport carrying the NDJSON protocol, such as the gRPC
+	// one grpcconsole.Dialer returns.
+	Dial func(ctx context.Context) (net.Conn, error)
+
+	// Token authenticates to a broker with BrokerOptions.Authenticate;
+	// empty uses the TokenEnv environment variable. It is sent in the
+	// clear unless TLS is set.
+	Token string
+
+	// Upstreams, if set, attaches to several brokers at once instead of
+	// Socket or Address, showing their lines merged with each upstream's
+	// name as a coloured source prefix. Each upstream is redialled when it
+	// goes away. Commands, publishing and server-side filtering are not
+	// available.
+	Upstreams []Upstream
+
+	// Reconnect keeps the UI open when the connection drops and redials
+	// with exponential backoff, from ReconnectMinDelay (default 250ms) up
+	// to ReconnectMaxDelay (default 30s). The server's config is applied
+	// again and lines already shown are not repeated.
+	Reconnect         bool
+	ReconnectMinDelay time.Duration
+	ReconnectMaxDelay time.Duration
+
+	// Since, if positive, asks the broker to replay only lines from that
+	// long ago instead of its whole ring. Older ones can still be scrolled
+	// back to.
+	Since time.Duration
+
+	// RecordFile, if set, saves every frame received to a session file
+	// that PlaybackFile can replay.
+	RecordFile string
+	// PlaybackFile, if set, replays a recorded session instead of
+	// connecting: Space pauses, < and > change the speed and :jump skips
+	// ahead.
+	PlaybackFile string
+
+	// UI, if set, is the base for the local UI. Its Rules are merged into the
+	// server's config and its MaxLines overrides the server's; NoColour and
+	// OnExit above win when set. Application is ignored. Without it the UI
+	// starts with mouse support enabled.
+	UI *UIOptions
 }
 
 // Attach connects to the server socket and renders the full interactive UI locally.
 func Attach(opts AttachOptions) error {
+	return AttachContext(context.Background(), opts)
+}
+
+// AttachContext is like Attach but closes the connection and stops the UI when
+// ctx is cancelled. In that case OnExit is not called and ctx.Err() is returned.
+func AttachContext(ctx context.Context, opts AttachOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if opts.PlaybackFile != "" {
+		return playback(ctx, opts)
+	}
+	if len(opts.Upstreams) > 0 {
+		return attachUpstreams(ctx, opts, AttachContext)
+	}
+	path, err := opts.resolvePath()
+	if err != nil {
+		return err
+	}
+	var rec *Recorder
+	if opts.RecordFile != "" {
+		if rec, err = CreateRecorder(opts.RecordFile); err != nil {
+			return err
+		}
+		defer rec.Close()
+	}
+	dial := func(ctx context.Context) (net.Conn, error) {
+		return opts.dial(ctx, path)
+	}
+	conn, err := dial(ctx)
+	if err != nil {
+		return err
+	}
+	// conn is replaced on reconnect; connMu guards it
+	var connMu sync.Mutex
+	current := func() net.Conn {
+		connMu.Lock()
+		defer connMu.Unlock()
+		return conn
+	}
+	defer func() { _ = current().Close() }()
+
+	uiOpts, local := attachUIOptions(opts.UI, opts.NoColour, opts.OnExit)
+	u := NewUI(uiOpts)
+	if opts.Transparent {
+		useTransparentStyles()
+	}
+	if opts.Title != "" {
+		u.SetTitle(opts.Title)
+	} else {
+		u.SetTitle(u.msg("title.attached"))
+	}
+	disconnectNotice := opts.DisconnectMessage
+	if strings.TrimSpace(disconnectNotice) == "" {
+		disconnectNotice = u.msg("notice.disconnected")
+	}
+
+	var writeMu sync.Mutex
+	send := func(v any) {
+		req, _ := json.Marshal(v)
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		_, _ = current().Write(append(req, '\n'))
+	}
+	_ = writeHello(conn, opts.sinceUs(), opts.token())
+	u.mu.Lock()
+	// scrolling to the top of the local buffer fetches older lines
+	u.onNeedHistory = func(beforeUs int64, beforeSeq uint64) {
+		send(HistoryRequest{Type: "history_request", BeforeUs: beforeUs, BeforeSeq: beforeSeq, Limit: historyChunk})
+	}
+	u.onStatsRequest = func() { send(StatsRequest{Type: "stats_request"}) }
+	u.onCommand = func(cmd string) { send(Command{Type: "command", Text: cmd}) }
+	u.onServerFilter = func(f Filter) { send(f) }
+	u.mu.Unlock()
+
+	// closed when the UI loop returns, to stop redialling
+	quit := make(chan struct{})
+	defer close(quit)
+
+	// reconnect redials until it succeeds or the UI goes away
+	reconnect := func() bool {
+		u.setReconnecting(true)
+		defer u.setReconnecting(false)
+		delay := cmp.Or(opts.ReconnectMinDelay, 250*time.Millisecond)
+		maxDelay := cmp.Or(opts.ReconnectMaxDelay, 30*time.Second)
+		for {
+			select {
+			case <-ctx.Done():
+				return false
+			case <-quit:
+				return false
+			case <-time.After(delay):
+			}
+			c, err := dial(ctx)
+			if err == nil {
+				connMu.Lock()
+				conn = c
+				connMu.Unlock()
+				u.Append(u.msg("notice.reconnected"))
+				return true
+			}
+			delay = min(delay*2, maxDelay)
+		}
+	}
+
+	// reader goroutine: consume NDJSON from server and feed the local UI
+	go func() {
+		fr := newConnFrameReader(conn)
+		var skew clockSkew
+		feed := frameFeeder{
+			u:        u,
+			local:    local,
+			lineTime: skew.lineTime,
+			onMeta: func(m Meta) {
+				skew.reset(m.ServerTimeUs, time.Now())
+				if off, significant := skew.Offset(); significant {
+					u.Append(u.msg("notice.clock_skew", off.Round(time.Millisecond)))
+				}
+				if m.Version > ProtocolVersion {
+					u.Append(u.msg("notice.protocol", m.Version, ProtocolVersion))
+				}
+				u.mu.Lock()
+				u.server = m
+				u.mu.Unlock()
+			},
+		}
+		for {
+			b, err := fr.next()
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				select {
+				case <-quit:
+					return // the UI closed the connection
+				default:
+				}
+				u.Append(disconnectNotice)
+				if !opts.Reconnect {
+					u.onExit(1)
+					return
+				}
+				_ = current().Close()
+				if !reconnect() {
+					return
+				}
+				// only lines newer than those already shown
+				writeMu.Lock()
+				_ = writeHello(current(), feed.newestUs, opts.token())
+				writeMu.Unlock()
+				u.resendServerFilter()
+				fr = newConnFrameReader(current())
+				continue
+			}
+			if rec != nil {
+				rec.record(b)
+			}
+			feed.frame(fr, b)
+		}
+	}()
+
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				_ = current().Close()
+				u.app.Stop()
+			case <-quit:
+			}
+		}()
+	}
+
+	// run local UI loop (blocks until exit)
+	if err := u.Run(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// resolvePath returns the broker address to dial: Address, Socket, the
+// resolver's answer or the first existing candidate, in that order, or ""
+// when Dial is set.
+func (opts *AttachOptions) resolvePath() (string, error) {
+	if opts.Dial != nil {
+		return "", nil
+	}
+	if addr := strings.TrimSpace(opts.Address); addr != "" {
+		return "tcp://" + addr, nil
+	}
 	path := strings.TrimSpace(opts.Socket)
 	var err error
 	if path == "" {
 		if opts.SocketResolver != nil {
 			path, err = opts.SocketResolver()
 			if err != nil {
-				return err
+				return "", err
 			}
 			path = strings.TrimSpace(path)
 		}
 		if path == "" && len(opts.SocketCandidates) > 0 {
 			path, err = chooseSocketPathForDial(opts.SocketCandidates)
 			if err != nil {
-				return err
+				return "", err
 			}
 		}
 		if path == "" {
-			return errors.New("console attach: socket path not resolved")
+			return "", errors.New("console attach: socket path not resolved")
 		}
 	}
+	return path, nil
+}
+
+// TokenEnv is the environment variable holding the token attaching clients
+// send when AttachOptions.Token is empty.
+const TokenEnv = "PLANECONSOLE_TOKEN"
+
+// token returns the token to authenticate with.
+func (opts *AttachOptions) token() string {
+	return cmp.Or(opts.Token, os.Getenv(TokenEnv))
+}
+
+// sinceUs returns the replay start for the first hello, 0 for everything.
+func (opts *AttachOptions) sinceUs() int64 {
+	if opts.Since <= 0 {
+		return 0
+	}
+	return time.Now().Add(-opts.Since).UnixMicro()
+}
+
+// dial connects to the broker at path, with TLS if configured, or with Dial.
+func (opts *AttachOptions) dial(ctx context.Context, path string) (net.Conn, error) {
+	if opts.Dial != nil {
+		conn, err := opts.Dial(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("console attach: %w", err)
+		}
+		return conn, nil
+	}
+	conn, err := dialConsole(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("console attach: %w", err)
+	}
+	if opts.TLS != nil {
+		tc, err := clientTLS(ctx, conn, path, opts.TLS)
+		if err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("console attach: %w", err)
+		}
+		conn = tc
+	}
+	return conn, nil
+}
+
+// useTransparentStyles makes tview draw on the terminal's own background.
+func useTransparentStyles() {
+	tview.Styles.PrimitiveBackgroundColor = tcell.ColorDefault
+	tview.Styles.ContrastBackgroundColor = tcell.ColorDefault
+	tview.Styles.MoreContrastBackgroundColor = tcell.ColorDefault
+}
+
+// dialConsole connects to a broker. A UNIX socket path is dialled as is; an
+// IP or hostname without a port gets the default port 9090. unix:// and
+// tcp:// prefixes force the network.
+func dialConsole(ctx context.Context, path string) (net.Conn, error) {
+	var d net.Dialer
+	if strings.HasPrefix(path, "unix://") {
+		return d.DialContext(ctx, "unix", strings.TrimPrefix(path, "unix://"))
+	}
+	if strings.HasPrefix(path, "tcp://") {
+		return d.DialContext(ctx, "tcp", strings.TrimPrefix(path, "tcp://"))
+	}
 	// Detect if path is a TCP address by trying to parse it as host:port
 	network := "unix"
 	if _, _, err := net.SplitHostPort(path); err == nil {
@@ -952,88 +2741,32 @@ func Attach(opts AttachOptions) error {
 			// It's a valid IP address without a port, append default port
 			path = net.JoinHostPort(path, "9090")
 			network = "tcp"
-		} else if !strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "unix://") {
-			// Not a UNIX socket path (doesn't start with / or unix://), treat as hostname and append port
+		} else if !strings.HasPrefix(path, "/") {
+			// Not a UNIX socket path (doesn't start with /), treat as hostname and append port
 			path = net.JoinHostPort(path, "9090")
 			network = "tcp"
 		}
 	}
-	conn, err := net.Dial(network, path)
-	if err != nil {
-		return fmt.Errorf("console attach: %w", err)
-	}
-	defer conn.Close()
+	return d.DialContext(ctx, network, path)
+}
 
-	uiOpts := UIOptions{
-		NoColour:     opts.NoColour,
-		MouseEnabled: true,
+// attachUIOptions builds the options for a client UI from an optional base,
+// and returns the local rules to merge into every server config.
+func attachUIOptions(base *UIOptions, noColour bool, onExit func(int)) (UIOptions, Config) {
+	o := UIOptions{MouseEnabled: true}
+	if base != nil {
+		o = *base
+		o.Application = nil
 	}
-	if opts.OnExit != nil {
-		uiOpts.OnExit = opts.OnExit
+	if noColour {
+		o.NoColour = true
 	}
-	u := NewUI(uiOpts)
-	if opts.Transparent {
-		tview.Styles.PrimitiveBackgroundColor = tcell.ColorDefault
-		tview.Styles.ContrastBackgroundColor = tcell.ColorDefault
-		tview.Styles.MoreContrastBackgroundColor = tcell.ColorDefault
+	if onExit != nil {
+		o.OnExit = onExit
 	}
-	if opts.Title != "" {
-		u.SetTitle(opts.Title)
-	} else {
-		u.SetTitle("Console (attached)")
-	}
-	disconnectNotice := opts.DisconnectMessage
-	if strings.TrimSpace(disconnectNotice) == "" {
-		disconnectNotice = "[notice] disconnected from server"
+	local := o.Rules
+	if o.MaxLines > 0 {
+		local.MaxLines = o.MaxLines
 	}
-
-	// reader goroutine: consume NDJSON from server and feed the local UI
-	r := bufio.NewReaderSize(conn, 64<<10)
-	go func() {
-		for {
-			b, err := r.ReadBytes('\n')
-			if err != nil {
-				u.Append(disconnectNotice)
-				u.onExit(1)
-				return
-			}
-			// peek type
-			var typ struct {
-				Type string `json:"type"`
-			}
-			if err := json.Unmarshal(b, &typ); err != nil {
-				continue
-			}
-			switch typ.Type {
-			case "meta":
-				var m Meta
-				if json.Unmarshal(b, &m) == nil {
-					u.ApplyConfig(Config{
-						MaxLines:   m.MaxLines,
-						Counters:   append([]CounterSpec(nil), m.Counters...),
-						Highlights: append([]HighlightSpec(nil), m.Highlights...),
-					})
-				}
-			case "line":
-				var ev Line
-				if json.Unmarshal(b, &ev) == nil {
-					var when time.Time
-					if ev.TsUs > 0 {
-						when = time.UnixMicro(ev.TsUs)
-					} else {
-						when = time.Now()
-					}
-					u.appendWithWhen(when, ev.Text)
-				}
-			case "notice":
-				var n Notice
-				if json.Unmarshal(b, &n) == nil {
-					u.Append(n.Text)
-				}
-			}
-		}
-	}()
-
-	// run local UI loop (blocks until exit)
-	return u.app.Run()
+	return o, local
 }
@@ -0,0 +1,249 @@
+package console
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// frameFeeder applies NDJSON frames from a broker stream or recording to a UI.
+type frameFeeder struct {
+	u *UI
+	// lineTime returns the local time of a line; now is when it arrived.
+	lineTime func(ev Line, now time.Time) time.Time
+	// onMeta, if set, runs before a meta frame's config is applied.
+	onMeta func(m Meta)
+	// local rules are appended to every config received; a positive
+	// MaxLines overrides the received one.
+	local Config
+
+	malformedTotal int
+	lastReport     time.Time
+	lines          int
+
+	// started and lastSeq identify the newest line seen; when a broker
+	// sends meta again after a reconnect, lines up to skipThrough are a
+	// replay of ones already shown.
+	started     int64
+	lastSeq     uint64
+	skipThrough uint64
+	// newestUs is the newest line timestamp seen, for the hello sent on
+	// reconnecting
+	newestUs int64
+	// nextSeq is the line ID expected next, 0 before the first line from a
+	// broker instance
+	nextSeq uint64
+}
+
+// frame decodes b and applies it, reporting malformed input at most once a
+// second.
+func (f *frameFeeder) frame(fr *frameReader, b []byte) {
+	u := f.u
+	// peek type, resynchronizing past malformed fragments
+	typ, b := fr.peekFrameType(b)
+	if n := fr.takeMalformed(); n > 0 {
+		f.malformedTotal += n
+		if time.Since(f.lastReport) >= time.Second {
+			f.lastReport = time.Now()
+			u.Append(u.msg("notice.malformed", f.malformedTotal))
+		}
+	}
+	switch typ {
+	case "meta":
+		var m Meta
+		if json.Unmarshal(b, &m) == nil {
+			if f.onMeta != nil {
+				f.onMeta(m)
+			}
+			f.skipThrough = 0
+			if m.StartedUs != 0 && m.StartedUs == f.started {
+				f.skipThrough = f.lastSeq
+			} else {
+				f.nextSeq = 0 // IDs of another broker run
+			}
+			f.started = m.StartedUs
+			cfg := Config{
+				MaxLines:   m.MaxLines,
+				Counters:   append(append([]CounterSpec(nil), m.Counters...), f.local.Counters...),
+				Highlights: append(append([]HighlightSpec(nil), m.Highlights...), f.local.Highlights...),
+			}
+			if f.local.MaxLines > 0 {
+				cfg.MaxLines = f.local.MaxLines
+			}
+			u.ApplyConfig(cfg)
+		}
+	case "line":
+		var ev Line
+		if json.Unmarshal(b, &ev) == nil && !f.seen(ev) {
+			f.lines++
+			u.appendTimed(f.live([]Line{ev}))
+		}
+	case "lines":
+		var evs Lines
+		if json.Unmarshal(b, &evs) == nil {
+			fresh := evs.Lines[:0]
+			for _, ev := range evs.Lines {
+				if !f.seen(ev) {
+					fresh = append(fresh, ev)
+				}
+			}
+			f.lines += len(fresh)
+			u.appendTimed(f.live(fresh))
+		}
+	case "history":
+		var h History
+		if json.Unmarshal(b, &h) == nil {
+			u.prependTimed(f.timed(h.Lines), h.More)
+		}
+	case "counters":
+		var cv CounterValues
+		if json.Unmarshal(b, &cv) == nil {
+			u.setCounterValues(cv.Counters)
+		}
+	case "viewers":
+		var v Viewers
+		if json.Unmarshal(b, &v) == nil {
+			u.setViewers(v.Count)
+		}
+	case "stats":
+		var st Stats
+		if json.Unmarshal(b, &st) == nil {
+			u.Do(func() { u.showTextModal(u.msg("modal.stats"), u.formatStats(st)) })
+		}
+	case "notice":
+		var n Notice
+		if json.Unmarshal(b, &n) == nil {
+			u.Append(n.Text)
+		}
+	}
+}
+
+// seen records ev as the newest line and reports whether it was already shown
+// before a reconnect.
+func (f *frameFeeder) seen(ev Line) bool {
+	if ev.Seq != 0 && ev.Seq <= f.skipThrough {
+		return true
+	}
+	f.lastSeq = max(f.lastSeq, ev.Seq)
+	f.newestUs = max(f.newestUs, ev.TsUs)
+	return false
+}
+
+// live converts newly streamed lines, inserting a marker where line IDs
+// skip, as when the broker dropped lines for a lagging viewer.
+func (f *frameFeeder) live(lines []Line) []timedLine {
+	now := time.Now()
+	filtered := f.u.serverFiltering()
+	batch := make([]timedLine, 0, len(lines))
+	for _, ev := range lines {
+		when := f.lineTime(ev, now)
+		if filtered {
+			f.nextSeq = 0 // the broker skips lines; it reports drops itself
+		} else if ev.Seq != 0 {
+			if f.nextSeq != 0 && ev.Seq > f.nextSeq {
+				batch = append(batch, timedLine{when: when, text: f.u.msg("notice.gap", ev.Seq-f.nextSeq), level: "warn"})
+			}
+			f.nextSeq = ev.Seq + 1
+		}
+		batch = append(batch, timedLine{when: when, text: lineText(ev), tsUs: ev.TsUs, seq: ev.Seq, channel: ev.Channel, fields: ev.Fields, level: lineLevel(ev), source: ev.Source})
+	}
+	return batch
+}
+
+func (f *frameFeeder) timed(lines []Line) []timedLine {
+	now := time.Now()
+	batch := make([]timedLine, 0, len(lines))
+	for _, ev := range lines {
+		batch = append(batch, timedLine{when: f.lineTime(ev, now), text: lineText(ev), tsUs: ev.TsUs, seq: ev.Seq, channel: ev.Channel, fields: ev.Fields, level: lineLevel(ev), source: ev.Source})
+	}
+	return batch
+}
+
+// lineText returns the text shown for ev, prefixed with its source if any.
+func lineText(ev Line) string {
+	if ev.Source == "" {
+		return ev.Text
+	}
+	return tview.Escape("["+ev.Source+"]") + " " + ev.Text
+}
+
+// recordedLineTime trusts a recorded line's own timestamp; there is no live
+// server clock to correct against.
+func recordedLineTime(ev Line, _ time.Time) time.Time {
+	if ev.TsUs == 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(ev.TsUs)
+}
+
+// ViewOptions control the offline viewer.
+type ViewOptions struct {
+	NoColour    bool
+	Transparent bool
+	Title       string // optional title override
+	OnExit      func(int)
+
+	// UI, if set, is the base for the viewer's UI, as in AttachOptions.
+	UI *UIOptions
+}
+
+// ViewFile opens an NDJSON recording and browses it with View. A path of "-"
+// reads standard input.
+func ViewFile(path string, opts ViewOptions) error {
+	if path == "-" {
+		return View(os.Stdin, opts)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("console view: %w", err)
+	}
+	defer f.Close()
+	return viewTitled(f, opts, path)
+}
+
+// View reads NDJSON frames, as written by a broker or a file sink, from r and
+// renders them in the full interactive UI. Input is read until EOF, so a
+// pipe streams in as it is written, while a file is shown as it was when
+// its end was reached; later appends are not followed. The UI stays open
+// afterwards until the user quits.
+func View(r io.Reader, opts ViewOptions) error {
+	return viewTitled(r, opts, "")
+}
+
+// viewTitled runs View; without a title override the title names the file.
+func viewTitled(r io.Reader, opts ViewOptions, file string) error {
+	uiOpts, local := attachUIOptions(opts.UI, opts.NoColour, opts.OnExit)
+	u := NewUI(uiOpts)
+	if opts.Transparent {
+		useTransparentStyles()
+	}
+	switch {
+	case opts.Title != "":
+		u.SetTitle(opts.Title)
+	case file != "":
+		u.SetTitle(u.msg("title.file", file))
+	default:
+		u.SetTitle(u.msg("title.offline"))
+	}
+
+	fr := newFrameReader(r)
+	go func() {
+		feed := frameFeeder{u: u, local: local, lineTime: recordedLineTime}
+		for {
+			b, err := fr.next()
+			if err != nil {
+				if err != io.EOF {
+					u.Append(u.msg("notice.read_error", err))
+				}
+				u.Append(u.msg("notice.end", feed.lines))
+				return
+			}
+			feed.frame(fr, b)
+		}
+	}()
+	return u.Run()
+}
@@ -0,0 +1,83 @@
+package console
+
+// hscrollStep is how many columns Left and Right scroll long lines.
+const hscrollStep = 8
+
+// toggleWrapDirect switches between wrapping long lines and cutting them at
+// the edge of the view.
+func (u *UI) toggleWrapDirect() {
+	u.mu.Lock()
+	u.wrap = !u.wrap
+	wrap := u.wrap
+	if wrap {
+		u.selecting = false // see rowsWrapped
+	}
+	u.mu.Unlock()
+	u.logView.SetWrap(wrap)
+	row, _ := u.logView.GetScrollOffset()
+	u.logView.ScrollTo(row, 0)
+	u.refreshDirect()
+}
+
+// rowsWrapped reports whether long lines wrap. The log view's scroll offset
+// then counts wrapped lines rather than display rows, and tview does not say
+// where each row starts, so the features that address a row by its offset
+// (selection, :goto, stepping through search matches, the inspector and
+// correlation) are off while wrapping.
+func (u *UI) rowsWrapped() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.wrap
+}
+
+// hscrollDirect scrolls unwrapped lines delta columns sideways; negative is
+// towards their start.
+func (u *UI) hscrollDirect(delta int) {
+	u.mu.Lock()
+	wrap := u.wrap
+	u.mu.Unlock()
+	if wrap {
+		return
+	}
+	row, col := u.logView.GetScrollOffset()
+	u.logView.ScrollTo(row, max(col+delta, 0))
+	u.updateBottomBarDirect()
+}
+
+// hscrollPage is how far Shift+Left/Right scroll: half the view's width.
+func (u *UI) hscrollPage() int {
+	_, _, w, _ := u.logView.GetInnerRect()
+	return max(w/2, hscrollStep)
+}
+
+// followEndDirect keeps the newest line at the bottom of the view after new
+// rows are written. Unlike ScrollToEnd it keeps the horizontal offset, so a
+// live view scrolled sideways stays where it is.
+func (u *UI) followEndDirect() {
+	_, col := u.logView.GetScrollOffset()
+	if col == 0 {
+		u.logView.ScrollToEnd()
+		return
+	}
+	_, _, _, h := u.logView.GetInnerRect()
+	u.logView.ScrollTo(max(u.viewLineCount()-h, 0), col)
+}
+
+// followTopDirect is followEndDirect for newest-first order.
+func (u *UI) followTopDirect() {
+	_, col := u.logView.GetScrollOffset()
+	u.logView.ScrollTo(0, col)
+}
+
+// viewLineCount returns how many lines the log view holds on screen: rows,
+// or the lines they wrap into.
+func (u *UI) viewLineCount() int {
+	u.mu.Lock()
+	wrap := u.wrap
+	u.mu.Unlock()
+	if wrap {
+		return u.logView.GetWrappedLineCount()
+	}
+	// less the empty line after the final newline
+	return u.logView.GetOriginalLineCount() - 1
+}
@@ -0,0 +1,69 @@
+package console
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// maxPendingLine is how long a partial line may grow before it is appended
+// without its newline.
+const maxPendingLine = 64 << 10
+
+// lineWriter is an io.Writer that appends each complete line it is given.
+type lineWriter struct {
+	mu      sync.Mutex
+	pending []byte
+	append  func(string)
+}
+
+// Write appends every newline-terminated line in p and keeps the rest until
+// the next write. It never fails.
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.pending = append(w.pending, p...)
+			if len(w.pending) >= maxPendingLine {
+				w.emit()
+			}
+			break
+		}
+		w.pending = append(w.pending, p[:i]...)
+		w.emit()
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// Close appends a final line left without a newline.
+func (w *lineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.pending) > 0 {
+		w.emit()
+	}
+	return nil
+}
+
+func (w *lineWriter) emit() {
+	line := bytes.TrimSuffix(w.pending, []byte("\r"))
+	w.append(string(line))
+	w.pending = w.pending[:0]
+}
+
+// Writer returns a writer that appends each line written to it, for
+// log.SetOutput, exec.Cmd.Stdout and the like. Each call returns a writer
+// with its own partial-line buffer; Close appends an unterminated last line.
+func (b *Broker) Writer() io.WriteCloser {
+	return &lineWriter{append: b.Append}
+}
+
+// Writer returns a writer that appends each line written to it to the UI; see
+// Broker.Writer.
+func (u *UI) Writer() io.WriteCloser {
+	return &lineWriter{append: u.Append}
+}
@@ -0,0 +1,58 @@
+// Package wsconsole gives the HTTPBridge of a console broker its WebSocket,
+// which the browser viewer talks to:
+//
+//	console.NewHTTPBridge(b, &console.HTTPBridgeOptions{Upgrade: wsconsole.Upgrade(nil)})
+package wsconsole
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/coder/websocket"
+	console "github.com/network-plane/planeconsole"
+)
+
+// Options configure Upgrade.
+type Options struct {
+	// OriginPatterns lists extra hosts, such as "*.example.com", whose
+	// pages may open the WebSocket. Same-origin requests are always
+	// allowed.
+	OriginPatterns []string
+}
+
+// Upgrade returns an HTTPBridgeOptions.Upgrade accepting WebSockets that
+// carry a frame per text message. opts may be nil.
+func Upgrade(opts *Options) func(w http.ResponseWriter, r *http.Request) (console.MessageConn, error) {
+	var accept websocket.AcceptOptions
+	if opts != nil {
+		accept.OriginPatterns = opts.OriginPatterns
+	}
+	return func(w http.ResponseWriter, r *http.Request) (console.MessageConn, error) {
+		ws, err := websocket.Accept(w, r, &accept)
+		if err != nil {
+			return nil, err
+		}
+		return conn{ws}, nil
+	}
+}
+
+// conn is a WebSocket as a console.MessageConn.
+type conn struct {
+	ws *websocket.Conn
+}
+
+func (c conn) Read(ctx context.Context) ([]byte, error) {
+	_, b, err := c.ws.Read(ctx)
+	return b, err
+}
+
+func (c conn) Write(ctx context.Context, b []byte) error {
+	return c.ws.Write(ctx, websocket.MessageText, b)
+}
+
+func (c conn) Close(reason string) error {
+	if reason == "" {
+		return c.ws.CloseNow()
+	}
+	return c.ws.Close(websocket.StatusGoingAway, reason)
+}